@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/backup"
+)
+
+// backupTimeFormat is the format of the timestamps in backup file names.
+const backupTimeFormat = "20060102T150405Z"
+
+// backupPaths returns the paths of the ledger directory saved by backups.
+// The configuration, shared by the ledgers, is saved from the workdir, at
+// the same place in the archives: they are laid out like the workdir with
+// the default ledger.
+func backupPaths() []string {
+	return []string{accountsDirName, transactionsDirName, keyInfoFileName}
+}
+
+// BackupWorkdir writes a backup archive of the named ledger of the workdir,
+// and of the configuration, into w.
+func BackupWorkdir(workdir, ledger string, w io.Writer) (*backup.Manifest, error) {
+	dir, err := LedgerDir(workdir, ledger)
+	if err != nil {
+		return nil, err
+	}
+	return backup.CreateDirs(w, []backup.Dir{
+		{Root: dir, Paths: backupPaths()},
+		{Root: workdir, Paths: []string{configDirName}},
+	})
+}
+
+// RestoreWorkdir replaces the named ledger of the workdir, and the
+// configuration, by the content of the backup archive at archivePath, once
+// verified. The replaced ledger directory is kept aside, with the replaced
+// configuration, at the returned path (empty if there was no ledger).
+func RestoreWorkdir(workdir, ledger, archivePath string) (string, error) {
+	dir, err := LedgerDir(workdir, ledger)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	stamp := time.Now().UTC().Format(backupTimeFormat)
+
+	restored := fmt.Sprintf("%s.restore-%s", dir, stamp)
+	if _, err := backup.Extract(f, restored); err != nil {
+		return "", err
+	}
+
+	old := fmt.Sprintf("%s.old-%s", dir, stamp)
+	if err := os.Rename(dir, old); errors.Is(err, os.ErrNotExist) {
+		old = ""
+	} else if err != nil {
+		os.RemoveAll(restored)
+		return "", err
+	}
+
+	if err := os.Rename(restored, dir); err != nil {
+		if old != "" {
+			os.Rename(old, dir)
+		}
+		os.RemoveAll(restored)
+		return "", err
+	}
+
+	if ledger == DefaultLedger {
+		// the other ledgers are not part of the backups of the default one
+		if old != "" {
+			err := os.Rename(filepath.Join(old, ledgersDirName), filepath.Join(workdir, ledgersDirName))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return old, err
+			}
+		}
+		return old, nil
+	}
+
+	// the configuration is restored into the workdir, if archived
+	config := filepath.Join(dir, configDirName)
+	if _, err := os.Stat(config); errors.Is(err, os.ErrNotExist) {
+		return old, nil
+	} else if err != nil {
+		return old, err
+	}
+	if old == "" {
+		old = fmt.Sprintf("%s.old-%s", dir, stamp)
+		if err := os.Mkdir(old, 0700); err != nil {
+			return "", err
+		}
+	}
+	err = os.Rename(filepath.Join(workdir, configDirName), filepath.Join(old, configDirName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return old, err
+	}
+	return old, os.Rename(config, filepath.Join(workdir, configDirName))
+}
+
+// Snapshot saves a backup archive of the named ledger of the workdir before
+// an operation rewriting it (named by reason, ex: "decrypt"), and returns
+// its path. Snapshots are kept next to the ledger directory, in
+// "<ledger directory>-snapshots".
+func Snapshot(workdir, ledger, reason string) (string, error) {
+	ledgerDir, err := LedgerDir(workdir, ledger)
+	if err != nil {
+		return "", err
+	}
+	dir := ledgerDir + "-snapshots"
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	stamp := time.Now().UTC().Format(backupTimeFormat)
+	path := filepath.Join(dir, fmt.Sprintf("pre-%s-%s.tar.gz", reason, stamp))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := BackupWorkdir(workdir, ledger, f); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, f.Close()
+}
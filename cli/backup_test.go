@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeBackup backs up the ledger of the workdir into a file, and returns
+// its path.
+func writeBackup(t *testing.T, workdir, ledger string) string {
+	var b bytes.Buffer
+	_, err := BackupWorkdir(workdir, ledger, &b)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "backup.tar.gz")
+	require.NoError(t, os.WriteFile(path, b.Bytes(), 0600))
+	return path
+}
+
+// accounts returns the number of accounts of the ledger of the workdir.
+func accounts(t *testing.T, workdir, ledger string) uint64 {
+	mitrackCli := NewMitrackCli()
+	require.NoError(t, mitrackCli.Open(workdir, ledger))
+	defer mitrackCli.Cleanup()
+	n, err := mitrackCli.AccService().Count()
+	require.NoError(t, err)
+	return n
+}
+
+func register(t *testing.T, workdir, ledger, name string) {
+	mitrackCli := NewMitrackCli()
+	require.NoError(t, mitrackCli.Open(workdir, ledger))
+	defer mitrackCli.Cleanup()
+	require.NoError(t, mitrackCli.AccService().Register(account.NewAccount(name, account.TypeAsset)))
+}
+
+func setCurrency(t *testing.T, workdir, currency string) {
+	mitrackCli := NewMitrackCli()
+	require.NoError(t, mitrackCli.OpenWorkdir(workdir, ""))
+	conf := mitrackCli.Config()
+	require.NoError(t, conf.Set("currency", currency))
+	require.NoError(t, conf.Save(mitrackCli.ConfigPath()))
+}
+
+func currency(t *testing.T, workdir string) string {
+	mitrackCli := NewMitrackCli()
+	require.NoError(t, mitrackCli.OpenWorkdir(workdir, ""))
+	return mitrackCli.Config().Currency
+}
+
+func TestBackupRestore(t *testing.T) {
+	t.Run("default ledger", func(t *testing.T) {
+		workdir := filepath.Join(t.TempDir(), "home")
+		register(t, workdir, DefaultLedger, "Cash")
+		require.NoError(t, CreateLedger(workdir, "business"))
+		register(t, workdir, "business", "Bank")
+		setCurrency(t, workdir, "MGA")
+		archive := writeBackup(t, workdir, DefaultLedger)
+
+		register(t, workdir, DefaultLedger, "Wallet")
+		register(t, workdir, "business", "Safe")
+		setCurrency(t, workdir, "EUR")
+
+		old, err := RestoreWorkdir(workdir, DefaultLedger, archive)
+		require.NoError(t, err)
+		assert.DirExists(t, old)
+		assert.Equal(t, uint64(1), accounts(t, workdir, DefaultLedger))
+		assert.Equal(t, "MGA", currency(t, workdir))
+		assert.Equal(t, uint64(2), accounts(t, workdir, "business"), "the other ledgers are moved back")
+	})
+
+	t.Run("named ledger", func(t *testing.T) {
+		workdir := filepath.Join(t.TempDir(), "home")
+		register(t, workdir, DefaultLedger, "Cash")
+		require.NoError(t, CreateLedger(workdir, "business"))
+		register(t, workdir, "business", "Bank")
+		setCurrency(t, workdir, "MGA")
+		archive := writeBackup(t, workdir, "business")
+
+		register(t, workdir, DefaultLedger, "Wallet")
+		register(t, workdir, "business", "Safe")
+		setCurrency(t, workdir, "EUR")
+
+		old, err := RestoreWorkdir(workdir, "business", archive)
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(workdir, "ledgers"), filepath.Dir(old))
+		assert.FileExists(t, filepath.Join(old, configDirName, "config.yaml"), "the replaced config is kept aside")
+		assert.Equal(t, uint64(1), accounts(t, workdir, "business"))
+		assert.Equal(t, "MGA", currency(t, workdir), "the config is restored into the workdir")
+		assert.NoDirExists(t, filepath.Join(workdir, "ledgers", "business", configDirName))
+		assert.Equal(t, uint64(2), accounts(t, workdir, DefaultLedger), "the default ledger is kept")
+
+		ledgers, err := Ledgers(workdir)
+		require.NoError(t, err)
+		assert.Equal(t, []string{DefaultLedger, "business"}, ledgers, "the kept ledger is not listed")
+	})
+}
@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"github.com/fitiavana07/mitrack/pkg/check"
+)
+
+// CheckWorkdir checks the integrity of the accounts and transactions of the
+// workdir, using key if it is encrypted.
+func CheckWorkdir(workdir string, key []byte) (*check.Report, error) {
+	encrypted, err := IsEncrypted(workdir)
+	if err != nil {
+		return nil, err
+	}
+
+	dbs := []check.Database{}
+	for _, dir := range recordDirs(workdir) {
+		s, err := openStore(dir, encrypted, key)
+		if err != nil {
+			return nil, err
+		}
+		dbs = append(dbs, check.Database{Dir: dir, Store: s})
+	}
+	return check.Check(dbs[0], dbs[1])
+}
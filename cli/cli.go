@@ -3,56 +3,234 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
 	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/config"
+	"github.com/fitiavana07/mitrack/pkg/search"
+	"github.com/fitiavana07/mitrack/pkg/store"
 	"github.com/fitiavana07/mitrack/pkg/transaction"
+	"github.com/mitchellh/go-homedir"
 )
 
 // Cli represents the mitrack command line interface.
+//
+// Its services can only be used once Open was called, which the root
+// command does after parsing the flags.
 type Cli interface {
+	// Open opens the named ledger of the workdir, creating the workdir if
+	// needed. An empty ledger is the one of the configuration.
+	Open(workdir, ledger string) error
+	// OpenWorkdir is like Open, without opening the ledger, which may not
+	// exist: only the configuration can be used, not the services.
+	OpenWorkdir(workdir, ledger string) error
+
+	Workdir() string
+	// Ledger returns the name of the opened ledger.
+	Ledger() string
+	// LedgerDir returns the directory of the opened ledger.
+	LedgerDir() string
+	Config() *config.Config
+	// ConfigPath returns the path of the configuration file.
+	ConfigPath() string
 	AccService() account.AccService
 	TxService() transaction.TxService
+	// SearchIndex returns the full-text index of the accounts and
+	// transactions of the ledger.
+	SearchIndex() *search.Index
+
+	// SetOutput sets the options of the output of the commands.
+	SetOutput(opts OutputOptions)
+	// Render writes l into w, in the output format of the options, or
+	// else in the configured one.
+	Render(w io.Writer, l *Listing) error
+
 	Cleanup() error
 }
 
 // MitrackCli represents an instance of the mitrack command line interface.
 // Instances are created using NewMitrackCli.
 type MitrackCli struct {
+	workdir    string
+	ledger     string
+	ledgerDir  string
+	config     *config.Config
 	accService account.AccService
 	txService  transaction.TxService
+	search     *search.Index
+	output     OutputOptions
 }
 
+const (
+	// DefaultWorkdirName is the name of the default workdir, in the home
+	// directory of the user.
+	DefaultWorkdirName = ".mitrack"
+
+	// WorkdirEnv is the environment variable holding the workdir, if not
+	// the default one.
+	WorkdirEnv = "MITRACK_HOME"
+
+	// WorkdirOnlyAnnotation annotates the commands, with their
+	// subcommands, for which only the workdir is opened (see
+	// Cli.OpenWorkdir), so that they work even if the ledger does not.
+	WorkdirOnlyAnnotation = "mitrack.workdir-only"
+)
+
 const (
 	dataDirName         = "data"
 	configDirName       = "config"
 	accountsDirName     = "accounts"
 	transactionsDirName = "transactions"
+
+	// indexDirName is the directory of the transactions and search indexes.
+	// They are not backed up, as `mitrack db reindex` rebuilds them.
+	indexDirName = "index"
 )
 
-// NewMitrackCli returns a new MitrackCli.
-func NewMitrackCli(workdir string) (Cli, error) {
-	accountsDir := filepath.Join(workdir, accountsDirName)
-	transactionsDir := filepath.Join(workdir, transactionsDirName)
+// DefaultWorkdir returns the workdir used when none is given: the value of
+// WorkdirEnv if set, DefaultWorkdirName in the home directory otherwise.
+func DefaultWorkdir() (string, error) {
+	if workdir := os.Getenv(WorkdirEnv); workdir != "" {
+		return workdir, nil
+	}
+	homeDir, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, DefaultWorkdirName), nil
+}
+
+// NewMitrackCli returns a new MitrackCli, to be opened.
+func NewMitrackCli() Cli {
+	return &MitrackCli{}
+}
+
+// Open opens the ledger of the workdir.
+// If the ledger is encrypted, its key is looked up using WorkdirKey;
+// without key, the services can only read plaintext records.
+func (c *MitrackCli) Open(workdir, ledger string) error {
+	conf, err := openWorkdir(workdir)
+	if err != nil {
+		return err
+	}
+
+	if ledger == "" {
+		ledger = conf.Ledger
+	}
+	if err := checkLedger(workdir, ledger); err != nil {
+		return err
+	}
+	ledgerDir, err := LedgerDir(workdir, ledger)
+	if err != nil {
+		return err
+	}
+	// ex: the index directory, not restored from backups
+	if err := createLedgerDirs(ledgerDir); err != nil {
+		return err
+	}
+
+	accountsDir := filepath.Join(ledgerDir, accountsDirName)
+	transactionsDir := filepath.Join(ledgerDir, transactionsDirName)
+	indexDir := filepath.Join(ledgerDir, indexDirName)
+
+	encrypted, err := IsEncrypted(ledgerDir)
+	if err != nil {
+		return err
+	}
+	key, err := WorkdirKey(ledgerDir, "")
+	if err != nil && !errors.Is(err, store.ErrLocked) {
+		return err
+	}
+
+	// the search index is shared by both services
+	indexStore, err := openStore(indexDir, encrypted, key)
+	if err != nil {
+		return err
+	}
+	searchIndex := search.NewIndex(indexStore)
+
+	accStore, err := openStore(accountsDir, encrypted, key)
+	if err != nil {
+		return err
+	}
+	accService, err := account.NewAccServiceWithSearch(accStore, searchIndex)
+	if err != nil {
+		return err
+	}
 
-	for _, dir := range []string{workdir, accountsDir, transactionsDir} {
-		if err := os.MkdirAll(dir, os.ModePerm); err != nil && !errors.Is(err, os.ErrExist) {
-			return nil, err
-		}
+	txStore, err := openStore(transactionsDir, encrypted, key)
+	if err != nil {
+		return err
 	}
+	txService, err := transaction.NewTxServiceWithSearch(txStore, indexStore, searchIndex, accService)
+	if err != nil {
+		return err
+	}
+
+	c.workdir, c.ledger, c.ledgerDir = workdir, ledger, ledgerDir
+	c.config, c.accService, c.txService, c.search = conf, accService, txService, searchIndex
+	return nil
+}
 
-	accService, err := account.NewAccService(accountsDir)
+// OpenWorkdir opens the workdir only, so that its configuration can be
+// fixed even if its ledger does not exist.
+func (c *MitrackCli) OpenWorkdir(workdir, ledger string) error {
+	conf, err := openWorkdir(workdir)
 	if err != nil {
+		return err
+	}
+	if ledger == "" {
+		ledger = conf.Ledger
+	}
+	c.workdir, c.ledger, c.config = workdir, ledger, conf
+	return nil
+}
+
+// openWorkdir creates the workdir if needed, and returns its configuration.
+func openWorkdir(workdir string) (*config.Config, error) {
+	configDir := filepath.Join(workdir, configDirName)
+	if err := createLedgerDirs(workdir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(configDir, os.ModePerm); err != nil && !errors.Is(err, os.ErrExist) {
 		return nil, err
 	}
 
-	txService, err := transaction.NewTxService(transactionsDir, accService)
+	conf, err := config.Load(filepath.Join(configDir, config.FileName))
 	if err != nil {
 		return nil, err
 	}
+	conf.CheckLedger = func(name string) error {
+		return checkLedger(workdir, name)
+	}
+	return conf, nil
+}
+
+// Workdir returns the mitrack working directory.
+func (c *MitrackCli) Workdir() string {
+	return c.workdir
+}
+
+// Ledger returns the name of the opened ledger.
+func (c *MitrackCli) Ledger() string {
+	return c.ledger
+}
+
+// LedgerDir returns the directory of the opened ledger.
+func (c *MitrackCli) LedgerDir() string {
+	return c.ledgerDir
+}
+
+// Config returns the configuration of the workdir.
+func (c *MitrackCli) Config() *config.Config {
+	return c.config
+}
 
-	return &MitrackCli{accService, txService}, nil
+// ConfigPath returns the path of the configuration file of the workdir.
+func (c *MitrackCli) ConfigPath() string {
+	return filepath.Join(c.workdir, configDirName, config.FileName)
 }
 
 // AccService returns the account service.
@@ -65,8 +243,32 @@ func (c *MitrackCli) TxService() transaction.TxService {
 	return c.txService
 }
 
+// SearchIndex returns the full-text index of the ledger.
+func (c *MitrackCli) SearchIndex() *search.Index {
+	return c.search
+}
+
+// SetOutput sets the options of the output of the commands.
+func (c *MitrackCli) SetOutput(opts OutputOptions) {
+	c.output = opts
+}
+
+// Render writes l into w, in the output format of the options, or else in
+// the configured one.
+func (c *MitrackCli) Render(w io.Writer, l *Listing) error {
+	opts := c.output
+	if opts.Format == "" && c.config != nil {
+		opts.Format = c.config.Output
+	}
+	return Render(w, opts, l)
+}
+
 // Cleanup clean up used resources (files, etc.).
+// It does nothing if the workdir was not opened.
 func (c *MitrackCli) Cleanup() error {
+	if c.accService == nil {
+		return nil
+	}
 	accServiceCleanupErr := c.AccService().Cleanup()
 	txServiceCleanupErr := c.TxService().Cleanup()
 	if accServiceCleanupErr != nil || txServiceCleanupErr != nil {
@@ -14,8 +14,7 @@ func NewAccountCommand(mitrackCli cli.Cli) *cobra.Command {
 	}
 	cmd.AddCommand(
 		NewRegisterCommand(mitrackCli),
-		// TODO
-		// NewCountCommand(mitrackCli),
+		NewCountCommand(mitrackCli),
 		NewListCommand(mitrackCli),
 		// NewShowCommand(mitrackCli),
 		// NewUpdateCommand(mitrackCli),
@@ -0,0 +1,53 @@
+package account
+
+import (
+	"fmt"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/cli/command/completion"
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/spf13/cobra"
+)
+
+type countOptions struct {
+	accountType account.Type
+}
+
+// NewCountCommand returns a new `mitrack account count` command.
+func NewCountCommand(mitrackCli cli.Cli) *cobra.Command {
+	options := countOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "count [--type=TYPE]",
+		Short: "Count accounts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCount(cmd, mitrackCli, options)
+		},
+		Example: `
+$ mitrack account count
+$ mitrack account count --type=expense
+`,
+	}
+
+	flags := cmd.Flags()
+	flags.Var(newAccountTypeValue(&options.accountType), "type", "count only the accounts of this type (asset|liability|equity|expense|revenue)")
+	cmd.RegisterFlagCompletionFunc("type", completion.AccountTypes)
+
+	return cmd
+}
+
+func runCount(cmd *cobra.Command, mitrackCli cli.Cli, options countOptions) error {
+	var count uint64
+	var err error
+	if cmd.Flags().Changed("type") {
+		count, err = mitrackCli.AccService().CountByType(options.accountType)
+	} else {
+		count, err = mitrackCli.AccService().Count()
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), count)
+	return nil
+}
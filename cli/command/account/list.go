@@ -1,8 +1,6 @@
 package account
 
 import (
-	"fmt"
-
 	"github.com/fitiavana07/mitrack/cli"
 	"github.com/spf13/cobra"
 )
@@ -13,21 +11,37 @@ func NewListCommand(mitrackCli cli.Cli) *cobra.Command {
 		Use:   "ls",
 		Short: "List accounts",
 		Args:  cobra.NoArgs,
-		Run: func(cmd *cobra.Command, args []string) {
-			runList(mitrackCli)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runList(cmd, mitrackCli)
 		},
 		Example: `
 $ mitrack account ls
+$ mitrack account ls --output json
+$ mitrack account ls --template '{{.Alias}}'
 `,
 	}
 
 	return cmd
 }
 
-func runList(mitrackCli cli.Cli) {
-	accounts := mitrackCli.AccService().List()
+func runList(cmd *cobra.Command, mitrackCli cli.Cli) error {
+	accounts, err := mitrackCli.AccService().List()
+	if err := cli.Warn(cmd.ErrOrStderr(), err); err != nil {
+		return err
+	}
+
+	views := []cli.AccountView{}
+	rows := [][]string{}
 	for _, acc := range accounts {
-		fmt.Printf("%s %s - %s (%s)\n", acc.ID.Short(), acc.Type.Initial(), acc.Name, acc.Alias)
+		v := cli.NewAccountView(acc)
+		views = append(views, v)
+		rows = append(rows, []string{acc.ID.Short(), v.Type, acc.Name, acc.Alias})
+	}
+
+	l := &cli.Listing{
+		Header: []string{"ID", "TYPE", "NAME", "ALIAS"},
+		Rows:   rows,
+		Value:  views,
 	}
-	return
+	return mitrackCli.Render(cmd.OutOrStdout(), l)
 }
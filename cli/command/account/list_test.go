@@ -0,0 +1,37 @@
+package account
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListLocked(t *testing.T) {
+	workdir := t.TempDir()
+	mitrackCli := cli.NewMitrackCli()
+	require.NoError(t, mitrackCli.Open(workdir, ""))
+	for _, name := range []string{"Cash", "Bank"} {
+		require.NoError(t, mitrackCli.AccService().Register(account.NewAccount(name, account.TypeAsset)))
+	}
+	require.NoError(t, cli.EncryptWorkdir(mitrackCli.LedgerDir(), "secret"))
+
+	t.Setenv(cli.KeyEnv, "")
+	t.Setenv(cli.PassphraseEnv, "")
+	locked := cli.NewMitrackCli()
+	require.NoError(t, locked.Open(workdir, ""))
+
+	var stdout, stderr bytes.Buffer
+	cmd := NewListCommand(locked)
+	cmd.SilenceUsage = true
+	cmd.SetArgs([]string{})
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	err := cmd.Execute()
+	assert.Equal(t, cli.ExitLocked, cli.ExitCode(err), "%v", err)
+	assert.Empty(t, stdout.String(), "no empty table")
+	assert.NotContains(t, stderr.String(), "warning")
+}
@@ -2,6 +2,7 @@ package account
 
 import (
 	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/cli/command/completion"
 	"github.com/fitiavana07/mitrack/pkg/account"
 	"github.com/spf13/cobra"
 )
@@ -27,6 +28,7 @@ $ mitrack account register --type=asset 'Checking Account'
 
 	flags.Var(newAccountTypeValue(&options.accountType), "type", "account type (asset|liability|equity|expense|revenue)")
 	cmd.MarkFlagRequired("type")
+	cmd.RegisterFlagCompletionFunc("type", completion.AccountTypes)
 
 	return cmd
 }
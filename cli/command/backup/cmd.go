@@ -0,0 +1,20 @@
+package backup
+
+import (
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+// NewBackupCommand returns a cobra command for `backup` subcommands.
+func NewBackupCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Create and restore backups",
+		Args:  cobra.NoArgs,
+	}
+	cmd.AddCommand(
+		NewCreateCommand(mitrackCli),
+		NewRestoreCommand(mitrackCli),
+	)
+	return cmd
+}
@@ -0,0 +1,69 @@
+package backup
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+// NewCreateCommand returns a new `mitrack backup create` command.
+func NewCreateCommand(mitrackCli cli.Cli) *cobra.Command {
+	options := createOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a backup archive of accounts, transactions and config",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCreate(cmd, mitrackCli, options)
+		},
+		Example: `
+$ mitrack backup create
+$ mitrack backup create --out ledger.tar.gz
+$ mitrack backup create --out - | ssh backup-host 'cat > ledger.tar.gz'
+`,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&options.out, "out", "", `archive file ("-" for stdout; default "mitrack-backup-DATE.tar.gz")`)
+
+	return cmd
+}
+
+func runCreate(cmd *cobra.Command, mitrackCli cli.Cli, options createOptions) error {
+	out := options.out
+	if out == "" {
+		out = fmt.Sprintf("mitrack-backup-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+	}
+
+	var w io.Writer = cmd.OutOrStdout()
+	if out != "-" {
+		f, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	m, err := cli.BackupWorkdir(mitrackCli.Workdir(), mitrackCli.Ledger(), w)
+	if err != nil {
+		if out != "-" {
+			os.Remove(out)
+		}
+		return err
+	}
+
+	if out != "-" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%d files saved into %s\n", len(m.Files), out)
+	}
+	return nil
+}
+
+type createOptions struct {
+	out string
+}
@@ -0,0 +1,40 @@
+package backup
+
+import (
+	"fmt"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+// NewRestoreCommand returns a new `mitrack backup restore` command.
+func NewRestoreCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore FILE",
+		Short: "Replace accounts, transactions and config by the content of a backup",
+		Long: `Replace accounts, transactions and config by the content of a backup archive.
+
+The archive is verified against its manifest before anything is replaced,
+and the current data is kept aside.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRestore(cmd, mitrackCli, args[0])
+		},
+		Example: `
+$ mitrack backup restore mitrack-backup-20261019T103000Z.tar.gz
+`,
+	}
+
+	return cmd
+}
+
+func runRestore(cmd *cobra.Command, mitrackCli cli.Cli, archivePath string) error {
+	old, err := cli.RestoreWorkdir(mitrackCli.Workdir(), mitrackCli.Ledger(), archivePath)
+	if err != nil {
+		return err
+	}
+	if old != "" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "previous data kept in %s\n", old)
+	}
+	return nil
+}
@@ -3,7 +3,15 @@ package command
 import (
 	"github.com/fitiavana07/mitrack/cli"
 	"github.com/fitiavana07/mitrack/cli/command/account"
+	"github.com/fitiavana07/mitrack/cli/command/backup"
+	"github.com/fitiavana07/mitrack/cli/command/completion"
+	"github.com/fitiavana07/mitrack/cli/command/config"
+	"github.com/fitiavana07/mitrack/cli/command/db"
+	"github.com/fitiavana07/mitrack/cli/command/ledger"
+	"github.com/fitiavana07/mitrack/cli/command/query"
+	"github.com/fitiavana07/mitrack/cli/command/search"
 	"github.com/fitiavana07/mitrack/cli/command/transaction"
+	"github.com/fitiavana07/mitrack/cli/command/tui"
 	"github.com/spf13/cobra"
 )
 
@@ -12,5 +20,13 @@ func AddCommands(cmd *cobra.Command, mitrackCli cli.Cli) {
 	cmd.AddCommand(
 		account.NewAccountCommand(mitrackCli),
 		transaction.NewTransactionCommand(mitrackCli),
+		db.NewDBCommand(mitrackCli),
+		backup.NewBackupCommand(mitrackCli),
+		config.NewConfigCommand(mitrackCli),
+		ledger.NewLedgerCommand(mitrackCli),
+		search.NewSearchCommand(mitrackCli),
+		query.NewQueryCommand(mitrackCli),
+		tui.NewTuiCommand(mitrackCli),
+		completion.NewCompletionCommand(),
 	)
 }
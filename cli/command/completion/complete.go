@@ -0,0 +1,111 @@
+package completion
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/spf13/cobra"
+)
+
+// CompleteFunc is a completion function of arguments or flag values.
+type CompleteFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// maxDescribed is the number of transaction hashes above which their notes
+// are not read to describe them.
+const maxDescribed = 20
+
+// opened returns whether the ledger of mitrackCli could be opened: nothing
+// is completed otherwise.
+func opened(mitrackCli cli.Cli) bool {
+	return mitrackCli.AccService() != nil && mitrackCli.TxService() != nil
+}
+
+// aliases returns the aliases of the accounts starting with prefix. The
+// accounts which can not be read are ignored.
+func aliases(mitrackCli cli.Cli, prefix string) []string {
+	// the context of cmd is not set while completing
+	accs, _ := mitrackCli.AccService().ListContext(context.Background())
+	aliases := []string{}
+	for _, acc := range accs {
+		if strings.HasPrefix(acc.Alias, prefix) {
+			aliases = append(aliases, acc.Alias+"\t"+acc.Name)
+		}
+	}
+	return aliases
+}
+
+// Aliases completes account aliases.
+func Aliases(mitrackCli cli.Cli) CompleteFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if !opened(mitrackCli) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return aliases(mitrackCli, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// Entries completes the aliases of alias=amount lists, as the values of
+// --debit and --credit: cash-in-wallet=400,fo completes to
+// cash-in-wallet=400,food=.
+func Entries(mitrackCli cli.Cli) CompleteFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if !opened(mitrackCli) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		done, last := "", toComplete
+		if i := strings.LastIndex(toComplete, ","); i >= 0 {
+			done, last = toComplete[:i+1], toComplete[i+1:]
+		}
+		if strings.Contains(last, "=") {
+			// an amount
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		completions := []string{}
+		for _, alias := range aliases(mitrackCli, last) {
+			alias, name, _ := strings.Cut(alias, "\t")
+			completions = append(completions, done+alias+"=\t"+name)
+		}
+		return completions, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// AccountTypes completes account types.
+func AccountTypes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	types := []string{}
+	for t := account.TypeAsset; t.IsValid(); t++ {
+		if strings.HasPrefix(t.String(), toComplete) {
+			types = append(types, t.String())
+		}
+	}
+	return types, cobra.ShellCompDirectiveNoFileComp
+}
+
+// TxHashes completes the hash of a transaction, as the only argument of a
+// command. The hashes are described by the note of their transaction if
+// there are a few of them.
+func TxHashes(mitrackCli cli.Cli) CompleteFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 || !opened(mitrackCli) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		hashes, err := mitrackCli.TxService().Hashes(toComplete)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		if len(hashes) > maxDescribed {
+			return hashes, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		completions := make([]string, len(hashes))
+		for i, hash := range hashes {
+			completions[i] = hash
+			if tx, err := mitrackCli.TxService().GetByHash(hash); err == nil {
+				completions[i] += "\t" + tx.Note()
+			}
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -0,0 +1,68 @@
+package completion
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCompletionCommand returns a new `mitrack completion` command.
+func NewCompletionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "completion bash|zsh|fish|powershell",
+		Short: "Generate a shell completion script",
+		Long: `Generate the completion script of mitrack for a shell.
+
+The script completes the commands and flags, and the account aliases,
+account types and transaction hashes of the ledger in use.
+
+Bash (requires the bash-completion package):
+
+	$ source <(mitrack completion bash)
+
+	# for every session, on Linux:
+	$ mitrack completion bash > /etc/bash_completion.d/mitrack
+
+Zsh (requires compinit):
+
+	$ mitrack completion zsh > "${fpath[1]}/_mitrack"
+
+Fish:
+
+	$ mitrack completion fish > ~/.config/fish/completions/mitrack.fish
+
+PowerShell:
+
+	PS> mitrack completion powershell | Out-String | Invoke-Expression`,
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+		// the scripts do not depend on the workdir: it is not opened
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCompletion(cmd, args[0])
+		},
+		Example: `
+$ source <(mitrack completion bash)
+$ mitrack completion zsh > "${fpath[1]}/_mitrack"
+`,
+	}
+
+	return cmd
+}
+
+func runCompletion(cmd *cobra.Command, shell string) error {
+	root, w := cmd.Root(), cmd.OutOrStdout()
+	switch shell {
+	case "bash":
+		return root.GenBashCompletion(w)
+	case "zsh":
+		return root.GenZshCompletion(w)
+	case "fish":
+		return root.GenFishCompletion(w, true)
+	case "powershell":
+		return root.GenPowerShellCompletionWithDesc(w)
+	}
+	return fmt.Errorf("unsupported shell %q", shell)
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+// NewConfigCommand returns a cobra command for `config` subcommands.
+func NewConfigCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage the configuration",
+		Long: `Manage the configuration of the workdir, kept in config/config.yaml.
+
+Keys:
+  currency      currency of the amounts
+  date-format   Go time layout of the dates (ex: 2006-01-02)
+  ledger        ledger used by default
+  output        default output format`,
+		Args: cobra.NoArgs,
+		// the configured ledger may be missing, and fixed by the subcommands
+		Annotations: map[string]string{cli.WorkdirOnlyAnnotation: "true"},
+	}
+	cmd.AddCommand(
+		NewGetCommand(mitrackCli),
+		NewSetCommand(mitrackCli),
+		NewListCommand(mitrackCli),
+	)
+	return cmd
+}
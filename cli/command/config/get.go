@@ -0,0 +1,30 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+// NewGetCommand returns a new `mitrack config get` command.
+func NewGetCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get KEY",
+		Short: "Print the value of a configuration key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value, err := mitrackCli.Config().Get(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), value)
+			return nil
+		},
+		Example: `
+$ mitrack config get currency
+`,
+	}
+
+	return cmd
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/fitiavana07/mitrack/cli"
+	pkgconfig "github.com/fitiavana07/mitrack/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// NewListCommand returns a new `mitrack config ls` command.
+func NewListCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List the configuration keys and their values",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rows := [][]string{}
+			values := map[string]string{}
+			for _, key := range pkgconfig.Keys() {
+				value, err := mitrackCli.Config().Get(key)
+				if err != nil {
+					return err
+				}
+				rows = append(rows, []string{key, value})
+				values[key] = value
+			}
+			return mitrackCli.Render(cmd.OutOrStdout(), &cli.Listing{
+				Header: []string{"KEY", "VALUE"},
+				Rows:   rows,
+				Text: func(w io.Writer) error {
+					for _, row := range rows {
+						if _, err := fmt.Fprintf(w, "%s=%s\n", row[0], row[1]); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+				Value: values,
+			})
+		},
+		Example: `
+$ mitrack config ls
+`,
+	}
+
+	return cmd
+}
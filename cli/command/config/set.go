@@ -0,0 +1,28 @@
+package config
+
+import (
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+// NewSetCommand returns a new `mitrack config set` command.
+func NewSetCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set KEY VALUE",
+		Short: "Set the value of a configuration key",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			conf := mitrackCli.Config()
+			if err := conf.Set(args[0], args[1]); err != nil {
+				return err
+			}
+			return conf.Save(mitrackCli.ConfigPath())
+		},
+		Example: `
+$ mitrack config set currency MGA
+$ mitrack config set date-format 2006-01-02
+`,
+	}
+
+	return cmd
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/pkg/check"
+	"github.com/spf13/cobra"
+)
+
+type checkOptions struct {
+	repair bool
+}
+
+// NewCheckCommand returns a new `mitrack db check` command.
+func NewCheckCommand(mitrackCli cli.Cli) *cobra.Command {
+	options := checkOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Check the integrity of accounts and transactions",
+		Long: `Check the integrity of accounts and transactions: unreadable records,
+transaction file names not matching the transaction hash, references
+to accounts which do not exist, unbalanced transactions, orphan files and
+bad .dbinfo files.
+
+With --repair, unreadable records, mismatching and orphan files are moved
+into the ` + check.QuarantineDirName + ` directory of their database, once a
+snapshot of the database is saved next to it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCheck(cmd, mitrackCli, options)
+		},
+		Example: `
+$ mitrack db check
+$ mitrack db check --repair
+`,
+	}
+
+	flags := cmd.Flags()
+	flags.BoolVar(&options.repair, "repair", false, "quarantine the bad files")
+
+	return cmd
+}
+
+func runCheck(cmd *cobra.Command, mitrackCli cli.Cli, options checkOptions) error {
+	key, err := workdirKey(cmd, mitrackCli)
+	if err != nil {
+		return err
+	}
+
+	report, err := cli.CheckWorkdir(mitrackCli.LedgerDir(), key)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	rows := [][]string{}
+	for _, p := range report.Problems {
+		rows = append(rows, []string{p.Path, p.Kind.String(), p.Detail})
+	}
+	err = mitrackCli.Render(out, &cli.Listing{
+		Header: []string{"PATH", "KIND", "DETAIL"},
+		Rows:   rows,
+		Text: func(w io.Writer) error {
+			for _, p := range report.Problems {
+				fmt.Fprintln(w, p)
+			}
+			_, err := fmt.Fprintf(w, "%d accounts, %d transactions, %d problems\n",
+				report.Accounts, report.Transactions, len(report.Problems))
+			return err
+		},
+		Value: cli.NewReportView(report),
+	})
+	if err != nil {
+		return err
+	}
+
+	if report.OK() {
+		return nil
+	}
+	if options.repair {
+		if err := snapshot(cmd, mitrackCli, "repair"); err != nil {
+			return err
+		}
+		quarantined, err := check.Repair(report)
+		for _, path := range quarantined {
+			fmt.Fprintf(out, "quarantined %s\n", path)
+		}
+		if err != nil {
+			return err
+		}
+		if len(quarantined) == len(report.Problems) {
+			return nil
+		}
+	}
+	return &cli.ExitCodeError{
+		Code: cli.ExitCorrupted,
+		Err:  fmt.Errorf("%d problems found", len(report.Problems)),
+	}
+}
@@ -0,0 +1,24 @@
+package db
+
+import (
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+// NewDBCommand returns a cobra command for `db` subcommands.
+func NewDBCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Manage the mitrack database",
+		Args:  cobra.NoArgs,
+	}
+	cmd.AddCommand(
+		NewEncryptCommand(mitrackCli),
+		NewDecryptCommand(mitrackCli),
+		NewUnlockCommand(mitrackCli),
+		NewCheckCommand(mitrackCli),
+		NewReindexCommand(mitrackCli),
+		NewPackCommand(mitrackCli),
+	)
+	return cmd
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshots(t *testing.T) {
+	workdir := filepath.Join(t.TempDir(), "home")
+	mitrackCli := cli.NewMitrackCli()
+	require.NoError(t, mitrackCli.Open(workdir, ""))
+	require.NoError(t, mitrackCli.AccService().Register(account.NewAccount("Cash", account.TypeAsset)))
+
+	run := func(args ...string) error {
+		cmd := NewDBCommand(mitrackCli)
+		cmd.SetArgs(args)
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		return cmd.Execute()
+	}
+	snapshots := func(reason string) []string {
+		paths, err := filepath.Glob(filepath.Join(workdir+"-snapshots", "pre-"+reason+"-*.tar.gz"))
+		require.NoError(t, err)
+		return paths
+	}
+
+	require.NoError(t, run("pack"))
+	assert.Len(t, snapshots("pack"), 1)
+
+	require.NoError(t, os.WriteFile(filepath.Join(workdir, "accounts", "orphan"), []byte("x"), 0644))
+	require.Error(t, run("check"))
+	assert.Empty(t, snapshots("repair"), "only when repairing")
+	require.NoError(t, run("check", "--repair"))
+	assert.Len(t, snapshots("repair"), 1)
+
+	t.Setenv(cli.PassphraseEnv, "secret")
+	require.NoError(t, run("encrypt"))
+	assert.Len(t, snapshots("encrypt"), 1)
+}
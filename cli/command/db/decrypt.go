@@ -0,0 +1,77 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+// NewDecryptCommand returns a new `mitrack db decrypt` command.
+func NewDecryptCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "decrypt",
+		Short: "Decrypt accounts and transactions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDecrypt(cmd, mitrackCli)
+		},
+		Example: `
+$ mitrack db decrypt
+`,
+	}
+
+	return cmd
+}
+
+func runDecrypt(cmd *cobra.Command, mitrackCli cli.Cli) error {
+	key, err := unlock(cmd, mitrackCli)
+	if err != nil {
+		return err
+	}
+
+	if err := snapshot(cmd, mitrackCli, "decrypt"); err != nil {
+		return err
+	}
+	return cli.DecryptWorkdir(mitrackCli.LedgerDir(), key)
+}
+
+// snapshot saves a snapshot of the ledger before the operation named by
+// reason, and tells where.
+func snapshot(cmd *cobra.Command, mitrackCli cli.Cli, reason string) error {
+	path, err := cli.Snapshot(mitrackCli.Workdir(), mitrackCli.Ledger(), reason)
+	if err != nil {
+		return fmt.Errorf("could not snapshot the database: %w", err)
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "snapshot saved into %s\n", path)
+	return nil
+}
+
+// unlock returns the key of the encrypted workdir, from the environment
+// or from a prompted passphrase.
+func unlock(cmd *cobra.Command, mitrackCli cli.Cli) ([]byte, error) {
+	key, err := workdirKey(cmd, mitrackCli)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, errors.New("the database is not encrypted")
+	}
+	return key, nil
+}
+
+// workdirKey is like unlock, but returns a nil key if the workdir is not
+// encrypted.
+func workdirKey(cmd *cobra.Command, mitrackCli cli.Cli) ([]byte, error) {
+	key, err := cli.WorkdirKey(mitrackCli.LedgerDir(), "")
+	if errors.Is(err, store.ErrLocked) {
+		passphrase, err := readPassphrase(cmd, false)
+		if err != nil {
+			return nil, err
+		}
+		return cli.WorkdirKey(mitrackCli.LedgerDir(), passphrase)
+	}
+	return key, err
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+// NewEncryptCommand returns a new `mitrack db encrypt` command.
+func NewEncryptCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "encrypt",
+		Short: "Encrypt accounts and transactions with a passphrase",
+		Long: `Encrypt accounts and transactions with a key derived from a passphrase.
+
+The passphrase is read from $` + cli.PassphraseEnv + `, or prompted for.
+If the encryption is interrupted, run it again with the same passphrase.
+
+A snapshot of the database is saved first, next to it: as it is not
+encrypted, remove it once no longer needed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runEncrypt(cmd, mitrackCli)
+		},
+		Example: `
+$ mitrack db encrypt
+`,
+	}
+
+	return cmd
+}
+
+func runEncrypt(cmd *cobra.Command, mitrackCli cli.Cli) error {
+	encrypted, err := cli.IsEncrypted(mitrackCli.LedgerDir())
+	if err != nil {
+		return err
+	}
+
+	// on an encrypted database, the passphrase is the existing one:
+	// there is no need to confirm it.
+	passphrase, err := readPassphrase(cmd, !encrypted)
+	if err != nil {
+		return err
+	}
+	if err := snapshot(cmd, mitrackCli, "encrypt"); err != nil {
+		return err
+	}
+	if !encrypted {
+		fmt.Fprintln(cmd.ErrOrStderr(), "the snapshot is not encrypted: remove it once no longer needed")
+	}
+	return cli.EncryptWorkdir(mitrackCli.LedgerDir(), passphrase)
+}
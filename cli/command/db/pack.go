@@ -0,0 +1,54 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+type packOptions struct {
+	olderThan time.Duration
+}
+
+// NewPackCommand returns a new `mitrack db pack` command.
+func NewPackCommand(mitrackCli cli.Cli) *cobra.Command {
+	options := packOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "pack",
+		Short: "Pack the transaction files",
+		Long: `Move the transaction files into a compressed pack file, indexed by
+transaction hash, so that a workdir does not need a file per transaction.
+
+The existing packs are merged into the new one. New transactions are still
+recorded in their own file, until packed. A snapshot of the database is
+saved first, next to it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPack(cmd, mitrackCli, options)
+		},
+		Example: `
+$ mitrack db pack
+$ mitrack db pack --older-than 720h
+`,
+	}
+
+	flags := cmd.Flags()
+	flags.DurationVar(&options.olderThan, "older-than", 0, "only pack the transaction files older than this")
+
+	return cmd
+}
+
+func runPack(cmd *cobra.Command, mitrackCli cli.Cli, options packOptions) error {
+	if err := snapshot(cmd, mitrackCli, "pack"); err != nil {
+		return err
+	}
+	n, err := cli.PackWorkdir(mitrackCli.LedgerDir(), time.Now().Add(-options.olderThan))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%d transactions packed\n", n)
+	return nil
+}
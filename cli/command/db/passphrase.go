@@ -0,0 +1,60 @@
+package db
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// readPassphrase returns the passphrase from the cli.PassphraseEnv
+// environment variable, or prompts for it. When confirm is true, the
+// passphrase is asked twice.
+func readPassphrase(cmd *cobra.Command, confirm bool) (string, error) {
+	if passphrase := os.Getenv(cli.PassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	in := cmd.InOrStdin()
+	var lines *bufio.Reader
+	prompt := func(msg string) (string, error) {
+		fmt.Fprint(cmd.ErrOrStderr(), msg)
+		if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+			b, err := term.ReadPassword(int(f.Fd()))
+			fmt.Fprintln(cmd.ErrOrStderr())
+			return string(b), err
+		}
+		if lines == nil {
+			lines = bufio.NewReader(in)
+		}
+		line, err := lines.ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	passphrase, err := prompt("Passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", errors.New("empty passphrase")
+	}
+	if confirm {
+		again, err := prompt("Confirm passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
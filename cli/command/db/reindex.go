@@ -0,0 +1,45 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+// NewReindexCommand returns a new `mitrack db reindex` command.
+func NewReindexCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reindex",
+		Short: "Rebuild the transactions and search indexes",
+		Long: `Rebuild the indexes of transactions by date and by account, and the
+search index of accounts and transactions.
+
+They are needed after upgrading from a version without indexes, or after
+restoring a backup, otherwise every transaction is read when listing them.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReindex(cmd, mitrackCli)
+		},
+		Example: `
+$ mitrack db reindex
+`,
+	}
+
+	return cmd
+}
+
+func runReindex(cmd *cobra.Command, mitrackCli cli.Cli) error {
+	err := mitrackCli.TxService().Reindex(cmd.Context())
+	if err := cli.Warn(cmd.ErrOrStderr(), err); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "transactions reindexed")
+
+	err = cli.ReindexSearch(cmd.Context(), mitrackCli)
+	if err := cli.Warn(cmd.ErrOrStderr(), err); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), "search index rebuilt")
+	return nil
+}
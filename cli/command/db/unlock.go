@@ -0,0 +1,36 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+// NewUnlockCommand returns a new `mitrack db unlock` command.
+func NewUnlockCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unlock",
+		Short: "Print the shell commands caching the encryption key in the environment",
+		Long: `Print the shell commands setting $` + cli.KeyEnv + ` to the encryption key,
+so that the passphrase is asked only once per shell session.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runUnlock(cmd, mitrackCli)
+		},
+		Example: `
+$ eval $(mitrack db unlock)
+`,
+	}
+
+	return cmd
+}
+
+func runUnlock(cmd *cobra.Command, mitrackCli cli.Cli) error {
+	key, err := unlock(cmd, mitrackCli)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s=%x; export %s\n", cli.KeyEnv, key, cli.KeyEnv)
+	return nil
+}
@@ -0,0 +1,28 @@
+package ledger
+
+import (
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+// NewLedgerCommand returns a cobra command for `ledger` subcommands.
+func NewLedgerCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ledger",
+		Short: "Manage ledgers",
+		Long: `Manage ledgers: independent sets of accounts and transactions of the
+workdir (ex: personal, household, business).
+
+The ledger used is the one given by --ledger, or else the one set by
+` + "`mitrack ledger use`" + `. The "` + cli.DefaultLedger + `" ledger is the workdir itself.`,
+		Args: cobra.NoArgs,
+		// the configured ledger may be missing, and fixed by the subcommands
+		Annotations: map[string]string{cli.WorkdirOnlyAnnotation: "true"},
+	}
+	cmd.AddCommand(
+		NewCreateCommand(mitrackCli),
+		NewUseCommand(mitrackCli),
+		NewListCommand(mitrackCli),
+	)
+	return cmd
+}
@@ -0,0 +1,24 @@
+package ledger
+
+import (
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+// NewCreateCommand returns a new `mitrack ledger create` command.
+func NewCreateCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a new ledger",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cli.CreateLedger(mitrackCli.Workdir(), args[0])
+		},
+		Example: `
+$ mitrack ledger create business
+$ mitrack --ledger business account register --type=asset 'Cash'
+`,
+	}
+
+	return cmd
+}
@@ -0,0 +1,59 @@
+package ledger
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+// NewListCommand returns a new `mitrack ledger ls` command.
+func NewListCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ls",
+		Short: "List ledgers",
+		Long:  "List ledgers, the one in use being marked by *.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ledgers, err := cli.Ledgers(mitrackCli.Workdir())
+			if err != nil {
+				return err
+			}
+			rows := [][]string{}
+			views := []ledgerView{}
+			for _, ledger := range ledgers {
+				current := ledger == mitrackCli.Ledger()
+				mark := ""
+				if current {
+					mark = "*"
+				}
+				rows = append(rows, []string{mark, ledger})
+				views = append(views, ledgerView{Name: ledger, Current: current})
+			}
+			return mitrackCli.Render(cmd.OutOrStdout(), &cli.Listing{
+				Header: []string{"CURRENT", "NAME"},
+				Rows:   rows,
+				Text: func(w io.Writer) error {
+					for _, row := range rows {
+						if _, err := fmt.Fprintf(w, "%1s %s\n", row[0], row[1]); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+				Value: views,
+			})
+		},
+		Example: `
+$ mitrack ledger ls
+`,
+	}
+
+	return cmd
+}
+
+type ledgerView struct {
+	Name    string `json:"name" yaml:"name"`
+	Current bool   `json:"current" yaml:"current"`
+}
@@ -0,0 +1,32 @@
+package ledger
+
+import (
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+// NewUseCommand returns a new `mitrack ledger use` command.
+func NewUseCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "use NAME",
+		Short: "Set the ledger used by default",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runUse(mitrackCli, args[0])
+		},
+		Example: `
+$ mitrack ledger use household
+`,
+	}
+
+	return cmd
+}
+
+func runUse(mitrackCli cli.Cli, name string) error {
+	// the ledger is checked by the configuration
+	conf := mitrackCli.Config()
+	if err := conf.Set("ledger", name); err != nil {
+		return err
+	}
+	return conf.Save(mitrackCli.ConfigPath())
+}
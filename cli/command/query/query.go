@@ -0,0 +1,89 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/pkg/query"
+	"github.com/spf13/cobra"
+)
+
+// NewQueryCommand returns a new `mitrack query` command.
+func NewQueryCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "query QUERY",
+		Short: "Run a query over the accounts and transactions",
+		Long: `Run a query over the accounts and transactions, and print its result.
+
+	SELECT target, ... [FROM table] [WHERE condition] [GROUP BY expr, ...]
+	[ORDER BY expr [ASC|DESC], ...] [LIMIT n]
+
+The targets are expressions, optionally named by AS, or * for every column.
+Expressions are made of columns, numbers, 'strings', dates (2026-01-31),
+the operators NOT, AND, OR, = != < <= > >=, ~ (regular expression match),
++ - * /, and the functions abs, lower and upper. On the right of a
+comparison, words which are not columns are strings: type = expense.
+
+The aggregates count(*), count, sum, min, max and avg are computed over
+the groups of GROUP BY, which also groups by the selected columns, or over
+every row.
+
+Tables:
+` + tablesHelp(),
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runQuery(cmd, mitrackCli, strings.Join(args, " "))
+		},
+		Example: `
+$ mitrack query "SELECT account, sum(amount) WHERE type = expense AND date >= 2026-01-01 GROUP BY month"
+$ mitrack query "SELECT month, sum(credit) AS spent WHERE alias = cash-in-wallet GROUP BY month ORDER BY spent DESC"
+$ mitrack query "SELECT date, note, amount WHERE note ~ '(?i)vary' ORDER BY amount DESC LIMIT 10"
+$ mitrack query "SELECT type, count(*) FROM accounts GROUP BY type" --output json
+`,
+	}
+
+	return cmd
+}
+
+// tablesHelp describes the tables and their columns.
+func tablesHelp() string {
+	var b strings.Builder
+	for _, table := range []string{query.TableEntries, query.TableAccounts} {
+		fmt.Fprintf(&b, "\n  %s", table)
+		if table == query.TableEntries {
+			b.WriteString(" (default): the entries of the transactions")
+		}
+		b.WriteString("\n")
+		for _, c := range query.Tables[table] {
+			fmt.Fprintf(&b, "    %-12s %s\n", c.Name, c.Doc)
+		}
+	}
+	return b.String()
+}
+
+func runQuery(cmd *cobra.Command, mitrackCli cli.Cli, q string) error {
+	result, err := query.Run(cmd.Context(), q, mitrackCli.AccService(), mitrackCli.TxService())
+	if err := cli.Warn(cmd.ErrOrStderr(), err); err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(result.Rows))
+	values := make([]map[string]interface{}, len(result.Rows))
+	for i, row := range result.Rows {
+		rows[i] = make([]string, len(row))
+		values[i] = make(map[string]interface{}, len(row))
+		for j, v := range row {
+			if v != nil {
+				rows[i][j] = fmt.Sprint(v)
+			}
+			values[i][result.Columns[j]] = v
+		}
+	}
+
+	return mitrackCli.Render(cmd.OutOrStdout(), &cli.Listing{
+		Header: result.Columns,
+		Rows:   rows,
+		Value:  values,
+	})
+}
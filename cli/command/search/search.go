@@ -0,0 +1,156 @@
+package search
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/search"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+type searchOptions struct {
+	limit int
+}
+
+// NewSearchCommand returns a new `mitrack search` command.
+func NewSearchCommand(mitrackCli cli.Cli) *cobra.Command {
+	options := searchOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "search QUERY",
+		Short: "Search accounts and transactions",
+		Long: `Search the notes of transactions, and the names, aliases and
+descriptions of accounts, for the words of QUERY.
+
+The results contain every word of QUERY, ignoring case and diacritics
+("lalana" matches "làlana"), best matches first: a word in an alias ranks
+higher than in a name, a note, then a description.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSearch(cmd, mitrackCli, strings.Join(args, " "), options)
+		},
+		Example: `
+$ mitrack search vola namehana
+$ mitrack search --limit=5 'sabotsy'
+$ mitrack search vola --output json
+`,
+	}
+
+	flags := cmd.Flags()
+	flags.IntVar(&options.limit, "limit", 0, "show at most this number of results (0 for all)")
+
+	return cmd
+}
+
+func runSearch(cmd *cobra.Command, mitrackCli cli.Cli, query string, options searchOptions) error {
+	ix := mitrackCli.SearchIndex()
+	built, err := ix.Built()
+	if err != nil {
+		return err
+	}
+	if !built {
+		fmt.Fprintln(cmd.ErrOrStderr(), "building the search index...")
+		if err := cli.Warn(cmd.ErrOrStderr(), cli.ReindexSearch(cmd.Context(), mitrackCli)); err != nil {
+			return err
+		}
+	}
+
+	results, err := ix.Search(query)
+	if err != nil {
+		return err
+	}
+
+	conf := mitrackCli.Config()
+	accounts := map[account.ID]*account.Account{}
+	views := []cli.SearchResultView{}
+	rows := [][]string{}
+	for _, r := range results {
+		if options.limit > 0 && len(views) == options.limit {
+			break
+		}
+		v := cli.SearchResultView{Kind: r.Kind, Score: r.Score}
+		var id, date, text string
+
+		switch r.Kind {
+		case search.KindAccount:
+			acc, err := mitrackCli.AccService().GetByID(r.ID)
+			if err != nil {
+				// deleted since indexed
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: account %s: %s\n", r.ID, err)
+				continue
+			}
+			av := cli.NewAccountView(acc)
+			v.Account = &av
+			id, date = av.ID, av.Created.Local().Format(conf.DateFormat)
+			text = fmt.Sprintf("%s (%s)", av.Name, av.Alias)
+			if av.Description != "" {
+				text += ": " + av.Description
+			}
+		case search.KindTransaction:
+			tx, err := mitrackCli.TxService().GetByHash(r.ID)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: transaction %s: %s\n", r.ID, err)
+				continue
+			}
+			for _, e := range tx.Entries() {
+				if _, ok := accounts[e.AccountID()]; ok {
+					continue
+				}
+				if acc, err := mitrackCli.AccService().GetByActualID(e.AccountID()); err == nil {
+					accounts[e.AccountID()] = acc
+				}
+			}
+			tv := cli.NewTransactionView(tx, accounts)
+			v.Transaction = &tv
+			id, date, text = tv.Hash, tv.Date.Local().Format(conf.DateFormat), tv.Note
+		default:
+			continue
+		}
+
+		views = append(views, v)
+		rows = append(rows, []string{v.Kind, id, date, text})
+	}
+
+	return mitrackCli.Render(cmd.OutOrStdout(), &cli.Listing{
+		Header: []string{"KIND", "ID", "DATE", "TEXT"},
+		Rows:   rows,
+		Text: func(w io.Writer) error {
+			renderTable(w, rows, query, isTerminal(w))
+			return nil
+		},
+		Value: views,
+	})
+}
+
+// renderTable renders the results as a table, highlighting the words of
+// query in their text if highlight is true.
+func renderTable(w io.Writer, rows [][]string, query string, highlight bool) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"KIND", "ID", "DATE", "TEXT"})
+	table.SetAutoWrapText(false)
+	for _, row := range rows {
+		text := row[3]
+		if highlight {
+			text = search.Highlight(text, query, bold)
+		}
+		table.Append([]string{row[0], row[1][:8], row[2], text})
+	}
+	table.Render()
+}
+
+// isTerminal returns whether w is a terminal, which the highlighting
+// escape sequences are written to only.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+func bold(word string) string {
+	return "\x1b[1m" + word + "\x1b[0m"
+}
@@ -0,0 +1,43 @@
+package search
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchNotHighlighted(t *testing.T) {
+	mitrackCli := cli.NewMitrackCli()
+	require.NoError(t, mitrackCli.Open(t.TempDir(), ""))
+	t.Cleanup(func() {
+		assert.NoError(t, mitrackCli.Cleanup())
+	})
+	for _, acc := range []*account.Account{
+		account.NewAccount("Cash", account.TypeAsset),
+		account.NewAccount("Food", account.TypeExpense),
+	} {
+		require.NoError(t, mitrackCli.AccService().Register(acc))
+	}
+	_, err := mitrackCli.TxService().RecordFromMaps("vary sy laoka", map[string]int64{"food": 400}, map[string]int64{"cash": 400})
+	require.NoError(t, err)
+
+	for _, opts := range []cli.OutputOptions{
+		{Format: "table"},
+		{Format: "json"},
+		{Template: "{{.Transaction.Note}}"},
+	} {
+		mitrackCli.SetOutput(opts)
+		var stdout bytes.Buffer
+		cmd := NewSearchCommand(mitrackCli)
+		cmd.SetArgs([]string{"laoka"})
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&bytes.Buffer{})
+		require.NoError(t, cmd.Execute(), "%+v", opts)
+		assert.Contains(t, stdout.String(), "laoka", "%+v", opts)
+		assert.NotContains(t, stdout.String(), "\x1b[", "%+v", opts)
+	}
+}
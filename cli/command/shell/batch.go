@@ -0,0 +1,43 @@
+package shell
+
+import (
+	"context"
+	"time"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+)
+
+// shellCli is the Cli of the commands run by the shell. While a batch is
+// begun, the transactions they record are added to it instead.
+type shellCli struct {
+	cli.Cli
+	// batch is nil if no batch is begun.
+	batch *transaction.Batch
+}
+
+func (c *shellCli) TxService() transaction.TxService {
+	if c.batch == nil {
+		return c.Cli.TxService()
+	}
+	return &batchTxService{TxService: c.Cli.TxService(), batch: c.batch}
+}
+
+// batchTxService is a TxService adding the transactions it records to
+// batch.
+type batchTxService struct {
+	transaction.TxService
+	batch *transaction.Batch
+}
+
+func (s *batchTxService) RecordFromMaps(note string, debitsMap, creditsMap map[string]int64) (transaction.Transaction, error) {
+	return s.RecordFromMapsContext(context.Background(), note, debitsMap, creditsMap)
+}
+
+func (s *batchTxService) RecordFromMapsContext(ctx context.Context, note string, debitsMap, creditsMap map[string]int64) (transaction.Transaction, error) {
+	return s.RecordFromMapsAt(ctx, time.Now(), note, debitsMap, creditsMap)
+}
+
+func (s *batchTxService) RecordFromMapsAt(ctx context.Context, t time.Time, note string, debitsMap, creditsMap map[string]int64) (transaction.Transaction, error) {
+	return s.batch.RecordFromMapsAt(ctx, t, note, debitsMap, creditsMap)
+}
@@ -0,0 +1,280 @@
+package shell
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+// NewRootFunc returns a new root command of the command lines run by the
+// shell, the ledger of mitrackCli being opened.
+type NewRootFunc func(mitrackCli cli.Cli) *cobra.Command
+
+// builtins are the commands of the shell itself.
+var builtins = []string{"begin", "commit", "rollback", "exit", "quit"}
+
+// reopening are the commands after which the ledger is opened again, as
+// they change it, or the configuration, besides the services.
+var reopening = map[string]bool{
+	"backup": true,
+	"config": true,
+	"db":     true,
+	"ledger": true,
+}
+
+// NewShellCommand returns a new `mitrack shell` command, running each
+// command line on a new root command returned by newRoot.
+func NewShellCommand(mitrackCli cli.Cli, newRoot NewRootFunc) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shell",
+		Short: "Run mitrack commands in an interactive shell",
+		Long: `Run mitrack commands in an interactive shell, the ledger being opened once
+for all of them. The commands are typed without "mitrack", edited with a
+history and completed on Tab.
+
+Besides the mitrack commands, the shell runs:
+  begin      begin a batch: the transactions recorded are kept in it
+  commit     record the transactions of the batch, all of them or none
+  rollback   discard the transactions of the batch
+  exit       exit the shell, discarding the batch (also Ctrl-D)
+
+The backup, config, db and ledger commands open the ledger again once run,
+and can not be run in a batch.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runShell(cmd, mitrackCli, newRoot)
+		},
+		Example: `
+$ mitrack shell
+mitrack> tx ls --limit 5
+mitrack> begin
+mitrack (batch of 0)> tx rec -d food=400 -c cash-in-wallet=400 lunch
+mitrack (batch of 1)> tx rec -i
+mitrack (batch of 2)> commit
+`,
+	}
+	return cmd
+}
+
+// shell runs the command lines read from its input.
+type shell struct {
+	cmd     *cobra.Command
+	cli     *shellCli
+	newRoot NewRootFunc
+	lines   *cli.LineReader
+}
+
+func runShell(cmd *cobra.Command, mitrackCli cli.Cli, newRoot NewRootFunc) error {
+	sh := &shell{cmd: cmd, cli: &shellCli{Cli: mitrackCli}, newRoot: newRoot}
+	sh.lines = cli.NewLineReader(cmd.InOrStdin(), cmd.ErrOrStderr(), sh.complete)
+	for {
+		line, err := sh.lines.ReadLine(sh.prompt())
+		if errors.Is(err, io.EOF) {
+			fmt.Fprintln(cmd.ErrOrStderr())
+			return sh.exit()
+		}
+		if err != nil {
+			return err
+		}
+
+		exit, err := sh.run(line)
+		if exit {
+			if exitErr := sh.exit(); err == nil {
+				err = exitErr
+			}
+			return err
+		}
+		if err != nil {
+			fmt.Fprintln(cmd.ErrOrStderr(), "Error:", err)
+		}
+	}
+}
+
+func (sh *shell) prompt() string {
+	if sh.cli.batch != nil {
+		return fmt.Sprintf("mitrack (batch of %d)> ", sh.cli.batch.Len())
+	}
+	return "mitrack> "
+}
+
+// run runs line, and returns whether the shell exits.
+func (sh *shell) run(line string) (exit bool, err error) {
+	words, err := splitWords(line)
+	if err != nil || len(words) == 0 {
+		return false, err
+	}
+
+	for _, builtin := range builtins {
+		if words[0] == builtin && len(words) > 1 {
+			return false, fmt.Errorf("%s takes no arguments", builtin)
+		}
+	}
+	switch words[0] {
+	case "exit", "quit":
+		return true, nil
+	case "begin":
+		return false, sh.begin()
+	case "commit":
+		return false, sh.commit()
+	case "rollback":
+		return false, sh.rollback()
+	}
+	return sh.execute(words)
+}
+
+// execute runs the mitrack command of args. It returns whether the shell
+// exits, if the ledger can not be opened again.
+func (sh *shell) execute(args []string) (exit bool, err error) {
+	root := sh.newRoot(sh.cli)
+	root.SetArgs(args)
+	root.SetIn(sh.lines.Input())
+	root.SetOut(sh.cmd.OutOrStdout())
+	root.SetErr(sh.cmd.ErrOrStderr())
+
+	reopen := false
+	if cmd, _, err := root.Find(args); err == nil {
+		for cmd.HasParent() && cmd.Parent().HasParent() {
+			cmd = cmd.Parent()
+		}
+		if reopen = reopening[cmd.Name()]; reopen && sh.cli.batch != nil {
+			return false, fmt.Errorf("%s can not be run in a batch: commit or rollback it first", cmd.Name())
+		}
+	}
+
+	pending := 0
+	if sh.cli.batch != nil {
+		pending = sh.cli.batch.Len()
+	}
+	err = root.ExecuteContext(sh.cmd.Context())
+	if sh.cli.batch != nil && sh.cli.batch.Len() > pending {
+		fmt.Fprintln(sh.cmd.ErrOrStderr(), "added to the batch, recorded by commit")
+	}
+
+	if reopen {
+		if reopenErr := sh.reopen(); reopenErr != nil {
+			return true, fmt.Errorf("could not open the ledger again: %w", reopenErr)
+		}
+	}
+	return false, err
+}
+
+// reopen opens the ledger of the shell again.
+func (sh *shell) reopen() error {
+	workdir, ledger := sh.cli.Workdir(), sh.cli.Ledger()
+	if err := sh.cli.Cleanup(); err != nil {
+		return err
+	}
+	return sh.cli.Open(workdir, ledger)
+}
+
+func (sh *shell) begin() error {
+	if sh.cli.batch != nil {
+		return errors.New("a batch is already begun")
+	}
+	sh.cli.batch = sh.cli.Cli.TxService().Begin()
+	return nil
+}
+
+func (sh *shell) commit() error {
+	b := sh.cli.batch
+	if b == nil {
+		return errors.New("no batch begun")
+	}
+	n := b.Len()
+	err := b.Commit(sh.cmd.Context())
+	if b.Len() > 0 {
+		return fmt.Errorf("%w: commit again or rollback", err)
+	}
+	sh.cli.batch = nil
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(sh.cmd.ErrOrStderr(), "%d transaction(s) recorded\n", n)
+	return nil
+}
+
+func (sh *shell) rollback() error {
+	b := sh.cli.batch
+	if b == nil {
+		return errors.New("no batch begun")
+	}
+	fmt.Fprintf(sh.cmd.ErrOrStderr(), "%d transaction(s) discarded\n", b.Len())
+	b.Rollback()
+	sh.cli.batch = nil
+	return nil
+}
+
+// exit discards the batch, if any, before exiting.
+func (sh *shell) exit() error {
+	if sh.cli.batch == nil {
+		return nil
+	}
+	return sh.rollback()
+}
+
+// complete completes the word before pos in line, as the shell completion
+// of mitrack would.
+func (sh *shell) complete(line string, pos int) (string, int, bool) {
+	before := line[:pos]
+	words, err := splitWords(before)
+	if err != nil {
+		return "", 0, false
+	}
+	if len(words) == 0 || strings.HasSuffix(before, " ") {
+		words = append(words, "")
+	}
+
+	candidates, directive := sh.completions(words)
+	if directive&cobra.ShellCompDirectiveError != 0 {
+		return "", 0, false
+	}
+	newLine, newPos, ok := cli.CompleteWord(line, pos, func(string) []string {
+		return candidates
+	})
+	if ok && directive&cobra.ShellCompDirectiveNoSpace != 0 && strings.HasSuffix(newLine[:newPos], " ") {
+		newLine, newPos = newLine[:newPos-1]+newLine[newPos:], newPos-1
+	}
+	return newLine, newPos, ok
+}
+
+// completions returns the completions of the last of words, given by the
+// hidden completion command of cobra, and its directive.
+func (sh *shell) completions(words []string) ([]string, cobra.ShellCompDirective) {
+	var out bytes.Buffer
+	root := sh.newRoot(sh.cli)
+	root.SetArgs(append([]string{cobra.ShellCompRequestCmd}, words...))
+	root.SetOut(&out)
+	root.SetErr(io.Discard)
+	if err := root.ExecuteContext(sh.cmd.Context()); err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	// a completion by line, with its description after a tab, then
+	// :directive
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	last := lines[len(lines)-1]
+	directive, err := strconv.Atoi(strings.TrimPrefix(last, ":"))
+	if !strings.HasPrefix(last, ":") || err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+	candidates := []string{}
+	for _, line := range lines[:len(lines)-1] {
+		if c := strings.SplitN(line, "\t", 2)[0]; c != "" {
+			candidates = append(candidates, c)
+		}
+	}
+	if len(words) == 1 {
+		for _, builtin := range builtins {
+			if strings.HasPrefix(builtin, words[0]) {
+				candidates = append(candidates, builtin)
+			}
+		}
+	}
+	return candidates, cobra.ShellCompDirective(directive)
+}
@@ -0,0 +1,157 @@
+package shell
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/cli/command/transaction"
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitWords(t *testing.T) {
+	tt := []struct {
+		line string
+		want []string
+	}{
+		{"", []string{}},
+		{"  tx   ls ", []string{"tx", "ls"}},
+		{`tx rec -d food=400 'naka vola'`, []string{"tx", "rec", "-d", "food=400", "naka vola"}},
+		{`"it's" a\ b "\"q\"" ''`, []string{"it's", "a b", `"q"`, ""}},
+		{`x'y'"z"`, []string{"xyz"}},
+	}
+	for _, tc := range tt {
+		got, err := splitWords(tc.line)
+		require.NoError(t, err, tc.line)
+		assert.Equal(t, tc.want, got, tc.line)
+	}
+
+	for _, line := range []string{`'a`, `"a`, `a\`} {
+		_, err := splitWords(line)
+		assert.ErrorIs(t, err, errUnterminatedQuote, line)
+	}
+}
+
+func newTestCli(t *testing.T) cli.Cli {
+	mitrackCli := cli.NewMitrackCli()
+	require.NoError(t, mitrackCli.Open(t.TempDir(), ""))
+	t.Cleanup(func() {
+		assert.NoError(t, mitrackCli.Cleanup())
+	})
+
+	for _, acc := range []*account.Account{
+		account.NewAccount("Cash in Wallet", account.TypeAsset),
+		account.NewAccount("Food", account.TypeExpense),
+	} {
+		require.NoError(t, mitrackCli.AccService().Register(acc))
+	}
+	return mitrackCli
+}
+
+func newTestRoot(mitrackCli cli.Cli) *cobra.Command {
+	root := &cobra.Command{Use: "mitrack", SilenceErrors: true, SilenceUsage: true}
+	root.AddCommand(transaction.NewTransactionCommand(mitrackCli))
+	return root
+}
+
+// runLines runs the shell with lines as input, and returns its output.
+func runLines(t *testing.T, mitrackCli cli.Cli, lines ...string) string {
+	var out bytes.Buffer
+	cmd := NewShellCommand(mitrackCli, newTestRoot)
+	cmd.SetArgs([]string{})
+	cmd.SetIn(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	require.NoError(t, cmd.Execute())
+	return out.String()
+}
+
+func count(t *testing.T, mitrackCli cli.Cli) uint64 {
+	n, err := mitrackCli.TxService().Count()
+	require.NoError(t, err)
+	return n
+}
+
+func TestShell(t *testing.T) {
+	t.Run("commands", func(t *testing.T) {
+		mitrackCli := newTestCli(t)
+		out := runLines(t, mitrackCli,
+			"tx rec -d food=400 -c cash-in-wallet=400 'naka sakafo'",
+			"tx show",
+			"tx count",
+			"exit",
+			"tx count",
+		)
+		assert.Equal(t, "mitrack> mitrack> Error: accepts 1 arg(s), received 0\nmitrack> 1\nmitrack> ", out)
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		mitrackCli := newTestCli(t)
+		out := runLines(t, mitrackCli,
+			"commit",
+			"begin",
+			"begin",
+			"tx rec -d food=400 -c cash-in-wallet=400 lunch",
+			"tx rec -d food=400 -c cash-in-wallet=300 unbalanced",
+			"tx rec -i",
+			"dinner", "", "d fo 100", "c cwal 100", "", "y",
+			"tx count",
+			"commit",
+			"tx count",
+		)
+		assert.Contains(t, out, "mitrack> Error: no batch begun\n")
+		assert.Contains(t, out, "mitrack (batch of 0)> Error: a batch is already begun\n")
+		assert.Contains(t, out, "mitrack (batch of 1)> Error: transaction.service: unbalanced")
+		assert.Contains(t, out, "mitrack (batch of 2)> 0\n", "not recorded before commit")
+		assert.Contains(t, out, "mitrack (batch of 2)> 2 transaction(s) recorded\nmitrack> 2\n")
+		assert.Equal(t, uint64(2), count(t, mitrackCli))
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		mitrackCli := newTestCli(t)
+		out := runLines(t, mitrackCli,
+			"rollback",
+			"begin",
+			"tx rec -d food=400 -c cash-in-wallet=400 lunch",
+			"rollback",
+			"begin",
+			"tx rec -d food=400 -c cash-in-wallet=400 lunch",
+		)
+		assert.Contains(t, out, "mitrack> Error: no batch begun\n")
+		assert.Contains(t, out, "mitrack (batch of 1)> 1 transaction(s) discarded\nmitrack> ")
+		assert.True(t, strings.HasSuffix(out, "mitrack (batch of 1)> \n1 transaction(s) discarded\n"), "discarded at the end of the input")
+		assert.Equal(t, uint64(0), count(t, mitrackCli))
+	})
+}
+
+func TestShellComplete(t *testing.T) {
+	sh := &shell{cmd: &cobra.Command{}, cli: &shellCli{Cli: newTestCli(t)}, newRoot: newTestRoot}
+
+	tt := []struct {
+		line    string
+		want    string
+		wantPos int
+	}{
+		{"tx co", "tx count ", 9},
+		{"be", "begin ", 6},
+		{"tx rec -d fo", "tx rec -d food=", 15},
+		{"tx rec --deb -c x", "tx rec --debit  -c x", 15},
+	}
+	for _, tc := range tt {
+		pos := strings.Index(tc.line, " -c")
+		if pos < 0 {
+			pos = len(tc.line)
+		}
+		got, gotPos, ok := sh.complete(tc.line, pos)
+		assert.True(t, ok, tc.line)
+		assert.Equal(t, tc.want, got, tc.line)
+		assert.Equal(t, tc.wantPos, gotPos, tc.line)
+	}
+
+	_, _, ok := sh.complete("nope", 4)
+	assert.False(t, ok)
+}
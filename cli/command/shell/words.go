@@ -0,0 +1,64 @@
+package shell
+
+import (
+	"errors"
+	"strings"
+)
+
+// errUnterminatedQuote is returned when a quote of a line is not closed.
+var errUnterminatedQuote = errors.New("unterminated quote")
+
+// splitWords splits line into words as a POSIX shell would, without
+// expansions: words are separated by spaces, which are kept in quotes.
+// Single quotes keep the text as is, double quotes and backslashes escape
+// the next character.
+func splitWords(line string) ([]string, error) {
+	words := []string{}
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == '\\':
+			escaped, inWord = true, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errUnterminatedQuote
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
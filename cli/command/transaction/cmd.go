@@ -15,10 +15,9 @@ func NewTransactionCommand(mitrackCli cli.Cli) *cobra.Command {
 	}
 	cmd.AddCommand(
 		NewRecordCommand(mitrackCli),
-		// TODO
-		// NewCountCommand(mitrackCli),
+		NewCountCommand(mitrackCli),
 		NewListCommand(mitrackCli),
-		// NewShowCommand(mitrackCli),
+		NewShowCommand(mitrackCli),
 	)
 	return cmd
 }
@@ -0,0 +1,56 @@
+package transaction
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/spf13/cobra"
+)
+
+type countOptions struct {
+	from, to time.Time
+}
+
+// NewCountCommand returns a new `mitrack tx count` command.
+func NewCountCommand(mitrackCli cli.Cli) *cobra.Command {
+	options := countOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "count [--from=DATE] [--to=DATE]",
+		Short: "Count transactions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCount(cmd, mitrackCli, options)
+		},
+		Example: `
+$ mitrack tx count
+$ mitrack tx count --from=2022-03-01 --to=2022-03-31
+`,
+	}
+
+	flags := cmd.Flags()
+	flags.Var(newDateValue(&options.from), "from", "count only the transactions recorded from this date (YYYY-MM-DD, UTC)")
+	flags.Var(newDateValue(&options.to), "to", "count only the transactions recorded until this date, included (YYYY-MM-DD, UTC)")
+
+	return cmd
+}
+
+func runCount(cmd *cobra.Command, mitrackCli cli.Cli, options countOptions) error {
+	var count uint64
+	var err error
+	if options.from.IsZero() && options.to.IsZero() {
+		count, err = mitrackCli.TxService().Count()
+	} else {
+		to := options.to
+		if !to.IsZero() {
+			to = to.AddDate(0, 0, 1)
+		}
+		count, err = mitrackCli.TxService().CountByDate(options.from, to)
+	}
+	if err := cli.Warn(cmd.ErrOrStderr(), err); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), count)
+	return nil
+}
@@ -0,0 +1,35 @@
+package transaction
+
+import "time"
+
+// dateLayout is the layout of the dates given in flags. Dates are in UTC,
+// like the timestamps of transactions.
+const dateLayout = "2006-01-02"
+
+type dateValue time.Time
+
+func newDateValue(p *time.Time) *dateValue {
+	return (*dateValue)(p)
+}
+
+func (d *dateValue) Set(val string) error {
+	got, err := time.Parse(dateLayout, val)
+	if err != nil {
+		return err
+	}
+	*d = dateValue(got)
+	return nil
+}
+
+const dateValueType = "date"
+
+func (d *dateValue) Type() string {
+	return dateValueType
+}
+
+func (d *dateValue) String() string {
+	if time.Time(*d).IsZero() {
+		return ""
+	}
+	return time.Time(*d).Format(dateLayout)
+}
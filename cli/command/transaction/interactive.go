@@ -0,0 +1,303 @@
+package transaction
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+	"github.com/spf13/cobra"
+)
+
+// errNotRecorded is returned when the input ends before the transaction is
+// confirmed.
+var errNotRecorded = errors.New("input ended: transaction not recorded")
+
+const entriesHelp = `Enter the entries, one by line:
+  d ALIAS AMOUNT   debit the account of ALIAS
+  c ALIAS AMOUNT   credit the account of ALIAS
+ALIAS may be abbreviated ("cwal" for "cash-in-wallet"), Tab completes it.
+"u" removes the last entry, an empty line ends the entries.
+`
+
+// entryDraft is an entry of the transaction being prompted for.
+type entryDraft struct {
+	op     transaction.Operation
+	acc    *account.Account
+	amount int64
+}
+
+// recordPrompt prompts for a transaction: its note, date and entries.
+type recordPrompt struct {
+	lines    *cli.LineReader
+	out      io.Writer
+	accounts []*account.Account
+	entries  []entryDraft
+}
+
+func runRecordInteractive(cmd *cobra.Command, mitrackCli cli.Cli, options recordOptions) error {
+	tx, err := RecordInteractive(cmd, mitrackCli, options.note)
+	if err != nil {
+		return err
+	}
+	if tx == nil {
+		fmt.Fprintln(cmd.ErrOrStderr(), "transaction not recorded")
+		return nil
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "recorded %x\n", tx.Hash())
+	return nil
+}
+
+// RecordInteractive prompts for a transaction, note being the default
+// note, previews it then records it once confirmed. It returns nil if the
+// transaction is not confirmed. The prompts are written to the standard
+// error of cmd.
+func RecordInteractive(cmd *cobra.Command, mitrackCli cli.Cli, note string) (transaction.Transaction, error) {
+	accounts, err := mitrackCli.AccService().ListContext(cmd.Context())
+	if err := cli.Warn(cmd.ErrOrStderr(), err); err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, errors.New("no account to record a transaction on: register some with `mitrack account register`")
+	}
+
+	p := &recordPrompt{out: cmd.ErrOrStderr(), accounts: accounts}
+	p.lines = cli.NewLineReader(cmd.InOrStdin(), p.out, p.complete)
+
+	note, err = p.readNote(note)
+	if err != nil {
+		return nil, err
+	}
+	date, err := p.readDate()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.readEntries(); err != nil {
+		return nil, err
+	}
+
+	conf := mitrackCli.Config()
+	debit, credit := amountHeaders(conf.Currency)
+	entries := p.merged()
+	view := cli.TransactionView{Date: date.UTC(), Note: note, Entries: []cli.EntryView{}}
+	for _, e := range entries {
+		view.Entries = append(view.Entries, cli.EntryView{
+			Operation: e.op.String(),
+			AccountID: e.acc.ID.Hex(),
+			Account:   e.acc.Name,
+			Amount:    e.amount,
+		})
+	}
+	renderTable(p.out, []cli.TransactionView{view}, conf.DateFormat, debit, credit)
+
+	answer, err := p.readLine("Record this transaction? [y/N]: ")
+	if err != nil {
+		return nil, err
+	}
+	if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+		return nil, nil
+	}
+
+	debits, credits := map[string]int64{}, map[string]int64{}
+	for _, e := range entries {
+		if e.op == transaction.OpDebit {
+			debits[e.acc.Alias] = e.amount
+		} else {
+			credits[e.acc.Alias] = e.amount
+		}
+	}
+	return mitrackCli.TxService().RecordFromMapsAt(cmd.Context(), date, note, debits, credits)
+}
+
+// readLine reads a line, errNotRecorded at the end of the input.
+func (p *recordPrompt) readLine(prompt string) (string, error) {
+	line, err := p.lines.ReadLine(prompt)
+	if errors.Is(err, io.EOF) {
+		return "", errNotRecorded
+	}
+	return line, err
+}
+
+// readNote prompts for the note, def if none is given.
+func (p *recordPrompt) readNote(def string) (string, error) {
+	prompt := "Note: "
+	if def != "" {
+		prompt = fmt.Sprintf("Note [%s]: ", def)
+	}
+	for {
+		note, err := p.readLine(prompt)
+		if err != nil {
+			return "", err
+		}
+		if note = strings.TrimSpace(note); note == "" {
+			note = def
+		}
+		if note != "" {
+			return note, nil
+		}
+		fmt.Fprintln(p.out, "a note is required")
+	}
+}
+
+// readDate prompts for the date, now if none is given. The transactions of
+// another day are dated at its start, in UTC.
+func (p *recordPrompt) readDate() (time.Time, error) {
+	now := time.Now()
+	today := now.UTC().Format(dateLayout)
+	for {
+		answer, err := p.readLine(fmt.Sprintf("Date [%s]: ", today))
+		if err != nil {
+			return time.Time{}, err
+		}
+		answer = strings.TrimSpace(answer)
+		if answer == "" || answer == today {
+			return now, nil
+		}
+		date, err := time.Parse(dateLayout, answer)
+		if err == nil {
+			return date, nil
+		}
+		fmt.Fprintln(p.out, "invalid date, expected YYYY-MM-DD")
+	}
+}
+
+// readEntries prompts for the entries, until they are balanced and an empty
+// line is read.
+func (p *recordPrompt) readEntries() error {
+	fmt.Fprint(p.out, entriesHelp)
+	for {
+		debits, credits := p.totals()
+		line, err := p.readLine(fmt.Sprintf("Entry [debits %d, credits %d]: ", debits, credits))
+		if err != nil {
+			return err
+		}
+
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			if msg := p.unfinished(); msg != "" {
+				fmt.Fprintln(p.out, msg)
+				continue
+			}
+			return nil
+		case len(fields) == 1 && (fields[0] == "u" || fields[0] == "undo"):
+			if len(p.entries) > 0 {
+				p.entries = p.entries[:len(p.entries)-1]
+			}
+			continue
+		case len(fields) != 3:
+			fmt.Fprint(p.out, entriesHelp)
+			continue
+		}
+
+		e, err := p.parseEntry(fields)
+		if err != nil {
+			fmt.Fprintln(p.out, err)
+			continue
+		}
+		p.entries = append(p.entries, e)
+		fmt.Fprintf(p.out, "  %s %s (%s) %d\n", e.op, e.acc.Alias, e.acc.Name, e.amount)
+	}
+}
+
+// parseEntry parses the fields of an entry line: operation, alias and
+// amount.
+func (p *recordPrompt) parseEntry(fields []string) (entryDraft, error) {
+	e := entryDraft{}
+	switch strings.ToLower(fields[0]) {
+	case "d", "dr", "debit":
+		e.op = transaction.OpDebit
+	case "c", "cr", "credit":
+		e.op = transaction.OpCredit
+	default:
+		return e, fmt.Errorf("%q is neither d (debit) nor c (credit)", fields[0])
+	}
+
+	matches := account.MatchAlias(p.accounts, fields[1])
+	switch {
+	case len(matches) == 0:
+		return e, fmt.Errorf("no account alias matches %q", fields[1])
+	case len(matches) > 1 && !strings.EqualFold(matches[0].Alias, fields[1]):
+		aliases := []string{}
+		for i, acc := range matches {
+			if i == 5 {
+				aliases = append(aliases, "...")
+				break
+			}
+			aliases = append(aliases, acc.Alias)
+		}
+		return e, fmt.Errorf("%q matches several accounts: %s", fields[1], strings.Join(aliases, ", "))
+	}
+	e.acc = matches[0]
+
+	amount, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil || amount <= 0 {
+		return e, fmt.Errorf("invalid amount %q, expected a positive integer", fields[2])
+	}
+	e.amount = amount
+	return e, nil
+}
+
+// unfinished returns why the entries can not be recorded yet, or "".
+func (p *recordPrompt) unfinished() string {
+	debits, credits := p.totals()
+	switch {
+	case debits == 0 || credits == 0:
+		return "a transaction needs debits and credits"
+	case debits != credits:
+		return fmt.Sprintf("unbalanced: debits %d, credits %d (difference %d)", debits, credits, debits-credits)
+	}
+	return ""
+}
+
+func (p *recordPrompt) totals() (debits, credits int64) {
+	for _, e := range p.entries {
+		if e.op == transaction.OpDebit {
+			debits += e.amount
+		} else {
+			credits += e.amount
+		}
+	}
+	return debits, credits
+}
+
+// merged returns the entries, those of the same operation on the same
+// account being added together.
+func (p *recordPrompt) merged() []entryDraft {
+	merged := []entryDraft{}
+	index := map[string]int{}
+	for _, e := range p.entries {
+		key := e.op.String() + e.acc.ID.Hex()
+		if i, ok := index[key]; ok {
+			merged[i].amount += e.amount
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+// complete completes the alias of an entry line.
+func (p *recordPrompt) complete(line string, pos int) (string, int, bool) {
+	before := line[:pos]
+	word := len(strings.Fields(before))
+	if before == "" || strings.HasSuffix(before, " ") {
+		word++
+	}
+	if word != 2 {
+		return "", 0, false
+	}
+	return cli.CompleteWord(line, pos, func(prefix string) []string {
+		aliases := []string{}
+		for _, acc := range account.MatchAlias(p.accounts, prefix) {
+			aliases = append(aliases, acc.Alias)
+		}
+		return aliases
+	})
+}
@@ -0,0 +1,118 @@
+package transaction
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCli(t *testing.T) cli.Cli {
+	mitrackCli := cli.NewMitrackCli()
+	require.NoError(t, mitrackCli.Open(t.TempDir(), ""))
+	t.Cleanup(func() {
+		assert.NoError(t, mitrackCli.Cleanup())
+	})
+
+	for _, acc := range []*account.Account{
+		account.NewAccount("Cash in Wallet", account.TypeAsset),
+		account.NewAccount("Cash at Home", account.TypeAsset),
+		account.NewAccount("Food", account.TypeExpense),
+	} {
+		require.NoError(t, mitrackCli.AccService().Register(acc))
+	}
+	return mitrackCli
+}
+
+// recordInteractively runs `tx rec -i args...` with input as stdin, and
+// returns its prompts.
+func recordInteractively(t *testing.T, mitrackCli cli.Cli, input string, args ...string) (string, error) {
+	var out bytes.Buffer
+	cmd := NewRecordCommand(mitrackCli)
+	cmd.SetArgs(append([]string{"-i"}, args...))
+	cmd.SetIn(strings.NewReader(input))
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SilenceUsage = true
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestRecordInteractive(t *testing.T) {
+	t.Run("recorded", func(t *testing.T) {
+		mitrackCli := newTestCli(t)
+
+		out, err := recordInteractively(t, mitrackCli, strings.Join([]string{
+			"", // a note is required
+			"Vary",
+			"01/03/2021", // invalid date
+			"2021-03-01",
+			"d fo 300",
+			"d cash 1", // ambiguous
+			"x fo 1",   // invalid operation
+			"d fo -1",  // invalid amount
+			"c cwal 400",
+			"", // unbalanced
+			"d food 100",
+			"d food 1",
+			"u", // removes the last entry
+			"",
+			"y",
+		}, "\n"))
+		require.NoError(t, err)
+
+		assert.Contains(t, out, "a note is required")
+		assert.Contains(t, out, "invalid date")
+		assert.Contains(t, out, `"cash" matches several accounts: cash-at-home, cash-in-wallet`)
+		assert.Contains(t, out, `"x" is neither d (debit) nor c (credit)`)
+		assert.Contains(t, out, `invalid amount "-1"`)
+		assert.Contains(t, out, "Entry [debits 300, credits 400]: unbalanced: debits 300, credits 400 (difference -100)")
+		assert.Contains(t, out, "Entry [debits 401, credits 400]: Entry [debits 400, credits 400]: ")
+		// the preview, then the confirmation
+		assert.Regexp(t, `(?s)Cash in Wallet.*Vary.*Record this transaction\? \[y/N\]: recorded [0-9a-f]{64}`, out)
+
+		txs, err := mitrackCli.TxService().List()
+		require.NoError(t, err)
+		require.Len(t, txs, 1)
+		tx := txs[0]
+		assert.Equal(t, "Vary", tx.Note())
+		assert.Equal(t, time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC).Unix(), tx.Timestamp())
+
+		food, err := mitrackCli.AccService().GetByAlias("food")
+		require.NoError(t, err)
+		wallet, err := mitrackCli.AccService().GetByAlias("cash-in-wallet")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []transaction.Entry{
+			transaction.NewEntry(transaction.OpDebit, food.ID, 400),
+			transaction.NewEntry(transaction.OpCredit, wallet.ID, 400),
+		}, tx.Entries(), "the debits of food are added together")
+	})
+	t.Run("not confirmed", func(t *testing.T) {
+		mitrackCli := newTestCli(t)
+
+		out, err := recordInteractively(t, mitrackCli, "\n\nd food 10\nc cash-at-home 10\n\nn\n", "Laoka")
+		require.NoError(t, err)
+		assert.Contains(t, out, "Note [Laoka]: ")
+		assert.Contains(t, out, "transaction not recorded")
+
+		count, err := mitrackCli.TxService().Count()
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+	t.Run("input ended", func(t *testing.T) {
+		mitrackCli := newTestCli(t)
+
+		_, err := recordInteractively(t, mitrackCli, "Laoka\n\nd food 10\n")
+		assert.ErrorIs(t, err, errNotRecorded)
+
+		count, err := mitrackCli.TxService().Count()
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+}
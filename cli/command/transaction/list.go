@@ -2,76 +2,283 @@ package transaction
 
 import (
 	"fmt"
-	"os"
+	"io"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/cli/command/completion"
+	"github.com/fitiavana07/mitrack/pkg/account"
 	"github.com/fitiavana07/mitrack/pkg/transaction"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 )
 
+type listOptions struct {
+	from, to time.Time
+	accounts []string
+	min, max int64
+	note     string
+	sort     string
+	reverse  bool
+	limit    int
+	offset   int
+	compact  bool
+}
+
 // NewListCommand returns a new `mitrack tx ls` command.
 func NewListCommand(mitrackCli cli.Cli) *cobra.Command {
+	options := listOptions{}
+
 	cmd := &cobra.Command{
 		Use:   "ls",
 		Short: "List transactions",
-		Args:  cobra.NoArgs,
-		Run: func(cmd *cobra.Command, args []string) {
-			runList(mitrackCli)
+		Long: `List the transactions, by date unless sorted otherwise.
+
+The transactions on an account include the ones on its children accounts.
+Dates are in UTC, the ones of --from and --to as well as the listed ones.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runList(cmd, mitrackCli, options)
 		},
 		Example: `
 $ mitrack tx ls
+$ mitrack tx ls --from=2022-03-01 --to=2022-03-31 --account=food
+$ mitrack tx ls --note='^naka vola' --min=1000 --compact
+$ mitrack tx ls --sort=amount --reverse --limit=10
+$ mitrack tx ls --output csv
+$ mitrack tx ls --template '{{.Date}} {{.Note}}'
 `,
 	}
 
+	flags := cmd.Flags()
+	flags.Var(newDateValue(&options.from), "from", "list only the transactions recorded from this date (YYYY-MM-DD, UTC)")
+	flags.Var(newDateValue(&options.to), "to", "list only the transactions recorded until this date, included (YYYY-MM-DD, UTC)")
+	flags.StringArrayVar(&options.accounts, "account", nil, "list only the transactions on this account or its children (alias or ID, repeatable)")
+	cmd.RegisterFlagCompletionFunc("account", completion.Aliases(mitrackCli))
+	flags.Int64Var(&options.min, "min", 0, "list only the transactions of at least this amount")
+	flags.Int64Var(&options.max, "max", 0, "list only the transactions of at most this amount")
+	flags.StringVar(&options.note, "note", "", "list only the transactions whose note matches this regular expression, ignoring case")
+	flags.StringVar(&options.sort, "sort", "date", "sort the transactions by date|amount")
+	flags.BoolVar(&options.reverse, "reverse", false, "reverse the order of the transactions")
+	flags.IntVar(&options.limit, "limit", 0, "list at most this number of transactions (0 for all)")
+	flags.IntVar(&options.offset, "offset", 0, "skip this number of transactions")
+	flags.BoolVar(&options.compact, "compact", false, "list a transaction per line, in the table format")
+
 	return cmd
 }
 
-func runList(mitrackCli cli.Cli) {
-	txs := mitrackCli.TxService().List()
+// filter returns the transaction filter given by the options.
+func (options *listOptions) filter(cmd *cobra.Command, mitrackCli cli.Cli) (transaction.Filter, error) {
+	f := transaction.Filter{
+		From:    options.from,
+		Reverse: options.reverse,
+		Offset:  options.offset,
+		Limit:   options.limit,
+	}
+	if !options.to.IsZero() {
+		f.To = options.to.AddDate(0, 0, 1)
+	}
+	if cmd.Flags().Changed("min") {
+		f.MinAmount = &options.min
+	}
+	if cmd.Flags().Changed("max") {
+		f.MaxAmount = &options.max
+	}
+	if options.offset < 0 || options.limit < 0 {
+		return f, fmt.Errorf("negative --offset or --limit")
+	}
 
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"DATE", "ACCOUNTS", "Debit", "Credit"})
+	var err error
+	if f.Sort, err = transaction.ParseSortKey(options.sort); err != nil {
+		return f, err
+	}
+	if options.note != "" {
+		if f.NoteRegexp, err = regexp.Compile("(?i)" + options.note); err != nil {
+			return f, fmt.Errorf("--note: %w", err)
+		}
+	}
 
-	data := [][]string{}
+	if len(options.accounts) > 0 {
+		accounts, err := mitrackCli.AccService().ListContext(cmd.Context())
+		if err := cli.Warn(cmd.ErrOrStderr(), err); err != nil {
+			return f, err
+		}
+		for _, prefixOrAlias := range options.accounts {
+			acc, err := mitrackCli.AccService().Get(prefixOrAlias)
+			if err != nil {
+				return f, fmt.Errorf("--account %s: %w", prefixOrAlias, err)
+			}
+			f.Accounts = append(f.Accounts, account.Descendants(accounts, acc.ID)...)
+		}
+	}
+	return f, nil
+}
 
-	for _, tx := range txs {
+func runList(cmd *cobra.Command, mitrackCli cli.Cli, options listOptions) error {
+	filter, err := options.filter(cmd, mitrackCli)
+	if err != nil {
+		return err
+	}
+	it := mitrackCli.TxService().Iter(cmd.Context(), filter)
+	defer it.Close()
 
-		date := time.Unix(tx.Timestamp(), 0)
-		dateStr := date.Format(time.RFC3339)
+	accounts := map[account.ID]*account.Account{}
+	views := []cli.TransactionView{}
+	for it.Next() {
+		views = append(views, newTransactionView(cmd, mitrackCli, it.Tx(), accounts))
+	}
+	if err := cli.Warn(cmd.ErrOrStderr(), it.Err()); err != nil {
+		return err
+	}
+	return renderTransactions(cmd, mitrackCli, views, options.compact)
+}
 
-		for i, entry := range tx.Entries() {
-			acc, err := mitrackCli.AccService().GetByActualID(entry.AccountID())
-			if err != nil {
-				return
+// newTransactionView returns the view of tx, reading the accounts of its
+// entries which are not in accounts yet. The accounts which can not be read
+// are warned about.
+func newTransactionView(cmd *cobra.Command, mitrackCli cli.Cli, tx transaction.Transaction, accounts map[account.ID]*account.Account) cli.TransactionView {
+	for _, entry := range tx.Entries() {
+		if _, ok := accounts[entry.AccountID()]; ok {
+			continue
+		}
+		acc, err := mitrackCli.AccService().GetByActualID(entry.AccountID())
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "warning: %x: %s\n", tx.Hash(), err)
+			continue
+		}
+		accounts[entry.AccountID()] = acc
+	}
+	return cli.NewTransactionView(tx, accounts)
+}
+
+// renderTransactions renders the transactions in the output format, as a
+// table of a row by entry or, if compact, by transaction.
+func renderTransactions(cmd *cobra.Command, mitrackCli cli.Cli, views []cli.TransactionView, compact bool) error {
+	conf := mitrackCli.Config()
+	debit, credit := amountHeaders(conf.Currency)
+
+	// one row by entry
+	rows := [][]string{}
+	for _, v := range views {
+		for _, e := range v.Entries {
+			amounts := []string{fmt.Sprintf("%d", e.Amount), ""}
+			if e.Operation == transaction.OpCredit.String() {
+				amounts[0], amounts[1] = amounts[1], amounts[0]
+			}
+			rows = append(rows, append([]string{
+				v.Hash, v.Date.Format(conf.DateFormat), accountName(e), v.Note,
+			}, amounts...))
+		}
+	}
+
+	return mitrackCli.Render(cmd.OutOrStdout(), &cli.Listing{
+		Header: []string{"HASH", "DATE", "ACCOUNT", "NOTE", debit, credit},
+		Rows:   rows,
+		Text: func(w io.Writer) error {
+			if compact {
+				renderCompactTable(w, views, conf.DateFormat, conf.Currency)
+			} else {
+				renderTable(w, views, conf.DateFormat, debit, credit)
+			}
+			return nil
+		},
+		Value: views,
+	})
+}
+
+// amountHeaders returns the headers of the debit and credit amounts, in
+// currency if set.
+func amountHeaders(currency string) (debit, credit string) {
+	debit, credit = "DEBIT", "CREDIT"
+	if currency != "" {
+		debit, credit = debit+" ("+currency+")", credit+" ("+currency+")"
+	}
+	return debit, credit
+}
+
+// accountName returns the name of the account of e, or the short form of
+// its ID if it could not be read.
+func accountName(e cli.EntryView) string {
+	if e.Account != "" {
+		return e.Account
+	}
+	id, err := account.DecodeID(e.AccountID)
+	if err != nil {
+		return e.AccountID
+	}
+	return id.Short()
+}
+
+// renderCompactTable renders the transactions as a table of a row by
+// transaction.
+func renderCompactTable(w io.Writer, views []cli.TransactionView, dateFormat, currency string) {
+	amount := "AMOUNT"
+	if currency != "" {
+		amount += " (" + currency + ")"
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"HASH", "DATE", "DEBIT", "CREDIT", amount, "NOTE"})
+	table.SetAutoWrapText(false)
+
+	for _, v := range views {
+		var debits, credits []string
+		var total int64
+		for _, e := range v.Entries {
+			if e.Operation == transaction.OpDebit.String() {
+				debits = append(debits, accountName(e))
+				total += e.Amount
+			} else {
+				credits = append(credits, accountName(e))
 			}
-			accountName := acc.Name
-			if entry.Operation() == transaction.OpDebit {
+		}
+		table.Append([]string{
+			v.Hash[:8],
+			v.Date.Format(dateFormat),
+			strings.Join(debits, ", "),
+			strings.Join(credits, ", "),
+			fmt.Sprintf("%d", total),
+			v.Note,
+		})
+	}
+	table.Render()
+}
+
+// renderTable renders the transactions as a table: a row by entry, then
+// their note and hash.
+func renderTable(w io.Writer, views []cli.TransactionView, dateFormat, debit, credit string) {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"DATE", "ACCOUNTS", debit, credit})
+
+	data := [][]string{}
+	for _, v := range views {
+		dateStr := v.Date.Format(dateFormat)
+
+		for i, entry := range v.Entries {
+			if entry.Operation == transaction.OpDebit.String() {
 				dateCellContent := ""
 				if i == 0 {
 					dateCellContent = dateStr
 				}
 				data = append(data, []string{
 					dateCellContent,
-					accountName,
-					fmt.Sprintf("%d", entry.Amount()),
+					accountName(entry),
+					fmt.Sprintf("%d", entry.Amount),
 					"",
 				})
-			} else if entry.Operation() == transaction.OpCredit {
+			} else if entry.Operation == transaction.OpCredit.String() {
 				data = append(data, []string{
 					"",
-					accountName,
+					accountName(entry),
 					"",
-					fmt.Sprintf("%d", entry.Amount()),
+					fmt.Sprintf("%d", entry.Amount),
 				})
 			}
 		}
 
-		note := tx.Note()
-		data = append(data, []string{"Note", note, "", ""})
-		hash := fmt.Sprintf("%x", tx.Hash())
-		data = append(data, []string{"Hash", hash, "", ""})
+		data = append(data, []string{"Note", v.Note, "", ""})
+		data = append(data, []string{"Hash", v.Hash, "", ""})
 
 		data = append(data, []string{"", "", "", ""})
 	}
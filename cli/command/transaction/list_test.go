@@ -0,0 +1,59 @@
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListLocked(t *testing.T) {
+	mitrackCli := newTestCli(t)
+	_, err := mitrackCli.TxService().RecordFromMaps("lunch", map[string]int64{"food": 400}, map[string]int64{"cash-in-wallet": 400})
+	require.NoError(t, err)
+	require.NoError(t, cli.EncryptWorkdir(mitrackCli.LedgerDir(), "secret"))
+
+	t.Setenv(cli.KeyEnv, "")
+	t.Setenv(cli.PassphraseEnv, "")
+	locked := cli.NewMitrackCli()
+	require.NoError(t, locked.Open(mitrackCli.Workdir(), ""))
+
+	var stderr bytes.Buffer
+	cmd := NewListCommand(locked)
+	cmd.SetArgs([]string{})
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&stderr)
+	err = cmd.Execute()
+	assert.Equal(t, cli.ExitLocked, cli.ExitCode(err), "%v", err)
+	assert.NotContains(t, stderr.String(), "warning")
+}
+
+func TestListDatesInUTC(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("UTC-10", -10*60*60)
+	t.Cleanup(func() { time.Local = local })
+
+	mitrackCli := newTestCli(t)
+	mitrackCli.SetOutput(cli.OutputOptions{Format: "csv"})
+	for _, date := range []time.Time{
+		time.Date(2026, 2, 28, 23, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 1, 5, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 2, 5, 0, 0, 0, time.UTC),
+	} {
+		_, err := mitrackCli.TxService().RecordFromMapsAt(context.Background(), date, "lunch", map[string]int64{"food": 400}, map[string]int64{"cash-in-wallet": 400})
+		require.NoError(t, err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := NewListCommand(mitrackCli)
+	cmd.SetArgs([]string{"--from=2026-03-01", "--to=2026-03-01"})
+	cmd.SetOut(&stdout)
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, stdout.String(), "2026-03-01T05:00:00Z")
+	assert.NotContains(t, stdout.String(), "2026-02-28")
+	assert.NotContains(t, stdout.String(), "2026-03-02")
+}
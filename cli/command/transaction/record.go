@@ -1,7 +1,12 @@
 package transaction
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/cli/command/completion"
 	"github.com/spf13/cobra"
 )
 
@@ -12,9 +17,24 @@ func NewRecordCommand(mitrackCli cli.Cli) *cobra.Command {
 		Use:     "r",
 		Aliases: []string{"rec", "record"},
 		Short:   "Record a new transaction",
-		Args:    cobra.ExactArgs(1),
+		Long: `Record a new transaction, of the given note, debits and credits.
+
+With --interactive, the note, date and entries are prompted for, then the
+transaction is previewed and recorded once confirmed.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			options.note = args[0]
+			if len(args) == 1 {
+				options.note = args[0]
+			}
+			if options.interactive {
+				if cmd.Flags().Changed("debit") || cmd.Flags().Changed("credit") {
+					return errors.New("--interactive prompts for the entries: no --debit nor --credit expected")
+				}
+				return runRecordInteractive(cmd, mitrackCli, options)
+			}
+			if err := checkRecordArgs(cmd, args); err != nil {
+				return err
+			}
 			return runRecord(mitrackCli, options)
 		},
 		Example: `
@@ -22,27 +42,49 @@ $ mitrack tx rec \
 	--debit cash-in-wallet=400,cash-at-home=500 \
 	--credit checking-account=900 \
 	'naka vola sabotsy namehana'
+$ mitrack tx rec -i
 `,
 	}
 
 	flags := cmd.Flags()
 
 	flags.StringToInt64VarP(&options.debitsMap, "debit", "d", map[string]int64{}, "debit lines")
-	cmd.MarkFlagRequired("debit")
+	cmd.RegisterFlagCompletionFunc("debit", completion.Entries(mitrackCli))
 
 	flags.StringToInt64VarP(&options.creditsMap, "credit", "c", map[string]int64{}, "credit lines")
-	cmd.MarkFlagRequired("credit")
+	cmd.RegisterFlagCompletionFunc("credit", completion.Entries(mitrackCli))
+
+	flags.BoolVarP(&options.interactive, "interactive", "i", false, "prompt for the note, date and entries")
 
 	return cmd
 }
 
+// checkRecordArgs checks that the note, debits and credits are given,
+// which is only required without --interactive.
+func checkRecordArgs(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("accepts 1 arg(s), received %d", len(args))
+	}
+	missing := []string{}
+	for _, name := range []string{"credit", "debit"} {
+		if !cmd.Flags().Changed(name) {
+			missing = append(missing, `"`+name+`"`)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("required flag(s) %s not set", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
 func runRecord(mitrackCli cli.Cli, options recordOptions) error {
 	_, err := mitrackCli.TxService().RecordFromMaps(options.note, options.debitsMap, options.creditsMap)
 	return err
 }
 
 type recordOptions struct {
-	note       string
-	debitsMap  map[string]int64
-	creditsMap map[string]int64
+	note        string
+	debitsMap   map[string]int64
+	creditsMap  map[string]int64
+	interactive bool
 }
@@ -0,0 +1,40 @@
+package transaction
+
+import (
+	"fmt"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/fitiavana07/mitrack/cli/command/completion"
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/spf13/cobra"
+)
+
+// NewShowCommand returns a new `mitrack tx show` command.
+func NewShowCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show HASH",
+		Short: "Show a transaction",
+		Long: `Show the transaction of the given hash, or of a prefix of its hash if no
+other transaction starts with it.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runShow(cmd, mitrackCli, args[0])
+		},
+		ValidArgsFunction: completion.TxHashes(mitrackCli),
+		Example: `
+$ mitrack tx show 3fa4c2d1
+$ mitrack tx show 3fa4c2d1 --output json
+`,
+	}
+
+	return cmd
+}
+
+func runShow(cmd *cobra.Command, mitrackCli cli.Cli, prefix string) error {
+	tx, err := mitrackCli.TxService().Get(prefix)
+	if err != nil {
+		return fmt.Errorf("%s: %w", prefix, err)
+	}
+	view := newTransactionView(cmd, mitrackCli, tx, map[account.ID]*account.Account{})
+	return renderTransactions(cmd, mitrackCli, []cli.TransactionView{view}, false)
+}
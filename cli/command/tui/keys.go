@@ -0,0 +1,115 @@
+package tui
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// key is a key pressed: a rune, or one of the special keys below.
+type key rune
+
+// Special keys, out of the range of Unicode.
+const (
+	keyUp key = -1 - iota
+	keyDown
+	keyLeft
+	keyRight
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyUnknown
+
+	keyEnter     key = '\r'
+	keyTab       key = '\t'
+	keyEscape    key = 0x1b
+	keyBackspace key = 0x7f
+	keyCtrlC     key = 0x03
+	keyCtrlL     key = 0x0c
+)
+
+// sequences are the escape sequences of the special keys.
+var sequences = map[string]key{
+	"\x1b[A":  keyUp,
+	"\x1bOA":  keyUp,
+	"\x1b[B":  keyDown,
+	"\x1bOB":  keyDown,
+	"\x1b[C":  keyRight,
+	"\x1bOC":  keyRight,
+	"\x1b[D":  keyLeft,
+	"\x1bOD":  keyLeft,
+	"\x1b[5~": keyPageUp,
+	"\x1b[6~": keyPageDown,
+	"\x1b[H":  keyHome,
+	"\x1bOH":  keyHome,
+	"\x1b[1~": keyHome,
+	"\x1b[7~": keyHome,
+	"\x1b[F":  keyEnd,
+	"\x1bOF":  keyEnd,
+	"\x1b[4~": keyEnd,
+	"\x1b[8~": keyEnd,
+}
+
+// keyReader reads the keys pressed on a terminal in raw mode.
+type keyReader struct {
+	in      io.Reader
+	pending []key
+}
+
+// next returns the next key pressed.
+func (r *keyReader) next() (key, error) {
+	for len(r.pending) == 0 {
+		buf := make([]byte, 64)
+		n, err := r.in.Read(buf)
+		if n == 0 && err != nil {
+			return 0, err
+		}
+		r.pending = parseKeys(buf[:n])
+	}
+	k := r.pending[0]
+	r.pending = r.pending[1:]
+	return k, nil
+}
+
+// parseKeys returns the keys of b, read at once. An escape alone is the
+// escape key, otherwise it starts a sequence.
+func parseKeys(b []byte) []key {
+	keys := []key{}
+	for len(b) > 0 {
+		if b[0] == 0x1b && len(b) > 1 {
+			end := sequenceEnd(b)
+			if k, ok := sequences[string(b[:end])]; ok {
+				keys = append(keys, k)
+			} else {
+				keys = append(keys, keyUnknown)
+			}
+			b = b[end:]
+			continue
+		}
+		if b[0] == '\n' {
+			// enter, in some terminals
+			keys = append(keys, keyEnter)
+			b = b[1:]
+			continue
+		}
+		r, size := utf8.DecodeRune(b)
+		keys = append(keys, key(r))
+		b = b[size:]
+	}
+	return keys
+}
+
+// sequenceEnd returns the length of the escape sequence starting b: ESC,
+// [ or O, then parameters up to a final byte.
+func sequenceEnd(b []byte) int {
+	if b[1] != '[' && b[1] != 'O' {
+		// alt-key
+		return 2
+	}
+	for i := 2; i < len(b); i++ {
+		if b[i] >= 0x40 && b[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(b)
+}
@@ -0,0 +1,549 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+)
+
+// pane is a pane of the screen, which may have the focus.
+type pane int
+
+const (
+	paneAccounts pane = iota
+	paneRegister
+)
+
+// actionKind is what the runner does after a key: the model itself does
+// not read nor write the ledger.
+type actionKind int
+
+const (
+	actionNone actionKind = iota
+	actionQuit
+	actionReload
+	actionRecord
+	actionReverse
+)
+
+type action struct {
+	kind actionKind
+	// tx is the transaction to reverse.
+	tx transaction.Transaction
+}
+
+// accountRow is a row of the account tree.
+type accountRow struct {
+	acc   *account.Account
+	depth int
+	// ids are the IDs of the account and its descendants.
+	ids map[account.ID]bool
+	// balance is the balance of the account and its descendants, positive
+	// on the normal side of the account.
+	balance int64
+}
+
+// registerRow is a row of the register of an account.
+type registerRow struct {
+	tx transaction.Transaction
+	// change is the change of the balance of the account by tx, balance
+	// the balance once changed.
+	change, balance int64
+}
+
+// popup is a box shown over the panes.
+type popup struct {
+	title string
+	lines []string
+	// confirm is the action done if the popup is answered by y.
+	confirm action
+}
+
+// model is the state of the terminal UI.
+type model struct {
+	ledger     string
+	dateFormat string
+
+	accounts map[account.ID]*account.Account
+	txs      []transaction.Transaction
+
+	tree     []accountRow
+	register []registerRow
+
+	focus    pane
+	selected [2]int
+	offset   [2]int
+
+	// filter selects the register rows by note or account name, while
+	// typed if filtering.
+	filter    string
+	filtering bool
+
+	popup  *popup
+	status string
+
+	width, height int
+}
+
+// newModel returns the model of the accounts and transactions, by date,
+// of ledger.
+func newModel(ledger string, accounts []*account.Account, txs []transaction.Transaction) *model {
+	m := &model{
+		ledger:     ledger,
+		dateFormat: "2006-01-02",
+		accounts:   map[account.ID]*account.Account{},
+		txs:        txs,
+		width:      80,
+		height:     24,
+	}
+	for _, acc := range accounts {
+		m.accounts[acc.ID] = acc
+	}
+	m.buildTree(accounts)
+	m.buildRegister()
+	return m
+}
+
+// buildTree builds the account tree: the root accounts by type then name,
+// each one followed by its children.
+func (m *model) buildTree(accounts []*account.Account) {
+	children := map[account.ID][]*account.Account{}
+	roots := []*account.Account{}
+	for _, acc := range accounts {
+		if _, ok := m.accounts[acc.ParentID]; ok && acc.ParentID != acc.ID {
+			children[acc.ParentID] = append(children[acc.ParentID], acc)
+		} else {
+			roots = append(roots, acc)
+		}
+	}
+	byTypeAndName := func(accs []*account.Account) {
+		sort.Slice(accs, func(i, j int) bool {
+			if accs[i].Type != accs[j].Type {
+				return accs[i].Type < accs[j].Type
+			}
+			return accs[i].Name < accs[j].Name
+		})
+	}
+
+	balances := transaction.Balances(m.txs)
+	m.tree = nil
+	visited := map[account.ID]bool{}
+	var walk func(acc *account.Account, depth int) map[account.ID]bool
+	walk = func(acc *account.Account, depth int) map[account.ID]bool {
+		visited[acc.ID] = true
+		i := len(m.tree)
+		m.tree = append(m.tree, accountRow{acc: acc, depth: depth})
+
+		ids := map[account.ID]bool{acc.ID: true}
+		kids := children[acc.ID]
+		byTypeAndName(kids)
+		for _, child := range kids {
+			// a corrupted tree may have cycles
+			if !visited[child.ID] {
+				for id := range walk(child, depth+1) {
+					ids[id] = true
+				}
+			}
+		}
+
+		var balance int64
+		for id := range ids {
+			balance += balances[id]
+		}
+		if !acc.Type.IsDebitNormal() {
+			balance = -balance
+		}
+		m.tree[i].ids, m.tree[i].balance = ids, balance
+		return ids
+	}
+	byTypeAndName(roots)
+	for _, acc := range roots {
+		walk(acc, 0)
+	}
+	// the accounts of a cycle have no root
+	for _, acc := range accounts {
+		if !visited[acc.ID] {
+			walk(acc, 0)
+		}
+	}
+	m.clamp(paneAccounts)
+}
+
+// buildRegister builds the register of the selected account: the
+// transactions on it or its descendants, matching the filter, with the
+// balance once each one recorded.
+func (m *model) buildRegister() {
+	m.register = nil
+	if len(m.tree) == 0 {
+		return
+	}
+	row := m.tree[m.selected[paneAccounts]]
+	filter := strings.ToLower(m.filter)
+
+	var balance int64
+	for _, tx := range m.txs {
+		change := transaction.Change(tx, row.ids)
+		if !row.acc.Type.IsDebitNormal() {
+			change = -change
+		}
+		touched := change != 0
+		for _, e := range tx.Entries() {
+			touched = touched || row.ids[e.AccountID()]
+		}
+		if !touched {
+			continue
+		}
+		balance += change
+		if filter == "" || m.matches(tx, filter) {
+			m.register = append(m.register, registerRow{tx: tx, change: change, balance: balance})
+		}
+	}
+	// the last transactions first in sight
+	m.selected[paneRegister] = len(m.register) - 1
+	m.clamp(paneRegister)
+}
+
+// matches returns whether the note or the name of an account of tx
+// contains filter, in lower case.
+func (m *model) matches(tx transaction.Transaction, filter string) bool {
+	if strings.Contains(strings.ToLower(tx.Note()), filter) {
+		return true
+	}
+	for _, e := range tx.Entries() {
+		if acc, ok := m.accounts[e.AccountID()]; ok && strings.Contains(strings.ToLower(acc.Name), filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// keepView keeps the view of old, the model before a reload: the focus,
+// the selected account, the filter and the size of the screen.
+func (m *model) keepView(old *model) {
+	m.focus, m.filter = old.focus, old.filter
+	m.width, m.height = old.width, old.height
+	if len(old.tree) > 0 {
+		id := old.tree[old.selected[paneAccounts]].acc.ID
+		for i, row := range m.tree {
+			if row.acc.ID == id {
+				m.selected[paneAccounts] = i
+			}
+		}
+	}
+	m.offset[paneAccounts] = old.offset[paneAccounts]
+	m.clamp(paneAccounts)
+	m.buildRegister()
+}
+
+// rows returns the number of rows of p.
+func (m *model) rows(p pane) int {
+	if p == paneAccounts {
+		return len(m.tree)
+	}
+	return len(m.register)
+}
+
+// bodyHeight is the number of rows shown in the panes.
+func (m *model) bodyHeight() int {
+	// title, headers and status lines
+	if h := m.height - 3; h > 1 {
+		return h
+	}
+	return 1
+}
+
+// clamp keeps the selection of p in its rows, and in sight.
+func (m *model) clamp(p pane) {
+	n := m.rows(p)
+	if m.selected[p] >= n {
+		m.selected[p] = n - 1
+	}
+	if m.selected[p] < 0 {
+		m.selected[p] = 0
+	}
+	h := m.bodyHeight()
+	if m.selected[p] < m.offset[p] {
+		m.offset[p] = m.selected[p]
+	}
+	if m.selected[p] >= m.offset[p]+h {
+		m.offset[p] = m.selected[p] - h + 1
+	}
+	if m.offset[p] > n-h {
+		m.offset[p] = n - h
+	}
+	if m.offset[p] < 0 {
+		m.offset[p] = 0
+	}
+}
+
+// resize sets the size of the screen.
+func (m *model) resize(width, height int) {
+	m.width, m.height = width, height
+	m.clamp(paneAccounts)
+	m.clamp(paneRegister)
+}
+
+// move moves the selection of the focused pane by delta rows.
+func (m *model) move(delta int) {
+	m.selected[m.focus] += delta
+	m.clamp(m.focus)
+	if m.focus == paneAccounts {
+		m.buildRegister()
+	}
+}
+
+// selectedTx returns the transaction selected in the register, or nil.
+func (m *model) selectedTx() transaction.Transaction {
+	if len(m.register) == 0 {
+		return nil
+	}
+	return m.register[m.selected[paneRegister]].tx
+}
+
+const helpText = `Tab, ←, →  switch between the accounts and the register
+↑, ↓, PgUp, PgDn, Home, End  move
+Enter      show the selected transaction
+/          filter the register by note or account, Esc to clear
+n          record a new transaction
+r          reverse the selected transaction
+Ctrl-L     reload the ledger
+q          quit`
+
+// update handles the key k, and returns what the runner has to do.
+func (m *model) update(k key) action {
+	m.status = ""
+
+	if m.popup != nil {
+		p := m.popup
+		switch {
+		case p.confirm.kind != actionNone && (k == 'y' || k == 'Y'):
+			m.popup = nil
+			return p.confirm
+		case k == keyEscape || k == keyEnter || k == 'q' || k == 'n' || k == ' ':
+			m.popup = nil
+		case k == keyCtrlC:
+			return action{kind: actionQuit}
+		}
+		return action{}
+	}
+
+	if m.filtering {
+		switch {
+		case k == keyEnter:
+			m.filtering = false
+		case k == keyEscape:
+			m.filtering, m.filter = false, ""
+			m.buildRegister()
+		case k == keyBackspace:
+			if r := []rune(m.filter); len(r) > 0 {
+				m.filter = string(r[:len(r)-1])
+				m.buildRegister()
+			}
+		case k == keyCtrlC:
+			return action{kind: actionQuit}
+		case k >= 0 && unicode.IsPrint(rune(k)):
+			m.filter += string(rune(k))
+			m.buildRegister()
+		}
+		return action{}
+	}
+
+	page := m.bodyHeight() - 1
+	switch k {
+	case 'q', keyCtrlC:
+		return action{kind: actionQuit}
+	case keyCtrlL:
+		return action{kind: actionReload}
+	case keyTab, keyLeft, keyRight, 'h', 'l':
+		m.focus = 1 - m.focus
+	case keyUp, 'k':
+		m.move(-1)
+	case keyDown, 'j':
+		m.move(1)
+	case keyPageUp:
+		m.move(-page)
+	case keyPageDown:
+		m.move(page)
+	case keyHome:
+		m.move(-m.rows(m.focus))
+	case keyEnd:
+		m.move(m.rows(m.focus))
+	case '/':
+		m.filtering = true
+	case keyEscape:
+		if m.filter != "" {
+			m.filter = ""
+			m.buildRegister()
+		}
+	case '?':
+		m.popup = &popup{title: "Keys", lines: strings.Split(helpText, "\n")}
+	case keyEnter:
+		if m.focus == paneAccounts {
+			m.focus = paneRegister
+		} else if tx := m.selectedTx(); tx != nil {
+			m.popup = &popup{title: "Transaction", lines: m.details(tx)}
+		}
+	case 'n':
+		return action{kind: actionRecord}
+	case 'r':
+		tx := m.selectedTx()
+		if tx == nil {
+			m.status = "no transaction selected"
+			break
+		}
+		lines := append(m.details(tx), "", "Record its reversal, of the opposite entries? [y/N]")
+		m.popup = &popup{title: "Reverse", lines: lines, confirm: action{kind: actionReverse, tx: tx}}
+	}
+	return action{}
+}
+
+// details returns the lines describing tx.
+func (m *model) details(tx transaction.Transaction) []string {
+	hash := tx.Hash()
+	lines := []string{
+		"Date  " + time.Unix(tx.Timestamp(), 0).Local().Format(m.dateFormat),
+		"Note  " + tx.Note(),
+		fmt.Sprintf("Hash  %x", hash),
+		"",
+	}
+	width := 0
+	names := make([]string, len(tx.Entries()))
+	for i, e := range tx.Entries() {
+		names[i] = e.AccountID().Short()
+		if acc, ok := m.accounts[e.AccountID()]; ok {
+			names[i] = acc.Name
+		}
+		if n := len([]rune(names[i])); n > width {
+			width = n
+		}
+	}
+	lines = append(lines, fmt.Sprintf("%-*s  %10s  %10s", width, "", "DEBIT", "CREDIT"))
+	for i, e := range tx.Entries() {
+		debit, credit := fmt.Sprint(e.Amount()), ""
+		if e.Operation() == transaction.OpCredit {
+			debit, credit = credit, debit
+		}
+		lines = append(lines, fmt.Sprintf("%-*s  %10s  %10s", width, names[i], debit, credit))
+	}
+	return lines
+}
+
+// draw draws the model on s.
+func (m *model) draw(s *screen) {
+	w, h := s.width, s.height
+	treeWidth := w / 3
+	if treeWidth < 24 {
+		treeWidth = w / 2
+	}
+	regX, regWidth := treeWidth+1, w-treeWidth-1
+	body := m.bodyHeight()
+
+	title := " mitrack"
+	if m.ledger != "" {
+		title += " - " + m.ledger
+	}
+	s.text(0, 0, w, title, styleReverse)
+	hint := "? keys  q quit "
+	s.text(w-len(hint), 0, len(hint), hint, styleReverse)
+
+	// account tree
+	const amountWidth = 11
+	nameWidth := treeWidth - amountWidth
+	s.text(0, 1, nameWidth, "ACCOUNT", styleBold)
+	s.right(nameWidth, 1, amountWidth, "BALANCE", styleBold)
+	for i := 0; i < body; i++ {
+		s.text(treeWidth, 2+i, 1, "│", styleDim)
+		r := m.offset[paneAccounts] + i
+		if r >= len(m.tree) {
+			continue
+		}
+		row := m.tree[r]
+		st := m.rowStyle(paneAccounts, r)
+		s.text(0, 2+i, nameWidth, strings.Repeat("  ", row.depth)+row.acc.Name, st)
+		s.right(nameWidth, 2+i, amountWidth, fmt.Sprint(row.balance), st)
+	}
+	if len(m.tree) == 0 {
+		s.text(1, 2, treeWidth-1, "no accounts", styleDim)
+	}
+
+	// register
+	dateWidth := len(m.dateFormat)
+	noteWidth := regWidth - dateWidth - 2 - 2*amountWidth
+	s.text(regX, 1, dateWidth+1, " DATE", styleBold)
+	s.text(regX+dateWidth+2, 1, noteWidth, "NOTE", styleBold)
+	s.right(regX+dateWidth+2+noteWidth, 1, amountWidth, "AMOUNT", styleBold)
+	s.right(regX+dateWidth+2+noteWidth+amountWidth, 1, amountWidth, "BALANCE", styleBold)
+	for i := 0; i < body; i++ {
+		r := m.offset[paneRegister] + i
+		if r >= len(m.register) {
+			continue
+		}
+		row := m.register[r]
+		st := m.rowStyle(paneRegister, r)
+		date := time.Unix(row.tx.Timestamp(), 0).Local().Format(m.dateFormat)
+		s.text(regX, 2+i, dateWidth+2, " "+date, st)
+		s.text(regX+dateWidth+2, 2+i, noteWidth, row.tx.Note(), st)
+		s.right(regX+dateWidth+2+noteWidth, 2+i, amountWidth, fmt.Sprint(row.change), st)
+		s.right(regX+dateWidth+2+noteWidth+amountWidth, 2+i, amountWidth, fmt.Sprint(row.balance), st)
+	}
+	if len(m.register) == 0 {
+		s.text(regX+1, 2, regWidth-1, "no transactions", styleDim)
+	}
+
+	// status line
+	switch {
+	case m.filtering:
+		s.text(0, h-1, w, "/"+m.filter+"█", styleNormal)
+	case m.status != "":
+		s.text(0, h-1, w, m.status, styleBold)
+	case m.filter != "":
+		s.text(0, h-1, w, fmt.Sprintf("filter: %s (Esc to clear)", m.filter), styleDim)
+	default:
+		s.text(0, h-1, w, "Tab pane  Enter details  / filter  n new  r reverse  Ctrl-L reload", styleDim)
+	}
+
+	if m.popup != nil {
+		m.drawPopup(s)
+	}
+}
+
+func (m *model) rowStyle(p pane, row int) style {
+	if row != m.selected[p] {
+		return styleNormal
+	}
+	if p == m.focus {
+		return styleReverse
+	}
+	return styleBold
+}
+
+// drawPopup draws the popup in the middle of s.
+func (m *model) drawPopup(s *screen) {
+	p := m.popup
+	width := len([]rune(p.title)) + 6
+	for _, line := range p.lines {
+		if n := len([]rune(line)) + 4; n > width {
+			width = n
+		}
+	}
+	if width > s.width {
+		width = s.width
+	}
+	height := len(p.lines) + 2
+	if height > s.height {
+		height = s.height
+	}
+	x, y := (s.width-width)/2, (s.height-height)/2
+	s.box(x, y, width, height, p.title)
+	for i, line := range p.lines {
+		if i >= height-2 {
+			break
+		}
+		s.text(x+2, y+1+i, width-4, line, styleNormal)
+	}
+}
@@ -0,0 +1,197 @@
+package tui
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testTx struct {
+	hash      byte
+	timestamp int64
+	note      string
+	entries   []transaction.Entry
+}
+
+func (t *testTx) Hash() [sha256.Size]byte      { return [sha256.Size]byte{t.hash} }
+func (t *testTx) Timestamp() int64             { return t.timestamp }
+func (t *testTx) Entries() []transaction.Entry { return t.entries }
+func (t *testTx) Note() string                 { return t.note }
+
+// testModel returns the model of a ledger of cash (of wallet as a child),
+// food and salary.
+func testModel() *model {
+	cash := account.NewAccount("Cash", account.TypeAsset)
+	wallet := account.NewAccount("Wallet", account.TypeAsset)
+	wallet.ParentID = cash.ID
+	food := account.NewAccount("Food", account.TypeExpense)
+	salary := account.NewAccount("Salary", account.TypeRevenue)
+
+	day := time.Date(2022, 3, 1, 12, 0, 0, 0, time.Local).Unix()
+	txs := []transaction.Transaction{
+		&testTx{0x01, day, "salary", []transaction.Entry{
+			transaction.NewEntry(transaction.OpDebit, cash.ID, 5000),
+			transaction.NewEntry(transaction.OpCredit, salary.ID, 5000),
+		}},
+		&testTx{0x02, day + 86400, "to the wallet", []transaction.Entry{
+			transaction.NewEntry(transaction.OpDebit, wallet.ID, 1000),
+			transaction.NewEntry(transaction.OpCredit, cash.ID, 1000),
+		}},
+		&testTx{0x03, day + 2*86400, "lunch", []transaction.Entry{
+			transaction.NewEntry(transaction.OpDebit, food.ID, 300),
+			transaction.NewEntry(transaction.OpCredit, wallet.ID, 300),
+		}},
+	}
+	return newModel("default", []*account.Account{food, salary, wallet, cash}, txs)
+}
+
+func treeNames(m *model) []string {
+	names := []string{}
+	for _, row := range m.tree {
+		names = append(names, strings.Repeat(" ", row.depth)+row.acc.Name)
+	}
+	return names
+}
+
+func registerNotes(m *model) []string {
+	notes := []string{}
+	for _, row := range m.register {
+		notes = append(notes, row.tx.Note())
+	}
+	return notes
+}
+
+func TestModelTree(t *testing.T) {
+	m := testModel()
+	assert.Equal(t, []string{"Cash", " Wallet", "Food", "Salary"}, treeNames(m))
+
+	balances := []int64{}
+	for _, row := range m.tree {
+		balances = append(balances, row.balance)
+	}
+	// cash includes the wallet, salary is a revenue
+	assert.Equal(t, []int64{4700, 700, 300, 5000}, balances)
+}
+
+func TestModelRegister(t *testing.T) {
+	m := testModel()
+	assert.Equal(t, []string{"salary", "to the wallet", "lunch"}, registerNotes(m), "cash and its children")
+	// moving money into the wallet does not change the cash
+	assert.Equal(t, []int64{5000, 0, -300}, []int64{m.register[0].change, m.register[1].change, m.register[2].change})
+	assert.Equal(t, int64(4700), m.register[2].balance)
+	assert.Equal(t, 2, m.selected[paneRegister], "last transaction selected")
+
+	m.update(keyDown)
+	assert.Equal(t, []string{"to the wallet", "lunch"}, registerNotes(m), "wallet")
+	assert.Equal(t, []int64{1000, 700}, []int64{m.register[0].balance, m.register[1].balance})
+
+	m.update(keyEnd)
+	assert.Equal(t, "Salary", m.tree[m.selected[paneAccounts]].acc.Name)
+	assert.Equal(t, []int64{5000}, []int64{m.register[0].balance}, "revenue on the normal side")
+}
+
+func TestModelFilter(t *testing.T) {
+	m := testModel()
+	for _, k := range []key{'/', 'W', 'a', 'l', 'x', keyBackspace} {
+		m.update(k)
+	}
+	assert.True(t, m.filtering)
+	assert.Equal(t, "Wal", m.filter)
+	assert.Equal(t, []string{"to the wallet", "lunch"}, registerNotes(m), "by note or account name")
+	assert.Equal(t, int64(4700), m.register[1].balance, "balance of all the transactions")
+
+	m.update(keyEnter)
+	assert.False(t, m.filtering)
+	m.update('q')
+	assert.Equal(t, "Wal", m.filter, "q quits, once the filter typed")
+
+	m.update(keyEscape)
+	assert.Equal(t, "", m.filter)
+	assert.Len(t, m.register, 3)
+}
+
+func TestModelActions(t *testing.T) {
+	t.Run("details", func(t *testing.T) {
+		m := testModel()
+		assert.Equal(t, actionNone, m.update(keyEnter).kind, "focus the register")
+		assert.Equal(t, paneRegister, m.focus)
+		m.update(keyEnter)
+		require.NotNil(t, m.popup)
+		assert.Contains(t, m.popup.lines, "Note  lunch")
+		assert.Contains(t, m.popup.lines, "Hash  03"+strings.Repeat("0", 62))
+		m.update(keyEscape)
+		assert.Nil(t, m.popup)
+	})
+
+	t.Run("reverse", func(t *testing.T) {
+		m := testModel()
+		m.update(keyTab)
+		m.update(keyUp)
+		assert.Equal(t, actionNone, m.update('r').kind, "confirmed first")
+		require.NotNil(t, m.popup)
+		a := m.update('y')
+		assert.Equal(t, actionReverse, a.kind)
+		assert.Equal(t, "to the wallet", a.tx.Note())
+		assert.Nil(t, m.popup)
+
+		m.update('r')
+		assert.Equal(t, actionNone, m.update('n').kind, "not confirmed")
+		assert.Nil(t, m.popup)
+	})
+
+	t.Run("others", func(t *testing.T) {
+		m := testModel()
+		assert.Equal(t, actionRecord, m.update('n').kind)
+		assert.Equal(t, actionReload, m.update(keyCtrlL).kind)
+		assert.Equal(t, actionQuit, m.update('q').kind)
+		assert.Equal(t, actionQuit, m.update(keyCtrlC).kind)
+	})
+}
+
+func TestModelKeepView(t *testing.T) {
+	m := testModel()
+	m.update(keyDown)
+	m.update(keyTab)
+	m.filter = "lunch"
+
+	reloaded := testModel()
+	reloaded.keepView(m)
+	assert.Equal(t, "Wallet", reloaded.tree[reloaded.selected[paneAccounts]].acc.Name)
+	assert.Equal(t, paneRegister, reloaded.focus)
+	assert.Equal(t, []string{"lunch"}, registerNotes(reloaded))
+}
+
+func TestModelDraw(t *testing.T) {
+	m := testModel()
+	m.resize(80, 8)
+	s := newScreen(80, 8)
+	m.draw(s)
+	lines := strings.Split(s.String(), "\n")
+
+	assert.True(t, strings.HasPrefix(lines[0], " mitrack - default"))
+	assert.Regexp(t, `^ACCOUNT +BALANCE  DATE +NOTE +AMOUNT +BALANCE$`, lines[1])
+	assert.Regexp(t, `^Cash +4700│ 2022-03-01 salary +5000 +5000$`, lines[2])
+	assert.Regexp(t, `^  Wallet +700│ 2022-03-02 to the wallet +0 +5000$`, lines[3])
+	assert.Regexp(t, `^Food +300│ 2022-03-03 lunch +-300 +4700$`, lines[4])
+	assert.Regexp(t, `^Salary +5000│ +$`, lines[5])
+	assert.True(t, strings.HasPrefix(lines[7], "Tab pane"))
+
+	m.update('?')
+	s = newScreen(80, 30)
+	m.resize(80, 30)
+	m.draw(s)
+	assert.Contains(t, s.String(), "┌─ Keys ─")
+	assert.Contains(t, s.String(), "│ q          quit")
+}
+
+func TestParseKeys(t *testing.T) {
+	keys := parseKeys([]byte("a\x1b[A\x1b[6~\x1bOHé\n\x1bx"))
+	assert.Equal(t, []key{'a', keyUp, keyPageDown, keyHome, 'é', keyEnter, keyUnknown}, keys)
+	assert.Equal(t, []key{keyEscape}, parseKeys([]byte("\x1b")))
+}
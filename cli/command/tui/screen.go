@@ -0,0 +1,120 @@
+package tui
+
+import "strings"
+
+// style is the style of a cell.
+type style uint8
+
+const (
+	styleNormal style = iota
+	styleBold
+	styleReverse
+	styleDim
+)
+
+// sgr are the escape sequences selecting the styles.
+var sgr = map[style]string{
+	styleNormal:  "\x1b[0m",
+	styleBold:    "\x1b[0;1m",
+	styleReverse: "\x1b[0;7m",
+	styleDim:     "\x1b[0;2m",
+}
+
+type cell struct {
+	r     rune
+	style style
+}
+
+// screen is a grid of cells, drawn then written at once. Every rune takes
+// one cell.
+type screen struct {
+	width, height int
+	cells         [][]cell
+}
+
+func newScreen(width, height int) *screen {
+	s := &screen{width: width, height: height, cells: make([][]cell, height)}
+	for y := range s.cells {
+		s.cells[y] = make([]cell, width)
+		for x := range s.cells[y] {
+			s.cells[y][x] = cell{' ', styleNormal}
+		}
+	}
+	return s
+}
+
+// text writes str at x, y, truncated or padded with spaces to width cells.
+func (s *screen) text(x, y, width int, str string, st style) {
+	if y < 0 || y >= s.height {
+		return
+	}
+	runes := []rune(str)
+	for i := 0; i < width && x+i < s.width; i++ {
+		if x+i < 0 {
+			continue
+		}
+		r := ' '
+		if i < len(runes) {
+			r = runes[i]
+			if r < ' ' {
+				r = ' '
+			}
+		}
+		s.cells[y][x+i] = cell{r, st}
+	}
+}
+
+// right writes str at x, y, aligned to the right of width cells.
+func (s *screen) right(x, y, width int, str string, st style) {
+	if n := len([]rune(str)); n < width {
+		str = strings.Repeat(" ", width-n) + str
+	}
+	s.text(x, y, width, str, st)
+}
+
+// box draws a box of the given size, titled, and clears its inside.
+func (s *screen) box(x, y, width, height int, title string) {
+	s.text(x, y, width, "┌"+strings.Repeat("─", width-2)+"┐", styleBold)
+	if title != "" {
+		s.text(x+2, y, len([]rune(title))+2, " "+title+" ", styleBold)
+	}
+	for i := 1; i < height-1; i++ {
+		s.text(x, y+i, 1, "│", styleBold)
+		s.text(x+1, y+i, width-2, "", styleNormal)
+		s.text(x+width-1, y+i, 1, "│", styleBold)
+	}
+	s.text(x, y+height-1, width, "└"+strings.Repeat("─", width-2)+"┘", styleBold)
+}
+
+// String returns the text of the screen, without styles.
+func (s *screen) String() string {
+	var b strings.Builder
+	for _, line := range s.cells {
+		for _, c := range line {
+			b.WriteRune(c.r)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// ansi returns the escape sequences drawing the screen on a terminal.
+func (s *screen) ansi() string {
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for y, line := range s.cells {
+		if y > 0 {
+			b.WriteString("\r\n")
+		}
+		current := style(255)
+		for _, c := range line {
+			if c.style != current {
+				current = c.style
+				b.WriteString(sgr[current])
+			}
+			b.WriteRune(c.r)
+		}
+	}
+	b.WriteString(sgr[styleNormal])
+	return b.String()
+}
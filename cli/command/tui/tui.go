@@ -0,0 +1,208 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fitiavana07/mitrack/cli"
+	txcommand "github.com/fitiavana07/mitrack/cli/command/transaction"
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+const (
+	// enterFullScreen switches to the alternate screen and hides the cursor.
+	enterFullScreen = "\x1b[?1049h\x1b[?25l"
+	// leaveFullScreen shows the cursor and switches back to the main screen.
+	leaveFullScreen = "\x1b[?25h\x1b[?1049l"
+)
+
+// NewTuiCommand returns a new `mitrack tui` command.
+func NewTuiCommand(mitrackCli cli.Cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Browse the ledger in a full-screen terminal UI",
+		Long: `Browse the ledger in a full-screen terminal UI: the account tree with
+the balances on the left, the register of the selected account on the right.
+
+Keys:
+` + helpText,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runTui(cmd, mitrackCli)
+		},
+		Example: `
+$ mitrack tui
+$ mitrack tui --ledger=business
+`,
+	}
+	return cmd
+}
+
+// terminal is the terminal the UI is drawn on.
+type terminal struct {
+	in    *os.File
+	out   *os.File
+	state *term.State
+}
+
+func runTui(cmd *cobra.Command, mitrackCli cli.Cli) error {
+	in, inOk := cmd.InOrStdin().(*os.File)
+	out, outOk := cmd.OutOrStdout().(*os.File)
+	if !inOk || !outOk || !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return errors.New("the terminal UI needs a terminal as standard input and output")
+	}
+	t := &terminal{in: in, out: out}
+
+	m, err := load(cmd, mitrackCli)
+	if err != nil {
+		return err
+	}
+	if err := t.enter(); err != nil {
+		return err
+	}
+	defer t.leave()
+
+	keys := &keyReader{in: in}
+	for {
+		if width, height, err := term.GetSize(int(out.Fd())); err == nil && width > 0 && height > 0 {
+			m.resize(width, height)
+		}
+		s := newScreen(m.width, m.height)
+		m.draw(s)
+		if _, err := io.WriteString(out, s.ansi()); err != nil {
+			return err
+		}
+
+		k, err := keys.next()
+		if err != nil {
+			return err
+		}
+		a := m.update(k)
+		switch a.kind {
+		case actionQuit:
+			return nil
+		case actionReload:
+			m, err = reload(cmd, mitrackCli, m, "")
+		case actionRecord:
+			// the prompts are in the main screen, as with `tx rec -i`
+			if err := t.leave(); err != nil {
+				return err
+			}
+			tx, recErr := txcommand.RecordInteractive(cmd, mitrackCli, "")
+			if err := t.enter(); err != nil {
+				return err
+			}
+			m, err = reload(cmd, mitrackCli, m, recordStatus(tx, recErr))
+		case actionReverse:
+			tx, revErr := reverse(cmd, mitrackCli, a.tx)
+			var status string
+			if revErr != nil {
+				status = revErr.Error()
+			} else {
+				status = fmt.Sprintf("recorded %x, reversal of %x", shortHash(tx), shortHash(a.tx))
+			}
+			m, err = reload(cmd, mitrackCli, m, status)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// enter puts the terminal in raw mode, and switches to the full screen.
+func (t *terminal) enter() error {
+	state, err := term.MakeRaw(int(t.in.Fd()))
+	if err != nil {
+		return err
+	}
+	t.state = state
+	_, err = io.WriteString(t.out, enterFullScreen)
+	return err
+}
+
+// leave restores the terminal, as it was before enter.
+func (t *terminal) leave() error {
+	if t.state == nil {
+		return nil
+	}
+	io.WriteString(t.out, leaveFullScreen)
+	err := term.Restore(int(t.in.Fd()), t.state)
+	t.state = nil
+	return err
+}
+
+// load returns the model of the opened ledger.
+func load(cmd *cobra.Command, mitrackCli cli.Cli) (*model, error) {
+	accounts, err := mitrackCli.AccService().ListContext(cmd.Context())
+	if err := cli.Warn(cmd.ErrOrStderr(), err); err != nil {
+		return nil, err
+	}
+
+	txs := []transaction.Transaction{}
+	it := mitrackCli.TxService().Iter(cmd.Context(), transaction.Filter{})
+	defer it.Close()
+	for it.Next() {
+		txs = append(txs, it.Tx())
+	}
+	if err := cli.Warn(cmd.ErrOrStderr(), it.Err()); err != nil {
+		return nil, err
+	}
+	return newModel(mitrackCli.Ledger(), accounts, txs), nil
+}
+
+// reload returns the model of the ledger read again, keeping the view of
+// m, and showing status.
+func reload(cmd *cobra.Command, mitrackCli cli.Cli, m *model, status string) (*model, error) {
+	// the warnings would be drawn over
+	errOut := cmd.ErrOrStderr()
+	cmd.SetErr(io.Discard)
+	reloaded, err := load(cmd, mitrackCli)
+	cmd.SetErr(errOut)
+	if err != nil {
+		return nil, err
+	}
+	reloaded.keepView(m)
+	reloaded.status = status
+	return reloaded, nil
+}
+
+// recordStatus returns the status once tx is recorded, or not.
+func recordStatus(tx transaction.Transaction, err error) string {
+	switch {
+	case err != nil:
+		return err.Error()
+	case tx == nil:
+		return "transaction not recorded"
+	}
+	return fmt.Sprintf("recorded %x", shortHash(tx))
+}
+
+// reverse records the reversal of tx: a transaction of its entries, the
+// debits credited and the credits debited.
+func reverse(cmd *cobra.Command, mitrackCli cli.Cli, tx transaction.Transaction) (transaction.Transaction, error) {
+	debits, credits := map[string]int64{}, map[string]int64{}
+	for _, e := range tx.Entries() {
+		acc, err := mitrackCli.AccService().GetByActualID(e.AccountID())
+		if err != nil {
+			return nil, fmt.Errorf("account %s: %w", e.AccountID().Short(), err)
+		}
+		if e.Operation() == transaction.OpDebit {
+			credits[acc.Alias] += e.Amount()
+		} else {
+			debits[acc.Alias] += e.Amount()
+		}
+	}
+	note := fmt.Sprintf("Reversal of %x: %s", shortHash(tx), tx.Note())
+	return mitrackCli.TxService().RecordFromMapsContext(cmd.Context(), note, debits, credits)
+}
+
+// shortHash returns the first bytes of the hash of tx, its 8 first hex
+// digits as listed by `mitrack tx ls`.
+func shortHash(tx transaction.Transaction) []byte {
+	hash := tx.Hash()
+	return hash[:4]
+}
@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+)
+
+const (
+	// keyInfoFileName is the file holding the store.KeyInfo of an encrypted
+	// workdir. Its presence is what makes a workdir encrypted.
+	keyInfoFileName = ".keyinfo"
+
+	// KeyEnv is the environment variable holding the hex key of an
+	// encrypted workdir, as printed by `mitrack db unlock`.
+	KeyEnv = "MITRACK_KEY"
+
+	// PassphraseEnv is the environment variable holding the passphrase
+	// of an encrypted workdir.
+	PassphraseEnv = "MITRACK_PASSPHRASE"
+)
+
+// IsEncrypted returns whether the workdir is encrypted.
+func IsEncrypted(workdir string) (bool, error) {
+	_, err := os.Stat(filepath.Join(workdir, keyInfoFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// WorkdirKey returns the encryption key of the workdir, derived from
+// passphrase, or, if passphrase is empty, taken from the KeyEnv or the
+// PassphraseEnv environment variable (in that order).
+// It returns a nil key if the workdir is not encrypted, and store.ErrLocked
+// if no key could be found.
+func WorkdirKey(workdir, passphrase string) ([]byte, error) {
+	ki, err := store.ReadKeyInfo(filepath.Join(workdir, keyInfoFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if passphrase == "" {
+		if hexKey := os.Getenv(KeyEnv); hexKey != "" {
+			key, err := hex.DecodeString(hexKey)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", KeyEnv, err)
+			}
+			if err := ki.Verify(key); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", KeyEnv, err)
+			}
+			return key, nil
+		}
+		passphrase = os.Getenv(PassphraseEnv)
+	}
+	if passphrase == "" {
+		return nil, store.ErrLocked
+	}
+
+	return ki.DeriveKey(passphrase)
+}
+
+// EncryptWorkdir encrypts every account and transaction of the workdir
+// with a key derived from passphrase.
+// If it is interrupted, it can be run again with the same passphrase.
+func EncryptWorkdir(workdir, passphrase string) error {
+	keyInfoPath := filepath.Join(workdir, keyInfoFileName)
+
+	var key []byte
+	ki, err := store.ReadKeyInfo(keyInfoPath)
+	if errors.Is(err, os.ErrNotExist) {
+		if ki, key, err = store.NewKeyInfo(passphrase); err != nil {
+			return err
+		}
+		// the key info is written first: from now on, the workdir is
+		// encrypted, even if some records are still in plaintext.
+		if err = store.WriteKeyInfo(keyInfoPath, ki); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	} else if key, err = ki.DeriveKey(passphrase); err != nil {
+		return err
+	}
+
+	for _, dir := range encryptedDirs(workdir) {
+		s, err := store.NewEncryptedStore(newDirStore(dir), key)
+		if err != nil {
+			return err
+		}
+		// Get returns plaintext records as is, and decrypts the ones
+		// already encrypted by a previous run.
+		if err := store.Copy(s, s); err != nil {
+			return err
+		}
+		if err := repack(dir); err != nil {
+			return err
+		}
+	}
+	return reindex(workdir, true, key)
+}
+
+// DecryptWorkdir decrypts every account and transaction of the workdir,
+// using key.
+func DecryptWorkdir(workdir string, key []byte) error {
+	for _, dir := range encryptedDirs(workdir) {
+		plain := newDirStore(dir)
+		s, err := store.NewEncryptedStore(plain, key)
+		if err != nil {
+			return err
+		}
+		if err := store.Copy(plain, s); err != nil {
+			return err
+		}
+		if err := repack(dir); err != nil {
+			return err
+		}
+	}
+	if err := reindex(workdir, false, nil); err != nil {
+		return err
+	}
+	return os.Remove(filepath.Join(workdir, keyInfoFileName))
+}
+
+// reindex rebuilds the transactions indexes of the workdir, whose keys are
+// hashed when it is encrypted.
+func reindex(workdir string, encrypted bool, key []byte) error {
+	txStore, err := openStore(filepath.Join(workdir, transactionsDirName), encrypted, key)
+	if err != nil {
+		return err
+	}
+	indexStore, err := openStore(filepath.Join(workdir, indexDirName), encrypted, key)
+	if err != nil {
+		return err
+	}
+	txService, err := transaction.NewTxServiceWithIndex(txStore, indexStore, nil)
+	if err != nil {
+		return err
+	}
+	return txService.Reindex(context.Background())
+}
+
+// recordDirs returns the directories of the workdir containing records.
+func recordDirs(workdir string) []string {
+	return []string{
+		filepath.Join(workdir, accountsDirName),
+		filepath.Join(workdir, transactionsDirName),
+	}
+}
+
+// encryptedDirs returns the directories of the workdir which are encrypted:
+// the records ones and the index one.
+func encryptedDirs(workdir string) []string {
+	return append(recordDirs(workdir), filepath.Join(workdir, indexDirName))
+}
+
+// openStore returns the store of dir, encrypted with key if the workdir is
+// encrypted (locked if key is nil).
+func openStore(dir string, encrypted bool, key []byte) (store.Store, error) {
+	s := newDirStore(dir)
+	if !encrypted {
+		return s, nil
+	}
+	return store.NewEncryptedStore(s, key)
+}
+
+// newDirStore returns the store of the files of dir. The transactions
+// directory may be packed by PackWorkdir.
+func newDirStore(dir string) store.Store {
+	if filepath.Base(dir) == transactionsDirName {
+		return store.NewPackStore(dir)
+	}
+	return store.NewDirStore(dir)
+}
+
+// repack packs again the records of dir, if it has packs, once they were
+// all copied into files: the old packs would otherwise keep their previous
+// (plaintext or encrypted) values.
+func repack(dir string) error {
+	packed, err := store.HasPacks(dir)
+	if err != nil || !packed {
+		return err
+	}
+	_, err = store.PackDir(dir, time.Now())
+	return err
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"errors"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/encoding"
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+)
+
+// Exit codes of mitrack, for scripting.
+const (
+	ExitOK = 0
+
+	// ExitError is the exit code of any error not listed below.
+	ExitError = 1
+
+	// ExitNotFound is returned when an account, a transaction or a ledger
+	// does not exist.
+	ExitNotFound = 3
+
+	// ExitCorrupted is returned when a record can not be decoded, or when
+	// `db check` finds problems.
+	ExitCorrupted = 4
+
+	// ExitUnbalanced is returned when recording an unbalanced transaction.
+	ExitUnbalanced = 5
+
+	// ExitLocked is returned when the workdir is encrypted and the key is
+	// missing or wrong.
+	ExitLocked = 6
+)
+
+// ExitCodeError is an error with an explicit exit code.
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitCodeError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of mitrack for err.
+func ExitCode(err error) int {
+	var exitErr *ExitCodeError
+	var decodeErr *encoding.DecodeError
+	var unbalancedErr *transaction.UnbalancedError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &exitErr):
+		return exitErr.Code
+	case errors.Is(err, account.ErrAccountNotFound), errors.Is(err, transaction.ErrTxNotFound),
+		errors.Is(err, ErrLedgerNotFound):
+		return ExitNotFound
+	case errors.As(err, &decodeErr):
+		return ExitCorrupted
+	case errors.As(err, &unbalancedErr):
+		return ExitUnbalanced
+	case errors.Is(err, store.ErrLocked), errors.Is(err, store.ErrWrongKey):
+		return ExitLocked
+	default:
+		return ExitError
+	}
+}
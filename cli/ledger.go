@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/fitiavana07/mitrack/pkg/config"
+)
+
+// Ledgers are independent sets of accounts and transactions of a workdir.
+//
+// The DefaultLedger is the workdir itself, the other ones are in the
+// ledgersDirName directory of the workdir. Each ledger directory is laid
+// out like a workdir: the functions of this package taking a workdir (to
+// encrypt, check, pack...) take the directory of a ledger.
+// The configuration is shared by all the ledgers of the workdir: the
+// backup functions take the workdir and the name of the ledger, to save
+// and restore it too.
+
+// DefaultLedger is the name of the ledger of the workdir itself.
+const DefaultLedger = "default"
+
+const ledgersDirName = "ledgers"
+
+// LedgerDir returns the directory of the named ledger of the workdir.
+func LedgerDir(workdir, name string) (string, error) {
+	if !config.IsLedgerName(name) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidLedgerName, name)
+	}
+	if name == DefaultLedger {
+		return workdir, nil
+	}
+	return filepath.Join(workdir, ledgersDirName, name), nil
+}
+
+// CreateLedger creates the named ledger of the workdir.
+func CreateLedger(workdir, name string) error {
+	dir, err := LedgerDir(workdir, name)
+	if err != nil {
+		return err
+	}
+	if exists, err := isLedgerDir(dir); err != nil {
+		return err
+	} else if exists {
+		return fmt.Errorf("%w: %q", ErrLedgerExists, name)
+	}
+	return createLedgerDirs(dir)
+}
+
+// Ledgers returns the names of the ledgers of the workdir, the default one
+// first.
+func Ledgers(workdir string) ([]string, error) {
+	dirEntries, err := os.ReadDir(filepath.Join(workdir, ledgersDirName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, entry := range dirEntries {
+		name := entry.Name()
+		if !entry.IsDir() || name == DefaultLedger || !config.IsLedgerName(name) {
+			continue
+		}
+		// ex: the snapshots of a ledger are next to it
+		if ok, err := isLedgerDir(filepath.Join(workdir, ledgersDirName, name)); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{DefaultLedger}, names...), nil
+}
+
+// checkLedger returns ErrLedgerNotFound if the named ledger of the workdir
+// does not exist.
+func checkLedger(workdir, name string) error {
+	dir, err := LedgerDir(workdir, name)
+	if err != nil {
+		return err
+	}
+	if ok, err := isLedgerDir(dir); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("%w: %q", ErrLedgerNotFound, name)
+	}
+	return nil
+}
+
+// isLedgerDir returns whether dir is the directory of a ledger.
+func isLedgerDir(dir string) (bool, error) {
+	info, err := os.Stat(filepath.Join(dir, accountsDirName))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
+// createLedgerDirs creates the directories of the ledger of dir, if needed.
+func createLedgerDirs(dir string) error {
+	for _, d := range []string{
+		dir,
+		filepath.Join(dir, accountsDirName),
+		filepath.Join(dir, transactionsDirName),
+		filepath.Join(dir, indexDirName),
+	} {
+		if err := os.MkdirAll(d, os.ModePerm); err != nil && !errors.Is(err, os.ErrExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	// ErrInvalidLedgerName is returned for ledger names which are not
+	// made of lowercase letters, digits, - and _.
+	ErrInvalidLedgerName = errors.New("invalid ledger name")
+
+	// ErrLedgerExists is returned when creating a ledger which exists.
+	ErrLedgerExists = errors.New("ledger already exists")
+
+	// ErrLedgerNotFound is returned when opening a ledger which does not
+	// exist.
+	ErrLedgerNotFound = errors.New("ledger not found")
+)
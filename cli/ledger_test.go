@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedgerDir(t *testing.T) {
+	workdir := t.TempDir()
+
+	dir, err := LedgerDir(workdir, DefaultLedger)
+	assert.NoError(t, err)
+	assert.Equal(t, workdir, dir)
+
+	dir, err = LedgerDir(workdir, "business")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(workdir, "ledgers", "business"), dir)
+
+	for _, name := range []string{"", ".", "..", "a/b", "../a", "Business", "-a"} {
+		_, err := LedgerDir(workdir, name)
+		assert.ErrorIs(t, err, ErrInvalidLedgerName, name)
+	}
+}
+
+func TestLedgers(t *testing.T) {
+	workdir := t.TempDir()
+	require.NoError(t, createLedgerDirs(workdir))
+
+	ledgers, err := Ledgers(workdir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{DefaultLedger}, ledgers)
+
+	require.NoError(t, CreateLedger(workdir, "household"))
+	require.NoError(t, CreateLedger(workdir, "business"))
+	assert.ErrorIs(t, CreateLedger(workdir, "business"), ErrLedgerExists)
+	assert.ErrorIs(t, CreateLedger(workdir, DefaultLedger), ErrLedgerExists)
+	assert.ErrorIs(t, CreateLedger(workdir, ".."), ErrInvalidLedgerName)
+
+	// not ledgers
+	require.NoError(t, os.MkdirAll(filepath.Join(workdir, "ledgers", "business-snapshots"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(workdir, "ledgers", "file"), nil, 0644))
+
+	ledgers, err = Ledgers(workdir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{DefaultLedger, "business", "household"}, ledgers)
+}
+
+func TestOpenLedger(t *testing.T) {
+	workdir := t.TempDir()
+	mitrackCli := NewMitrackCli()
+	require.NoError(t, mitrackCli.Open(workdir, ""))
+	assert.Equal(t, DefaultLedger, mitrackCli.Ledger())
+	require.NoError(t, mitrackCli.Cleanup())
+
+	assert.ErrorIs(t, mitrackCli.Open(workdir, "business"), ErrLedgerNotFound)
+	require.NoError(t, CreateLedger(workdir, "business"))
+	require.NoError(t, mitrackCli.Open(workdir, "business"))
+	require.NoError(t, mitrackCli.AccService().Register(account.NewAccount("Cash", account.TypeAsset)))
+	require.NoError(t, mitrackCli.Cleanup())
+
+	t.Run("use", func(t *testing.T) {
+		require.NoError(t, mitrackCli.Open(workdir, ""))
+		conf := mitrackCli.Config()
+		assert.ErrorIs(t, conf.Set("ledger", "nope"), ErrLedgerNotFound)
+		assert.Error(t, conf.Set("ledger", ".."))
+		require.NoError(t, conf.Set("ledger", "business"))
+		require.NoError(t, conf.Save(mitrackCli.ConfigPath()))
+		require.NoError(t, mitrackCli.Cleanup())
+
+		require.NoError(t, mitrackCli.Open(workdir, ""))
+		assert.Equal(t, "business", mitrackCli.Ledger())
+		assert.Equal(t, filepath.Join(workdir, "ledgers", "business"), mitrackCli.LedgerDir())
+		n, err := mitrackCli.AccService().Count()
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(1), n)
+		require.NoError(t, mitrackCli.Cleanup())
+	})
+
+	t.Run("missing configured ledger", func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(filepath.Join(workdir, "ledgers", "business")))
+
+		assert.ErrorIs(t, mitrackCli.Open(workdir, ""), ErrLedgerNotFound)
+		require.NoError(t, mitrackCli.OpenWorkdir(workdir, ""))
+		assert.Equal(t, "business", mitrackCli.Ledger())
+		conf := mitrackCli.Config()
+		require.NoError(t, conf.Set("ledger", DefaultLedger))
+		require.NoError(t, conf.Save(mitrackCli.ConfigPath()))
+
+		require.NoError(t, mitrackCli.Open(workdir, ""))
+		assert.Equal(t, DefaultLedger, mitrackCli.Ledger())
+		require.NoError(t, mitrackCli.Cleanup())
+	})
+}
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"text/template"
+
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats.
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputCSV   = "csv"
+	OutputTSV   = "tsv"
+	OutputYAML  = "yaml"
+)
+
+// OutputOptions are the options of the output of the commands, given by
+// the global flags.
+type OutputOptions struct {
+	// Format is one of the output formats, the configured one if empty.
+	Format string
+
+	// Template is a Go text/template executed for each item of the output,
+	// instead of formatting it. See Listing.Value.
+	Template string
+}
+
+// Listing is the output of a command, rendered by Render.
+type Listing struct {
+	// Header and Rows are rendered by the table, csv and tsv formats.
+	Header []string
+	Rows   [][]string
+
+	// Text renders the table format instead of Header and Rows, if set.
+	Text func(w io.Writer) error
+
+	// Value is rendered by the json and yaml formats, and by templates.
+	// Templates are executed for each element of Value if it is a slice,
+	// once for Value otherwise.
+	Value interface{}
+}
+
+// Render writes l into w, in the format of opts.
+func Render(w io.Writer, opts OutputOptions, l *Listing) error {
+	if opts.Template != "" {
+		return renderTemplate(w, opts.Template, l.Value)
+	}
+
+	switch opts.Format {
+	case OutputTable, "":
+		if l.Text != nil {
+			return l.Text(w)
+		}
+		table := tablewriter.NewWriter(w)
+		table.SetHeader(l.Header)
+		table.SetAutoWrapText(false)
+		table.AppendBulk(l.Rows)
+		table.Render()
+		return nil
+	case OutputJSON:
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(l.Value)
+	case OutputYAML:
+		e := yaml.NewEncoder(w)
+		if err := e.Encode(l.Value); err != nil {
+			return err
+		}
+		return e.Close()
+	case OutputCSV, OutputTSV:
+		cw := csv.NewWriter(w)
+		if opts.Format == OutputTSV {
+			cw.Comma = '\t'
+		}
+		if err := cw.Write(l.Header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(l.Rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedOutput, opts.Format)
+	}
+}
+
+func renderTemplate(w io.Writer, text string, value interface{}) error {
+	tmpl, err := template.New("output").Parse(text)
+	if err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice {
+		return executeLine(w, tmpl, value)
+	}
+	for i := 0; i < v.Len(); i++ {
+		if err := executeLine(w, tmpl, v.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func executeLine(w io.Writer, tmpl *template.Template, data interface{}) error {
+	if err := tmpl.Execute(w, data); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ErrUnsupportedOutput is returned when rendering in an unknown format.
+var ErrUnsupportedOutput = errors.New("unsupported output format")
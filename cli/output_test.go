@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"testing"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/check"
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The json outputs are the schemas scripts rely on: changing these
+// expectations is a breaking change.
+
+type testTx struct {
+	entries []transaction.Entry
+}
+
+func (t *testTx) Hash() [sha256.Size]byte      { return [sha256.Size]byte{0xab} }
+func (t *testTx) Timestamp() int64             { return 1600000000 }
+func (t *testTx) Entries() []transaction.Entry { return t.entries }
+func (t *testTx) Note() string                 { return "lunch" }
+
+func renderString(t *testing.T, opts OutputOptions, l *Listing) string {
+	var b bytes.Buffer
+	require.NoError(t, Render(&b, opts, l))
+	return b.String()
+}
+
+func TestViews(t *testing.T) {
+	cash := &account.Account{
+		ID:        account.ID{0x01},
+		Name:      "Cash",
+		Alias:     "cash",
+		Type:      account.TypeAsset,
+		Timestamp: 1600000000,
+	}
+	food := &account.Account{
+		ID:          account.ID{0x02},
+		Name:        "Food",
+		Description: "meals",
+		Type:        account.TypeExpense,
+		ParentID:    account.ID{0x03},
+		Timestamp:   1600000000,
+	}
+	json := OutputOptions{Format: OutputJSON}
+
+	t.Run("account", func(t *testing.T) {
+		out := renderString(t, json, &Listing{Value: []AccountView{NewAccountView(cash), NewAccountView(food)}})
+
+		assert.JSONEq(t, `[
+			{
+				"id": "`+cash.ID.Hex()+`",
+				"name": "Cash",
+				"alias": "cash",
+				"description": "",
+				"type": "asset",
+				"created": "2020-09-13T12:26:40Z"
+			},
+			{
+				"id": "`+food.ID.Hex()+`",
+				"name": "Food",
+				"alias": "",
+				"description": "meals",
+				"type": "expense",
+				"parent_id": "`+food.ParentID.Hex()+`",
+				"created": "2020-09-13T12:26:40Z"
+			}
+		]`, out)
+	})
+	t.Run("transaction", func(t *testing.T) {
+		tx := &testTx{entries: []transaction.Entry{
+			transaction.NewEntry(transaction.OpDebit, food.ID, 1500),
+			transaction.NewEntry(transaction.OpCredit, account.ID{0x04}, 1500),
+		}}
+		v := NewTransactionView(tx, map[account.ID]*account.Account{food.ID: food})
+		out := renderString(t, json, &Listing{Value: []TransactionView{v}})
+
+		assert.JSONEq(t, `[
+			{
+				"hash": "ab00000000000000000000000000000000000000000000000000000000000000",
+				"date": "2020-09-13T12:26:40Z",
+				"note": "lunch",
+				"entries": [
+					{"operation": "debit", "account_id": "`+food.ID.Hex()+`", "account": "Food", "amount": 1500},
+					{"operation": "credit", "account_id": "`+account.ID{0x04}.Hex()+`", "account": "", "amount": 1500}
+				]
+			}
+		]`, out)
+	})
+	t.Run("report", func(t *testing.T) {
+		r := &check.Report{Accounts: 2, Transactions: 1, Problems: []check.Problem{
+			{Kind: check.KindOrphan, Path: "accounts/x", Detail: "not a record"},
+		}}
+		out := renderString(t, json, &Listing{Value: NewReportView(r)})
+
+		assert.JSONEq(t, `{
+			"accounts": 2,
+			"transactions": 1,
+			"problems": [
+				{"kind": "orphan", "path": "accounts/x", "detail": "not a record", "packed": false}
+			]
+		}`, out)
+
+		out = renderString(t, json, &Listing{Value: NewReportView(&check.Report{})})
+		assert.JSONEq(t, `{"accounts": 0, "transactions": 0, "problems": []}`, out)
+	})
+	t.Run("search result", func(t *testing.T) {
+		av := NewAccountView(cash)
+		out := renderString(t, json, &Listing{Value: []SearchResultView{{Kind: "account", Score: 7, Account: &av}}})
+
+		assert.JSONEq(t, `[
+			{
+				"kind": "account",
+				"score": 7,
+				"account": {
+					"id": "`+cash.ID.Hex()+`",
+					"name": "Cash",
+					"alias": "cash",
+					"description": "",
+					"type": "asset",
+					"created": "2020-09-13T12:26:40Z"
+				}
+			}
+		]`, out)
+	})
+}
+
+func TestRender(t *testing.T) {
+	l := &Listing{
+		Header: []string{"NAME", "NOTE"},
+		Rows:   [][]string{{"a", "x, y"}, {"b", ""}},
+		Value: []struct {
+			Name string `json:"name" yaml:"name"`
+		}{{"a"}, {"b"}},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		assert.Equal(t, "NAME,NOTE\na,\"x, y\"\nb,\n", renderString(t, OutputOptions{Format: OutputCSV}, l))
+	})
+	t.Run("tsv", func(t *testing.T) {
+		assert.Equal(t, "NAME\tNOTE\na\tx, y\nb\t\n", renderString(t, OutputOptions{Format: OutputTSV}, l))
+	})
+	t.Run("yaml", func(t *testing.T) {
+		assert.Equal(t, "- name: a\n- name: b\n", renderString(t, OutputOptions{Format: OutputYAML}, l))
+	})
+	t.Run("template", func(t *testing.T) {
+		out := renderString(t, OutputOptions{Format: OutputJSON, Template: "<{{.Name}}>"}, l)
+		assert.Equal(t, "<a>\n<b>\n", out)
+
+		out = renderString(t, OutputOptions{Template: "{{len .}}"}, &Listing{Value: map[string]string{"k": "v"}})
+		assert.Equal(t, "1\n", out)
+	})
+	t.Run("text", func(t *testing.T) {
+		l := &Listing{Text: func(w io.Writer) error {
+			_, err := io.WriteString(w, "text\n")
+			return err
+		}}
+		assert.Equal(t, "text\n", renderString(t, OutputOptions{}, l))
+	})
+	t.Run("unsupported", func(t *testing.T) {
+		err := Render(&bytes.Buffer{}, OutputOptions{Format: "xml"}, l)
+		assert.ErrorIs(t, err, ErrUnsupportedOutput)
+	})
+}
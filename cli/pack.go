@@ -0,0 +1,15 @@
+package cli
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/store"
+)
+
+// PackWorkdir moves the transactions of the workdir whose files were last
+// modified before the given time into a pack file.
+// It returns the number of transactions moved.
+func PackWorkdir(workdir string, before time.Time) (int, error) {
+	return store.PackDir(filepath.Join(workdir, transactionsDirName), before)
+}
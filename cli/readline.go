@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Completer completes the line being edited on Tab, pos being the byte
+// offset of the cursor. It returns the completed line and cursor, or false
+// if there is nothing to complete.
+type Completer func(line string, pos int) (newLine string, newPos int, ok bool)
+
+// LineReader reads lines of input after a prompt. If the input is a
+// terminal, lines are edited in it, with a history and completion;
+// otherwise they are read as is, for scripts and tests.
+type LineReader struct {
+	in  io.Reader
+	out io.Writer
+
+	// terminal is nil if in is not a terminal.
+	terminal *term.Terminal
+	fd       int
+	lines    *bufio.Reader
+}
+
+// NewLineReader returns a LineReader reading from in and writing prompts
+// into out. complete, if not nil, completes the lines on a terminal.
+func NewLineReader(in io.Reader, out io.Writer, complete Completer) *LineReader {
+	r := &LineReader{in: in, out: out}
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		r.fd = int(f.Fd())
+		r.terminal = term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{in, out}, "")
+		if complete != nil {
+			r.terminal.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+				if key != '\t' {
+					return "", 0, false
+				}
+				return complete(line, pos)
+			}
+		}
+	} else {
+		r.lines = bufio.NewReader(in)
+	}
+	return r
+}
+
+// ReadLine prints prompt and returns the next line, without its end. It
+// returns io.EOF at the end of the input, or on Ctrl-C or Ctrl-D in a
+// terminal.
+func (r *LineReader) ReadLine(prompt string) (string, error) {
+	if r.terminal == nil {
+		fmt.Fprint(r.out, prompt)
+		line, err := r.lines.ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	// raw while editing only, so that the output in between is as usual
+	state, err := term.MakeRaw(r.fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(r.fd, state)
+	if width, height, err := term.GetSize(r.fd); err == nil && width > 0 {
+		r.terminal.SetSize(width, height)
+	}
+	r.terminal.SetPrompt(prompt)
+	line, err := r.terminal.ReadLine()
+	if errors.Is(err, term.ErrPasteIndicator) {
+		err = nil
+	}
+	return line, err
+}
+
+// Input returns the input left after the lines read: the input itself if
+// it is a terminal, otherwise the input buffered by r then the rest.
+func (r *LineReader) Input() io.Reader {
+	if r.terminal != nil {
+		return r.in
+	}
+	return r.lines
+}
+
+// CompleteWord completes the word before pos in line with the longest
+// common prefix of candidates, the completions of the word, followed by a
+// space if there is only one of them.
+func CompleteWord(line string, pos int, candidates func(word string) []string) (string, int, bool) {
+	start := strings.LastIndexAny(line[:pos], " \t") + 1
+	words := candidates(line[start:pos])
+	if len(words) == 0 {
+		return "", 0, false
+	}
+
+	completed := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, completed) {
+			completed = completed[:len(completed)-1]
+		}
+	}
+	if len(words) == 1 {
+		completed += " "
+	} else if len(completed) <= pos-start {
+		// no progress
+		return "", 0, false
+	}
+	return line[:start] + completed + line[pos:], start + len(completed), true
+}
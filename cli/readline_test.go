@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineReader(t *testing.T) {
+	var out bytes.Buffer
+	r := NewLineReader(strings.NewReader("vary\r\n\nlaoka"), &out, nil)
+
+	for _, expected := range []string{"vary", "", "laoka"} {
+		line, err := r.ReadLine("> ")
+		require.NoError(t, err)
+		assert.Equal(t, expected, line)
+	}
+	_, err := r.ReadLine("> ")
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, "> > > > ", out.String())
+}
+
+func TestCompleteWord(t *testing.T) {
+	candidates := func(word string) []string {
+		words := []string{}
+		for _, w := range []string{"cash-at-home", "cash-in-wallet", "food"} {
+			if strings.Contains(w, word) {
+				words = append(words, w)
+			}
+		}
+		return words
+	}
+
+	tests := []struct {
+		line      string
+		pos       int
+		expected  string
+		expectPos int
+		ok        bool
+	}{
+		{"d fo", 4, "d food ", 7, true},
+		{"d wal 10", 5, "d cash-in-wallet  10", 17, true},
+		{"d ca", 4, "d cash-", 7, true},
+		{"d cash-", 7, "", 0, false},
+		{"d xyz", 5, "", 0, false},
+	}
+	for _, tt := range tests {
+		line, pos, ok := CompleteWord(tt.line, tt.pos, candidates)
+		assert.Equal(t, tt.ok, ok, tt.line)
+		assert.Equal(t, tt.expected, line, tt.line)
+		assert.Equal(t, tt.expectPos, pos, tt.line)
+	}
+}
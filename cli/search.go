@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/search"
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+)
+
+// ReindexSearch rebuilds the search index of the opened ledger from its
+// accounts and transactions. The records which could not be read are
+// left out, and returned as a *store.ListError.
+func ReindexSearch(ctx context.Context, c Cli) error {
+	listErr := &store.ListError{}
+	addFailures := func(err error) error {
+		var e *store.ListError
+		if !errors.As(err, &e) {
+			return err
+		}
+		listErr.Failures = append(listErr.Failures, e.Failures...)
+		return nil
+	}
+
+	docs := []search.Document{}
+	accounts, err := c.AccService().ListContext(ctx)
+	if err := addFailures(err); err != nil {
+		return err
+	}
+	for _, acc := range accounts {
+		docs = append(docs, account.SearchDocument(acc))
+	}
+	txs, err := c.TxService().ListContext(ctx)
+	if err := addFailures(err); err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		docs = append(docs, transaction.SearchDocument(tx))
+	}
+
+	if err := c.SearchIndex().Rebuild(ctx, docs); err != nil {
+		return err
+	}
+	return listErr.ErrOrNil()
+}
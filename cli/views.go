@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/check"
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+)
+
+// The views are the values rendered by the json and yaml output formats,
+// and by templates. Their fields are a stable interface for scripts: they
+// may be added, never renamed or removed.
+
+// AccountView is the view of an account.
+type AccountView struct {
+	ID          string `json:"id" yaml:"id"`
+	Name        string `json:"name" yaml:"name"`
+	Alias       string `json:"alias" yaml:"alias"`
+	Description string `json:"description" yaml:"description"`
+	// Type is the name of the type of the account, empty if invalid.
+	Type string `json:"type" yaml:"type"`
+	// ParentID is empty for root accounts.
+	ParentID string    `json:"parent_id,omitempty" yaml:"parent_id,omitempty"`
+	Created  time.Time `json:"created" yaml:"created"`
+}
+
+// NewAccountView returns the view of acc.
+func NewAccountView(acc *account.Account) AccountView {
+	v := AccountView{
+		ID:          acc.ID.Hex(),
+		Name:        acc.Name,
+		Alias:       acc.Alias,
+		Description: acc.Description,
+		Created:     time.Unix(acc.Timestamp, 0).UTC(),
+	}
+	if acc.Type.IsValid() {
+		v.Type = acc.Type.String()
+	}
+	if acc.ParentID != (account.ID{}) {
+		v.ParentID = acc.ParentID.Hex()
+	}
+	return v
+}
+
+// TransactionView is the view of a transaction.
+type TransactionView struct {
+	Hash    string      `json:"hash" yaml:"hash"`
+	Date    time.Time   `json:"date" yaml:"date"`
+	Note    string      `json:"note" yaml:"note"`
+	Entries []EntryView `json:"entries" yaml:"entries"`
+}
+
+// EntryView is the view of a transaction entry.
+type EntryView struct {
+	// Operation is "debit" or "credit".
+	Operation string `json:"operation" yaml:"operation"`
+	AccountID string `json:"account_id" yaml:"account_id"`
+	// Account is the name of the account, empty if it could not be read.
+	Account string `json:"account" yaml:"account"`
+	Amount  int64  `json:"amount" yaml:"amount"`
+}
+
+// NewTransactionView returns the view of tx. accounts gives the accounts
+// of its entries, by ID; the missing ones are left unnamed.
+func NewTransactionView(tx transaction.Transaction, accounts map[account.ID]*account.Account) TransactionView {
+	hash := tx.Hash()
+	v := TransactionView{
+		Hash:    hex.EncodeToString(hash[:]),
+		Date:    time.Unix(tx.Timestamp(), 0).UTC(),
+		Note:    tx.Note(),
+		Entries: []EntryView{},
+	}
+	for _, e := range tx.Entries() {
+		ev := EntryView{
+			Operation: e.Operation().String(),
+			AccountID: e.AccountID().Hex(),
+			Amount:    e.Amount(),
+		}
+		if acc, ok := accounts[e.AccountID()]; ok {
+			ev.Account = acc.Name
+		}
+		v.Entries = append(v.Entries, ev)
+	}
+	return v
+}
+
+// ReportView is the view of a check report.
+type ReportView struct {
+	Accounts     int           `json:"accounts" yaml:"accounts"`
+	Transactions int           `json:"transactions" yaml:"transactions"`
+	Problems     []ProblemView `json:"problems" yaml:"problems"`
+}
+
+// ProblemView is the view of a problem found by a check.
+type ProblemView struct {
+	Kind   string `json:"kind" yaml:"kind"`
+	Path   string `json:"path" yaml:"path"`
+	Detail string `json:"detail" yaml:"detail"`
+	Packed bool   `json:"packed" yaml:"packed"`
+}
+
+// NewReportView returns the view of r.
+func NewReportView(r *check.Report) ReportView {
+	v := ReportView{Accounts: r.Accounts, Transactions: r.Transactions, Problems: []ProblemView{}}
+	for _, p := range r.Problems {
+		v.Problems = append(v.Problems, ProblemView{
+			Kind:   p.Kind.String(),
+			Path:   p.Path,
+			Detail: p.Detail,
+			Packed: p.Packed,
+		})
+	}
+	return v
+}
+
+// SearchResultView is the view of a search result: an account or a
+// transaction.
+type SearchResultView struct {
+	// Kind is "account" or "transaction".
+	Kind  string `json:"kind" yaml:"kind"`
+	Score int    `json:"score" yaml:"score"`
+
+	// Account is set for accounts, Transaction for transactions.
+	Account     *AccountView     `json:"account,omitempty" yaml:"account,omitempty"`
+	Transaction *TransactionView `json:"transaction,omitempty" yaml:"transaction,omitempty"`
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/fitiavana07/mitrack/pkg/store"
+)
+
+// Warn prints into w a warning for each failure of err if it is a
+// *store.ListError, and returns nil: the records which could be read can
+// still be shown. Any other error is returned as is, and so is
+// store.ErrLocked if a record could not be read for lack of key, as the
+// other ones are likely locked as well.
+func Warn(w io.Writer, err error) error {
+	var listErr *store.ListError
+	if !errors.As(err, &listErr) {
+		return err
+	}
+	for _, f := range listErr.Failures {
+		if errors.Is(f, store.ErrLocked) {
+			return store.ErrLocked
+		}
+	}
+	for _, f := range listErr.Failures {
+		fmt.Fprintf(w, "warning: %s\n", f)
+	}
+	return nil
+}
@@ -1,22 +1,116 @@
 package cmd
 
 import (
+	"io"
+
 	"github.com/fitiavana07/mitrack/cli"
 	"github.com/fitiavana07/mitrack/cli/command"
+	"github.com/fitiavana07/mitrack/cli/command/shell"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+type rootOptions struct {
+	workdir string
+	ledger  string
+	output  cli.OutputOptions
+}
+
 // NewMitrackRootCmd creates the root command.
+// The ledger of mitrackCli is opened once the flags are parsed.
 func NewMitrackRootCmd(mitrackCli cli.Cli) *cobra.Command {
+	options := rootOptions{}
+
 	cmd := &cobra.Command{
 		Use:   "mitrack",
 		Short: "A CLI-based finance management tool",
+
+		// printed once by main, which maps them to exit codes
+		SilenceErrors: true,
+		// an error is not always a usage error
+		SilenceUsage: true,
+
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Name() == cobra.ShellCompRequestCmd {
+				// the completed command line is not parsed yet: its global
+				// flags are, to complete from the ledger they select.
+				// Nothing is completed if it can not be opened.
+				parseGlobalFlags(cmd.Root().PersistentFlags(), args[:len(args)-1])
+				_ = open(cmd, mitrackCli, options)
+				return nil
+			}
+			return open(cmd, mitrackCli, options)
+		},
 	}
 
+	flags := cmd.PersistentFlags()
+	flags.StringVarP(&options.workdir, "workdir", "C", "",
+		"mitrack working directory (default $"+cli.WorkdirEnv+", or ~/"+cli.DefaultWorkdirName+")")
+	flags.StringVar(&options.ledger, "ledger", "", "ledger to use (default the configured one)")
+	addOutputFlags(flags, &options.output)
+
 	command.AddCommands(cmd, mitrackCli)
+	cmd.AddCommand(shell.NewShellCommand(mitrackCli, newShellRootCmd))
+
+	return cmd
+}
+
+// newShellRootCmd creates the root command of the command lines of
+// `mitrack shell`, the ledger of mitrackCli being opened already.
+func newShellRootCmd(mitrackCli cli.Cli) *cobra.Command {
+	output := cli.OutputOptions{}
+
+	cmd := &cobra.Command{
+		Use:           "mitrack",
+		Short:         "A CLI-based finance management tool",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			mitrackCli.SetOutput(output)
+			return nil
+		},
+	}
 
-	// think of viper if ever needed a conf file
-	// (look at the cobra-generated code)
+	addOutputFlags(cmd.PersistentFlags(), &output)
+	command.AddCommands(cmd, mitrackCli)
 
 	return cmd
 }
+
+// addOutputFlags adds the flags of the output options to flags.
+func addOutputFlags(flags *pflag.FlagSet, output *cli.OutputOptions) {
+	flags.StringVarP(&output.Format, "output", "o", "",
+		"output format: table|json|csv|tsv|yaml (default the configured one)")
+	flags.StringVar(&output.Template, "template", "",
+		"Go template executed for each listed item, instead of the output format")
+}
+
+// open opens the ledger of mitrackCli given by the options, or only its
+// workdir for the commands annotated with cli.WorkdirOnlyAnnotation.
+func open(cmd *cobra.Command, mitrackCli cli.Cli, options rootOptions) error {
+	workdir := options.workdir
+	if workdir == "" {
+		var err error
+		if workdir, err = cli.DefaultWorkdir(); err != nil {
+			return err
+		}
+	}
+	mitrackCli.SetOutput(options.output)
+	for c := cmd; c != nil; c = c.Parent() {
+		if _, ok := c.Annotations[cli.WorkdirOnlyAnnotation]; ok {
+			return mitrackCli.OpenWorkdir(workdir, options.ledger)
+		}
+	}
+	return mitrackCli.Open(workdir, options.ledger)
+}
+
+// parseGlobalFlags parses the flags of global in args, ignoring the other
+// flags and the errors.
+func parseGlobalFlags(global *pflag.FlagSet, args []string) {
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	fs.ParseErrorsWhitelist.UnknownFlags = true
+	fs.SetOutput(io.Discard)
+	fs.AddFlagSet(global)
+	_ = fs.Parse(args)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fitiavana07/mitrack/cli"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkdir(t *testing.T) {
+	homedir.DisableCache = true
+	home, env, flag := t.TempDir(), t.TempDir(), t.TempDir()
+	t.Setenv("HOME", home)
+
+	// workdir returns the workdir opened by `mitrack account ls args...`.
+	workdir := func(t *testing.T, args ...string) string {
+		mitrackCli := cli.NewMitrackCli()
+		cmd := NewMitrackRootCmd(mitrackCli)
+		cmd.SetArgs(append(args, "account", "ls"))
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		require.NoError(t, cmd.Execute())
+		require.NoError(t, mitrackCli.Cleanup())
+		return mitrackCli.Workdir()
+	}
+
+	t.Run("default", func(t *testing.T) {
+		t.Setenv(cli.WorkdirEnv, "")
+		assert.Equal(t, filepath.Join(home, cli.DefaultWorkdirName), workdir(t))
+	})
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv(cli.WorkdirEnv, env)
+		assert.Equal(t, env, workdir(t))
+	})
+	t.Run("flag first", func(t *testing.T) {
+		t.Setenv(cli.WorkdirEnv, env)
+		assert.Equal(t, flag, workdir(t, "--workdir", flag))
+		assert.Equal(t, flag, workdir(t, "-C", flag))
+	})
+}
+
+func TestWorkdirOnly(t *testing.T) {
+	workdir := t.TempDir()
+	run := func(args ...string) error {
+		mitrackCli := cli.NewMitrackCli()
+		defer mitrackCli.Cleanup()
+		cmd := NewMitrackRootCmd(mitrackCli)
+		cmd.SetArgs(append([]string{"-C", workdir}, args...))
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		return cmd.Execute()
+	}
+
+	require.NoError(t, run("ledger", "create", "business"))
+	require.NoError(t, run("ledger", "use", "business"))
+	assert.ErrorIs(t, run("config", "set", "ledger", "nope"), cli.ErrLedgerNotFound)
+	require.NoError(t, os.RemoveAll(filepath.Join(workdir, "ledgers", "business")))
+
+	// the configured ledger is missing
+	assert.ErrorIs(t, run("account", "ls"), cli.ErrLedgerNotFound)
+	require.NoError(t, run("ledger", "ls"))
+	require.NoError(t, run("config", "get", "ledger"))
+	require.NoError(t, run("config", "set", "ledger", cli.DefaultLedger))
+	assert.NoError(t, run("account", "ls"))
+}
+
+// TestHelp runs --help on every command, which fails if the flags of a
+// command clash with the global ones.
+func TestHelp(t *testing.T) {
+	var walk func(t *testing.T, cmd *cobra.Command)
+	walk = func(t *testing.T, cmd *cobra.Command) {
+		for _, sub := range cmd.Commands() {
+			walk(t, sub)
+		}
+		path := strings.Fields(cmd.CommandPath())[1:]
+		t.Run(strings.Join(append([]string{"mitrack"}, path...), " "), func(t *testing.T) {
+			mitrackCli := cli.NewMitrackCli()
+			defer mitrackCli.Cleanup()
+			root := NewMitrackRootCmd(mitrackCli)
+			var out bytes.Buffer
+			root.SetArgs(append(append([]string{"-C", t.TempDir()}, path...), "--help"))
+			root.SetOut(&out)
+			root.SetErr(&out)
+			require.NoError(t, root.Execute())
+			assert.Contains(t, out.String(), "Usage:")
+		})
+	}
+	walk(t, NewMitrackRootCmd(cli.NewMitrackCli()))
+}
@@ -1,31 +1,22 @@
 package main
 
 import (
-	"path/filepath"
+	"fmt"
+	"os"
 
 	"github.com/fitiavana07/mitrack/cli"
 	"github.com/fitiavana07/mitrack/cmd"
-	"github.com/mitchellh/go-homedir"
-	"github.com/spf13/cobra"
-)
-
-const (
-	mitrackDirName = ".mitrack"
 )
 
 func main() {
-	homeDir, err := homedir.Dir()
-	cobra.CheckErr(err)
-
-	mitrackWorkdir := filepath.Join(homeDir, mitrackDirName)
-
-	mitrackCli, err := cli.NewMitrackCli(mitrackWorkdir)
-	cobra.CheckErr(err)
-
-	defer func() {
-		cobra.CheckErr(mitrackCli.Cleanup())
-	}()
-
-	err = cmd.NewMitrackRootCmd(mitrackCli).Execute()
-	cobra.CheckErr(err)
+	mitrackCli := cli.NewMitrackCli()
+
+	err := cmd.NewMitrackRootCmd(mitrackCli).Execute()
+	if cleanupErr := mitrackCli.Cleanup(); err == nil {
+		err = cleanupErr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(cli.ExitCode(err))
+	}
 }
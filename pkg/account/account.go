@@ -5,39 +5,46 @@ import (
 	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"strings"
 	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/encoding"
 )
 
 // Account represents an a financial account in a double-entry
 // accounting system
+//
+// The mitrack tags are the field ordinals used by encoding.Marshal.
+// An ordinal must never be reused, even after its field was removed.
 type Account struct {
 
 	// ID is the identifier of the account, generated on creation.
 	// It is obtained by calculating the hash of initial attributes
 	// of the account using SHA256 algorithm.
+	// It is not tagged, as it is the name of the account file.
 	ID ID
 
 	// Name is the name of the account.
-	Name string
+	Name string `mitrack:"1"`
 
 	// Alias is an alias to the account.
-	Alias string
+	Alias string `mitrack:"2"`
 
 	// Description is the description of the account.
-	Description string
+	Description string `mitrack:"3"`
 
 	// Type is the type of the account.
-	Type Type
+	Type Type `mitrack:"4"`
 
 	// ParentID is the ID of the parent of this account in the account tree.
 	// We don't really need to declare parent here, because we will not need
 	// to access it often.
-	ParentID ID
+	ParentID ID `mitrack:"5"`
 
 	// Timestamp is the creation date of the account, in timestamp.
 	// It is obtained using time.Now().UTC().Unix().
-	Timestamp int64
+	Timestamp int64 `mitrack:"6"`
 }
 
 // NewAccount returns a new initialized Account.
@@ -50,6 +57,15 @@ func NewAccount(name string, t Type) *Account {
 	a.Alias = strings.ReplaceAll(strings.ToLower(a.Name), " ", "-")
 	a.Timestamp = time.Now().UTC().Unix()
 
+	a.ID = a.ComputeID()
+
+	return a
+}
+
+// ComputeID returns the ID of the account, computed from its attributes.
+// It only matches the ID of an account whose attributes did not change
+// since its creation.
+func (a *Account) ComputeID() ID {
 	data := []interface{}{
 		a.Name,
 		a.Alias,
@@ -69,9 +85,54 @@ func NewAccount(name string, t Type) *Account {
 		}
 	}
 
-	a.ID = sha256.Sum256(all.Bytes())
+	return sha256.Sum256(all.Bytes())
+}
 
-	return a
+// Decode decodes the account of the given ID from its record bytes.
+func Decode(id ID, b []byte) (*Account, error) {
+	rr, err := encoding.NewRecordReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	a := Account{ID: id}
+	if err = rr.ReadDecoded(&a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// fields returns pointers to the encoded fields of the account, in the order
+// they are stored. The ID is not part of them: it is the name of the file.
+func (a *Account) fields() []interface{} {
+	return []interface{}{
+		&a.Type,
+		&a.ParentID,
+		&a.Timestamp,
+		&a.Alias,
+		&a.Name,
+		&a.Description,
+	}
+}
+
+// MarshalEncoded implements encoding.Marshaler.
+func (a *Account) MarshalEncoded(w io.Writer, e encoding.Encoder) error {
+	for _, v := range a.fields() {
+		if err := e.WriteEncoded(w, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalDecoded implements encoding.Unmarshaler.
+func (a *Account) UnmarshalDecoded(r io.Reader, d encoding.Decoder) error {
+	for _, v := range a.fields() {
+		if err := d.ReadDecoded(r, v); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // TODO
@@ -1,7 +1,12 @@
 package account
 
 import (
+	"bytes"
 	"testing"
+
+	"github.com/fitiavana07/mitrack/pkg/encoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestAccountNew(t *testing.T) {
@@ -20,3 +25,41 @@ func TestAccountNew(t *testing.T) {
 		t.Error("ID was not initialized")
 	}
 }
+
+func TestAccountMarshal(t *testing.T) {
+	for _, version := range []uint32{encoding.FormatVersionV3, encoding.FormatVersionV4} {
+		acc := NewAccount("Cash in Wallet", TypeAsset)
+		acc.Description = "coins and notes"
+		acc.ParentID = NewAccount("Cash", TypeAsset).ID
+
+		b := new(bytes.Buffer)
+		rw, err := encoding.NewRecordWriter(b, version)
+		require.NoError(t, err)
+		require.NoError(t, rw.WriteEncoded(acc))
+		require.NoError(t, rw.Close())
+
+		rr, err := encoding.NewRecordReader(b)
+		require.NoError(t, err)
+
+		got := &Account{ID: acc.ID}
+		err = rr.ReadDecoded(got)
+
+		assert.NoError(t, err)
+		assert.Equal(t, acc, got)
+	}
+}
+
+func TestAccountReflectionMarshal(t *testing.T) {
+	acc := NewAccount("Cash in Wallet", TypeAsset)
+	acc.Description = "coins and notes"
+	acc.ParentID = NewAccount("Cash", TypeAsset).ID
+
+	b, err := encoding.Marshal(acc)
+	require.NoError(t, err)
+
+	got := &Account{ID: acc.ID}
+	err = encoding.Unmarshal(b, got)
+
+	assert.NoError(t, err)
+	assert.Equal(t, acc, got)
+}
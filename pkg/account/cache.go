@@ -0,0 +1,58 @@
+package account
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// accountCache keeps the decoded accounts, so that reading the same
+// account again (ex: for every entry of every transaction) does not decode
+// its file again.
+//
+// An account is cached along with the modification time and size of its
+// file, and is only returned while they did not change: the file may be
+// rewritten by another process.
+type accountCache struct {
+	mu      sync.Mutex
+	entries map[ID]cacheEntry
+}
+
+type cacheEntry struct {
+	acc     Account
+	modTime time.Time
+	size    int64
+}
+
+func newAccountCache() *accountCache {
+	return &accountCache{entries: map[ID]cacheEntry{}}
+}
+
+// get returns a copy of the account of the given ID, if it is cached and
+// info is still the one of its file.
+func (c *accountCache) get(id ID, info os.FileInfo) (*Account, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[id]
+	if !ok || !e.modTime.Equal(info.ModTime()) || e.size != info.Size() {
+		return nil, false
+	}
+	acc := e.acc
+	return &acc, true
+}
+
+// put caches a copy of acc, decoded from the file described by info.
+func (c *accountCache) put(acc *Account, info os.FileInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[acc.ID] = cacheEntry{acc: *acc, modTime: info.ModTime(), size: info.Size()}
+}
+
+func (c *accountCache) invalidate(id ID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, id)
+}
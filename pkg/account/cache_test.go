@@ -0,0 +1,126 @@
+package account
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccServiceCache(t *testing.T) {
+	t.Run("copies", func(t *testing.T) {
+		s, cleanup := createFakeService(t, t.TempDir())
+		defer cleanup()
+
+		acc := NewAccount("Jiro sy Rano", TypeExpense)
+		require.NoError(t, s.Register(acc))
+
+		found, err := s.GetByActualID(acc.ID)
+		require.NoError(t, err)
+		found.Name = "changed by the caller"
+
+		found, err = s.GetByActualID(acc.ID)
+		require.NoError(t, err)
+		assert.Equal(t, acc, found)
+	})
+	t.Run("update", func(t *testing.T) {
+		s, cleanup := createFakeService(t, t.TempDir())
+		defer cleanup()
+
+		acc := NewAccount("Jiro sy Rano", TypeExpense)
+		require.NoError(t, s.Register(acc))
+		_, err := s.GetByActualID(acc.ID)
+		require.NoError(t, err)
+
+		acc.Description = "JIRAMA"
+		require.NoError(t, s.Update(acc))
+
+		found, err := s.GetByActualID(acc.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "JIRAMA", found.Description)
+	})
+	t.Run("file changed by another process", func(t *testing.T) {
+		dir := t.TempDir()
+		s1, cleanup := createFakeService(t, dir)
+		defer cleanup()
+		s2, cleanup := createFakeService(t, dir)
+		defer cleanup()
+
+		acc := NewAccount("Jiro sy Rano", TypeExpense)
+		require.NoError(t, s1.Register(acc))
+		_, err := s1.GetByActualID(acc.ID)
+		require.NoError(t, err)
+
+		acc.Description = "JIRAMA"
+		require.NoError(t, s2.Update(acc))
+		// the modification time may not change on coarse file systems
+		later := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(filepath.Join(dir, acc.ID.Hex()), later, later))
+
+		found, err := s1.GetByActualID(acc.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "JIRAMA", found.Description)
+	})
+	t.Run("file deleted by another process", func(t *testing.T) {
+		dir := t.TempDir()
+		s, cleanup := createFakeService(t, dir)
+		defer cleanup()
+
+		acc := NewAccount("Jiro sy Rano", TypeExpense)
+		require.NoError(t, s.Register(acc))
+		_, err := s.GetByActualID(acc.ID)
+		require.NoError(t, err)
+
+		require.NoError(t, os.Remove(filepath.Join(dir, acc.ID.Hex())))
+
+		_, err = s.GetByActualID(acc.ID)
+		assert.ErrorIs(t, err, ErrAccountNotFound)
+	})
+}
+
+// BenchmarkGetByActualID looks up the accounts of 10k transactions of two
+// entries each, as `mitrack tx ls` does.
+func BenchmarkGetByActualID(b *testing.B) {
+	const (
+		accountsCount     = 50
+		transactionsCount = 10000
+	)
+
+	for _, bc := range []struct {
+		name string
+		// wrap returns the store of the service
+		wrap func(store.Store) store.Store
+	}{
+		{"cached", func(st store.Store) store.Store { return st }},
+		// hiding the files of the store disables the cache
+		{"uncached", func(st store.Store) store.Store { return struct{ store.Store }{st} }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			s, err := NewAccServiceWithStore(bc.wrap(store.NewDirStore(b.TempDir())))
+			require.NoError(b, err)
+
+			ids := make([]ID, accountsCount)
+			for i := range ids {
+				acc := NewAccount(fmt.Sprintf("Account %d", i), TypeAsset)
+				require.NoError(b, s.Register(acc))
+				ids[i] = acc.ID
+			}
+
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				for tx := 0; tx < transactionsCount; tx++ {
+					for _, i := range []int{tx, tx + 1} {
+						if _, err := s.GetByActualID(ids[i%accountsCount]); err != nil {
+							b.Fatal(err)
+						}
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run with -race.
+func TestAccServiceConcurrency(t *testing.T) {
+	s, cleanup := createFakeService(t, t.TempDir())
+	defer cleanup()
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, s.Register(NewAccount(fmt.Sprintf("Account %d", i), TypeAsset)))
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, err := s.List()
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := s.CountByType(TypeAsset)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	accounts, err := s.List()
+	require.NoError(t, err)
+	assert.Len(t, accounts, n)
+
+	count, err := s.CountByType(TypeAsset)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(n), count, "concurrent registrations must all be counted")
+}
+
+// The services of the processes sharing a ledger do not share their mutex.
+func TestAccServiceSharedLedger(t *testing.T) {
+	dir := t.TempDir()
+	services := make([]AccService, 4)
+	for i := range services {
+		s, cleanup := createFakeService(t, dir)
+		defer cleanup()
+		services[i] = s
+	}
+	_, err := services[0].CountByType(TypeAsset)
+	require.NoError(t, err, "counts saved")
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i, s := range services {
+		for j := 0; j < n; j++ {
+			wg.Add(1)
+			go func(s AccService, name string) {
+				defer wg.Done()
+				assert.NoError(t, s.Register(NewAccount(name, TypeAsset)))
+			}(s, fmt.Sprintf("Account %d-%d", i, j))
+		}
+	}
+	wg.Wait()
+
+	counts, err := services[0].(*accService).savedTypeCounts()
+	require.NoError(t, err)
+	require.NotNil(t, counts, "no update lost")
+	assert.Equal(t, uint64(len(services)*n), counts.Types[TypeAsset.String()])
+}
+
+func TestAccServiceContext(t *testing.T) {
+	s, cleanup := createFakeService(t, t.TempDir())
+	defer cleanup()
+	acc := NewAccount("Cash", TypeAsset)
+	require.NoError(t, s.Register(acc))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, s.RegisterContext(ctx, NewAccount("Bank", TypeAsset)), context.Canceled)
+	_, err := s.ListContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = s.GetByAliasContext(ctx, acc.Alias)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	count, err := s.Count()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), count, "nothing must be registered once ctx is done")
+}
@@ -0,0 +1,134 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fitiavana07/mitrack/pkg/store"
+)
+
+// The number of accounts of each type is kept in the typeCountsKey metadata,
+// so that counting accounts does not decode them. It is maintained by
+// Register, Update and Delete, and rebuilt from the accounts when missing
+// or stale, holding the lock of the store (see store.LockOf) so that the
+// processes sharing the ledger do not lose each other's updates.
+// It is encrypted like the accounts are.
+const typeCountsKey = ".counts"
+
+func init() {
+	store.RegisterPrivateMetadata(typeCountsKey)
+}
+
+// typeCounts is the content of the typeCountsKey metadata.
+//
+// Records is the number of accounts the counts are about: they are stale
+// when it no longer matches the number of account files, as accounts were
+// registered by another process at the same time, or removed by hand or by
+// `db check --repair`.
+type typeCounts struct {
+	Records uint64            `json:"records"`
+	Types   map[string]uint64 `json:"types"`
+}
+
+func (s *accService) Count() (uint64, error) {
+	keys, err := s.store.Keys()
+	if err != nil {
+		return 0, fmt.Errorf("account.service: could not count accounts: %w", err)
+	}
+
+	var count uint64
+	for _, key := range keys {
+		if _, err := DecodeID(key); err == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *accService) CountByType(t Type) (uint64, error) {
+	return s.CountByTypeContext(context.Background(), t)
+}
+
+func (s *accService) CountByTypeContext(ctx context.Context, t Type) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := store.LockOf(s.store)
+	if err != nil {
+		return 0, fmt.Errorf("account.service: could not lock accounts: %w", err)
+	}
+	defer unlock()
+
+	counts, err := s.savedTypeCounts()
+	if err == nil && counts == nil {
+		counts, err = s.rebuildTypeCounts(ctx)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("account.service: could not count accounts: %w", err)
+	}
+	return counts.Types[t.String()], nil
+}
+
+// savedTypeCounts returns the saved number of accounts of each type, or
+// nil if they were not saved or are stale.
+func (s *accService) savedTypeCounts() (*typeCounts, error) {
+	b, err := s.store.Get(typeCountsKey)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	counts := &typeCounts{}
+	if err := json.Unmarshal(b, counts); err != nil || counts.Types == nil {
+		// rebuilt rather than failing: it is only a cache
+		return nil, nil
+	}
+	records, err := s.Count()
+	if err != nil {
+		return nil, err
+	}
+	if records != counts.Records {
+		return nil, nil
+	}
+	return counts, nil
+}
+
+// rebuildTypeCounts counts the accounts of each type by decoding them, and
+// saves the result. Accounts which can not be read are not counted.
+// s.mu must be held.
+func (s *accService) rebuildTypeCounts(ctx context.Context) (*typeCounts, error) {
+	accounts, err := s.ListContext(ctx)
+	var listErr *store.ListError
+	if err != nil && !errors.As(err, &listErr) {
+		return nil, err
+	}
+
+	counts := &typeCounts{Records: uint64(len(accounts)), Types: map[string]uint64{}}
+	for _, acc := range accounts {
+		if acc.Type.IsValid() {
+			counts.Types[acc.Type.String()]++
+		}
+	}
+	if listErr != nil {
+		// not saved: the counts of the accounts which could not be read
+		// would be lost
+		for _, f := range listErr.Failures {
+			if errors.Is(f, store.ErrLocked) {
+				return nil, store.ErrLocked
+			}
+		}
+		return counts, nil
+	}
+	return counts, s.saveTypeCounts(counts)
+}
+
+func (s *accService) saveTypeCounts(counts *typeCounts) error {
+	b, err := json.Marshal(counts)
+	if err != nil {
+		return err
+	}
+	return s.store.Put(typeCountsKey, b)
+}
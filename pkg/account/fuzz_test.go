@@ -0,0 +1,54 @@
+package account
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fitiavana07/mitrack/pkg/encoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func FuzzGetByActualID(f *testing.F) {
+	acc := NewAccount("Cash in Wallet", TypeAsset)
+	for _, version := range []uint32{encoding.FormatVersionV3, encoding.FormatVersionV4} {
+		b := new(bytes.Buffer)
+		rw, err := encoding.NewRecordWriter(b, version)
+		require.NoError(f, err)
+		require.NoError(f, rw.WriteEncoded(acc))
+		require.NoError(f, rw.Close())
+		f.Add(b.Bytes())
+	}
+
+	dir := f.TempDir()
+	s, cleanup := createFakeService(f, dir)
+	defer cleanup()
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, acc.ID.Hex()), b, 0644))
+
+		// must not panic, whatever the content of the file
+		s.GetByActualID(acc.ID)
+	})
+}
+
+func FuzzAccountRoundTrip(f *testing.F) {
+	f.Add("Cash in Wallet", "coins and notes", uint8(TypeAsset))
+
+	dir := f.TempDir()
+	s, cleanup := createFakeService(f, dir)
+	defer cleanup()
+
+	f.Fuzz(func(t *testing.T, name, description string, accType uint8) {
+		acc := NewAccount(name, Type(accType))
+		acc.Description = description
+		require.NoError(t, s.Register(acc))
+
+		got, err := s.GetByActualID(acc.ID)
+
+		require.NoError(t, err)
+		assert.Equal(t, acc, got)
+	})
+}
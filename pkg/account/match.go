@@ -0,0 +1,93 @@
+package account
+
+import (
+	"sort"
+	"strings"
+)
+
+// Ranks of the matches of an alias, best first.
+const (
+	matchExact = iota
+	matchPrefix
+	matchContains
+	matchFuzzy
+)
+
+// MatchAlias returns the accounts whose alias matches query, ignoring case,
+// best matches first: the alias equal to query, then the aliases starting
+// with it, containing it, then containing its characters in order ("cwal"
+// matches "cash-in-wallet"), the tighter the better. Matches of the same
+// rank are ordered by alias.
+func MatchAlias(accounts []*Account, query string) []*Account {
+	query = strings.ToLower(query)
+
+	type match struct {
+		acc  *Account
+		rank int
+		span int
+	}
+	matches := []match{}
+	for _, acc := range accounts {
+		alias := strings.ToLower(acc.Alias)
+		switch {
+		case alias == query:
+			matches = append(matches, match{acc, matchExact, 0})
+		case strings.HasPrefix(alias, query):
+			matches = append(matches, match{acc, matchPrefix, 0})
+		case strings.Contains(alias, query):
+			matches = append(matches, match{acc, matchContains, 0})
+		default:
+			if span, ok := fuzzySpan(alias, query); ok {
+				matches = append(matches, match{acc, matchFuzzy, span})
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.span != b.span {
+			return a.span < b.span
+		}
+		return a.acc.Alias < b.acc.Alias
+	})
+
+	accs := make([]*Account, len(matches))
+	for i, m := range matches {
+		accs[i] = m.acc
+	}
+	return accs
+}
+
+// fuzzySpan returns the length of the shortest part of s containing the
+// characters of query in order, and whether there is one.
+func fuzzySpan(s, query string) (int, bool) {
+	q := []rune(query)
+	if len(q) == 0 {
+		return 0, true
+	}
+	r := []rune(s)
+
+	best := -1
+	for start := range r {
+		if r[start] != q[0] {
+			continue
+		}
+		i, j := start, 0
+		for ; i < len(r) && j < len(q); i++ {
+			if r[i] == q[j] {
+				j++
+			}
+		}
+		if j < len(q) {
+			// no match from a later start either
+			break
+		}
+		if span := i - start; best < 0 || span < best {
+			best = span
+		}
+	}
+	return best, best >= 0
+}
@@ -0,0 +1,40 @@
+package account
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchAlias(t *testing.T) {
+	cash := &Account{Alias: "cash"}
+	wallet := &Account{Alias: "cash-in-wallet"}
+	home := &Account{Alias: "cash-at-home"}
+	food := &Account{Alias: "food"}
+	seafood := &Account{Alias: "seafood"}
+	checking := &Account{Alias: "checking-account"}
+	accounts := []*Account{checking, seafood, food, home, wallet, cash}
+
+	aliases := func(accs []*Account) []string {
+		aliases := []string{}
+		for _, acc := range accs {
+			aliases = append(aliases, acc.Alias)
+		}
+		return aliases
+	}
+
+	for query, expected := range map[string][]string{
+		"cash":    {"cash", "cash-at-home", "cash-in-wallet"},
+		"FOOD":    {"food", "seafood"},
+		"wal":     {"cash-in-wallet"},
+		"cwal":    {"cash-in-wallet"},
+		"ch":      {"checking-account", "cash", "cash-at-home", "cash-in-wallet"},
+		"cacc":    {"checking-account"},
+		"hm":      {"cash-at-home"},
+		"xyz":     {},
+		"walletc": {},
+	} {
+		assert.Equal(t, expected, aliases(MatchAlias(accounts, query)), query)
+	}
+	assert.Len(t, MatchAlias(accounts, ""), len(accounts))
+}
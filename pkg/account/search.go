@@ -0,0 +1,17 @@
+package account
+
+import "github.com/fitiavana07/mitrack/pkg/search"
+
+// SearchDocument returns the search document of acc: its name, alias and
+// description.
+func SearchDocument(acc *Account) search.Document {
+	return search.Document{
+		Kind: search.KindAccount,
+		ID:   acc.ID.Hex(),
+		Fields: map[search.Field]string{
+			search.FieldName:        acc.Name,
+			search.FieldAlias:       acc.Alias,
+			search.FieldDescription: acc.Description,
+		},
+	}
+}
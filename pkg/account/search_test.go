@@ -0,0 +1,43 @@
+package account
+
+import (
+	"testing"
+
+	"github.com/fitiavana07/mitrack/pkg/search"
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccServiceSearch(t *testing.T) {
+	ix := search.NewIndex(store.NewDirStore(t.TempDir()))
+	s, err := NewAccServiceWithSearch(store.NewDirStore(t.TempDir()), ix)
+	require.NoError(t, err)
+
+	searchIDs := func(query string) []string {
+		results, err := ix.Search(query)
+		require.NoError(t, err)
+		ids := []string{}
+		for _, r := range results {
+			assert.Equal(t, search.KindAccount, r.Kind)
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+
+	wallet := NewAccount("Vola an-tanàna", TypeAsset)
+	require.NoError(t, s.Register(wallet))
+	assert.Equal(t, []string{wallet.ID.Hex()}, searchIDs("tanana"))
+
+	updated := *wallet
+	updated.Name = "Kitapo"
+	updated.Alias = "kitapo"
+	updated.Description = "vola eny am-paosy"
+	require.NoError(t, s.Update(&updated))
+	assert.Empty(t, searchIDs("tanana"))
+	assert.Equal(t, []string{wallet.ID.Hex()}, searchIDs("kitapo"))
+	assert.Equal(t, []string{wallet.ID.Hex()}, searchIDs("vola"), "description")
+
+	require.NoError(t, s.Delete(wallet.ID.Hex()))
+	assert.Empty(t, searchIDs("kitapo"))
+}
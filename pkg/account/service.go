@@ -1,16 +1,22 @@
 package account
 
 import (
-	"bufio"
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
+	"sync"
 
 	"github.com/fitiavana07/mitrack/pkg/encoding"
+	"github.com/fitiavana07/mitrack/pkg/search"
+	"github.com/fitiavana07/mitrack/pkg/store"
 )
 
 // AccService provides methods for managing accounts.
+// It is safe for concurrent use by multiple goroutines.
+//
+// The methods taking a context stop when it is done, returning ctx.Err().
 type AccService interface {
 	// ==== CREATE ====
 
@@ -18,13 +24,23 @@ type AccService interface {
 	// The provided account must be a valid initialized account
 	// (ie: with valid ID timestamp and alias).
 	Register(*Account) error
+	// RegisterContext is like Register, with a context.
+	RegisterContext(ctx context.Context, acc *Account) error
 
 	// ==== READ ====
 
 	// Count returns the total number of accounts in the DB.
-	Count() uint64
+	Count() (uint64, error)
+	// CountByType returns the number of accounts of the given type.
+	CountByType(t Type) (uint64, error)
+	// CountByTypeContext is like CountByType, with a context.
+	CountByTypeContext(ctx context.Context, t Type) (uint64, error)
 	// List returns all accounts in the DB.
-	List() []*Account
+	// If some accounts could not be read, the others are returned along with
+	// a *store.ListError listing the failures.
+	List() ([]*Account, error)
+	// ListContext is like List, with a context.
+	ListContext(ctx context.Context) ([]*Account, error)
 	// Get returns the account given the alias, short ID (prefix), or full ID (hex).
 	// The Order of search trials is: full ID, alias, prefix.
 	// For more inspiration, look at daemon/container at moby repo.
@@ -36,12 +52,16 @@ type AccService interface {
 	// GetByAlias would basically find the ID using a alias->ID map,
 	// then GetByID(id) to get the Account.
 	GetByAlias(alias string) (*Account, error)
+	// GetByAliasContext is like GetByAlias, with a context.
+	GetByAliasContext(ctx context.Context, alias string) (*Account, error)
 	// GetByPrefix returns the Account corresponding to a given ID prefix.
 	GetByPrefix(prefix string) (*Account, error)
 
 	// ==== UPDATE ====
 
 	// Update updates the given account in the database.
+	// Its ID is kept: it no longer matches ComputeID once its name, alias,
+	// description, type or parent changed.
 	Update(*Account) error
 
 	// ==== DELETE ====
@@ -56,102 +76,148 @@ type AccService interface {
 	Cleanup() error
 }
 
-// NewAccService returns a new AccService.
+// NewAccService returns a new AccService, keeping accounts files in accountsDir.
 func NewAccService(accountsDir string) (AccService, error) {
-	// file, err := os.OpenFile(baseFilePath, os.O_RDWR|os.O_CREATE, 0644)
+	return NewAccServiceWithStore(store.NewDirStore(accountsDir))
+}
 
-	dbinfoFilePath := filepath.Join(accountsDir, dbInfoFileName)
-	_, err := os.Stat(dbinfoFilePath)
-	if errors.Is(err, os.ErrNotExist) {
-		file, err := os.Create(dbinfoFilePath)
-		if err != nil {
-			return nil, fmt.Errorf("account.service: could not create .dbinfo: %s", err)
-		}
-		defer file.Close()
+// NewAccServiceWithStore returns a new AccService, keeping accounts in st,
+// without search index.
+func NewAccServiceWithStore(st store.Store) (AccService, error) {
+	return NewAccServiceWithSearch(st, nil)
+}
 
-		if _, err = file.WriteString("quick:v0.4"); err != nil {
-			return nil, fmt.Errorf("account.service: could not write .dbinfo content: %s", err)
+// NewAccServiceWithSearch returns a new AccService, keeping accounts in st
+// and indexing them into searchIndex.
+func NewAccServiceWithSearch(st store.Store, searchIndex *search.Index) (AccService, error) {
+	_, err := st.Get(dbInfoFileName)
+	if errors.Is(err, os.ErrNotExist) {
+		if err = st.Put(dbInfoFileName, []byte("quick:v0.4")); err != nil {
+			return nil, fmt.Errorf("account.service: could not write .dbinfo content: %w", err)
 		}
 	} else if err != nil {
-		return nil, fmt.Errorf("account.service: could not read .dbinfo: %s", err)
+		return nil, fmt.Errorf("account.service: could not read .dbinfo: %w", err)
 	}
 
-	return &accService{workDir: accountsDir}, nil
+	return &accService{store: st, cache: newAccountCache(), search: searchIndex}, nil
 }
 
 type accService struct {
-	workDir string
+	store store.Store
+	cache *accountCache
+
+	// search is nil if the accounts are not indexed for search.
+	search *search.Index
+
+	// mu serializes the updates of the accounts and of the counts metadata.
+	mu sync.Mutex
 }
 
 const dbInfoFileName = ".dbinfo"
 
 func (s *accService) Register(acc *Account) error {
-	path := filepath.Join(s.workDir, fmt.Sprintf("%x", acc.ID))
-	f, err := os.Create(path)
+	return s.RegisterContext(context.Background(), acc)
+}
+
+func (s *accService) RegisterContext(ctx context.Context, acc *Account) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b := new(bytes.Buffer)
+	rw, err := encoding.NewRecordWriter(b, encoding.FormatVersionCurrent)
 	if err != nil {
-		return fmt.Errorf("account.service: could not create account file: %s", err)
+		return fmt.Errorf("account.service: %w", err)
 	}
-	defer f.Close()
 
-	encoder := encoding.NewEncoderV3()
+	if err = rw.WriteEncoded(acc); err != nil {
+		return fmt.Errorf("account.service: %w", err)
+	}
+	if err = rw.Close(); err != nil {
+		return fmt.Errorf("account.service: %w", err)
+	}
 
-	toEncode := []interface{}{
-		acc.Type,
-		acc.ParentID,
-		acc.Timestamp,
-		acc.Alias,
-		acc.Name,
-		acc.Description,
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := store.LockOf(s.store)
+	if err != nil {
+		return fmt.Errorf("account.service: could not lock accounts: %w", err)
 	}
+	defer unlock()
 
-	b := bufio.NewWriter(f)
-	for _, v := range toEncode {
-		if err = encoder.WriteEncoded(b, v); err != nil {
-			return fmt.Errorf("account.service: %s", err)
-		}
+	// the counts are rebuilt by CountByType when not saved yet
+	counts, err := s.savedTypeCounts()
+	if err != nil {
+		return fmt.Errorf("account.service: could not read accounts counts: %w", err)
 	}
-	if err = b.Flush(); err != nil {
-		return fmt.Errorf("account.service: error while writing buffered data into file: %s", err)
+	_, err = s.store.Get(acc.ID.Hex())
+	exists := err == nil
+	var old *Account
+	if exists {
+		// unindexed if unreadable
+		old, _ = s.GetByActualID(acc.ID)
 	}
 
+	if err = s.store.Put(acc.ID.Hex(), b.Bytes()); err != nil {
+		return fmt.Errorf("account.service: could not write account file: %w", err)
+	}
+	s.cache.invalidate(acc.ID)
+	if err = s.index(old, acc); err != nil {
+		return fmt.Errorf("account.service: account %s registered but not indexed, reindex needed: %w", acc.ID.Short(), err)
+	}
+
+	if counts != nil && !exists {
+		counts.Records++
+		if acc.Type.IsValid() {
+			counts.Types[acc.Type.String()]++
+		}
+		if err = s.saveTypeCounts(counts); err != nil {
+			return fmt.Errorf("account.service: could not save accounts counts: %w", err)
+		}
+	}
 	return nil
 }
 
-func (s *accService) Count() uint64 {
-	// TODO
-	return 0
+func (s *accService) List() ([]*Account, error) {
+	return s.ListContext(context.Background())
 }
 
-func (s *accService) List() []*Account {
-	accounts := []*Account{}
-
-	dirEntries, err := os.ReadDir(s.workDir)
+func (s *accService) ListContext(ctx context.Context) ([]*Account, error) {
+	keys, err := s.store.Keys()
 	if err != nil {
-		return accounts
+		return nil, fmt.Errorf("account.service: could not list accounts: %w", err)
 	}
 
-	for _, entry := range dirEntries {
-		fileName := entry.Name()
-		actualID, err := DecodeID(fileName)
+	accounts := []*Account{}
+	listErr := &store.ListError{}
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		actualID, err := DecodeID(key)
 		if err != nil {
-			// the fileName is not of an account
+			listErr.Add(key, fmt.Errorf("not an account file: %w", err))
 			continue
 		}
 		acc, err := s.GetByActualID(actualID)
 		if err != nil {
-			// TODO should we really not return any error?
+			listErr.Add(key, err)
 			continue
 		}
 
 		accounts = append(accounts, acc)
 	}
 
-	return accounts
+	return accounts, listErr.ErrOrNil()
 }
 
 func (s *accService) Get(prefixOrAlias string) (*Account, error) {
-	// TODO
-	return nil, nil
+	if id, err := DecodeID(prefixOrAlias); err == nil {
+		return s.GetByActualID(id)
+	}
+	// TODO search by prefix, once GetByPrefix is implemented
+	return s.GetByAlias(prefixOrAlias)
 }
 
 func (s *accService) GetByID(id string) (*Account, error) {
@@ -163,50 +229,62 @@ func (s *accService) GetByID(id string) (*Account, error) {
 	return s.GetByActualID(actualID)
 }
 
+// GetByActualID returns the cached account while its file did not change.
+// Stores which do not keep values in files are not cached.
 func (s *accService) GetByActualID(id ID) (*Account, error) {
-	hexValue := id.Hex()
-	accountFilePath := filepath.Join(s.workDir, hexValue)
-	f, err := os.Open(accountFilePath)
+	info, statErr := store.StatOf(s.store, id.Hex())
+	if statErr == nil {
+		if a, ok := s.cache.get(id, info); ok {
+			return a, nil
+		}
+	}
+
+	b, err := s.store.Get(id.Hex())
 	if errors.Is(err, os.ErrNotExist) {
-		return nil, fmt.Errorf("account.service: account not found")
+		return nil, fmt.Errorf("account.service: %w", ErrAccountNotFound)
 	} else if err != nil {
-		return nil, fmt.Errorf("account.service: could not open account file: %s", err)
+		return nil, fmt.Errorf("account.service: could not read account file: %w", err)
 	}
-	defer f.Close()
-
-	decoder := encoding.NewDecoderV3()
 
-	a := Account{ID: id}
-	toDecode := []interface{}{
-		&(a.Type),
-		&(a.ParentID),
-		&(a.Timestamp),
-		&(a.Alias),
-		&(a.Name),
-		&(a.Description),
+	a, err := Decode(id, b)
+	if err != nil {
+		err = encoding.SetDecodePath(err, store.PathOf(s.store, id.Hex()))
+		return nil, fmt.Errorf("account.service: invalid account file format: %w", err)
 	}
 
-	r := bufio.NewReader(f)
-	for _, v := range toDecode {
-		if err = decoder.ReadDecoded(r, v); err != nil {
-			return nil, fmt.Errorf("account.service: invalid account file format: %s", err)
-		}
+	if statErr == nil {
+		s.cache.put(a, info)
 	}
-
-	return &a, nil
+	return a, nil
 }
 
 func (s *accService) GetByAlias(alias string) (*Account, error) {
+	return s.GetByAliasContext(context.Background(), alias)
+}
+
+func (s *accService) GetByAliasContext(ctx context.Context, alias string) (*Account, error) {
 	// TODO add alias->account index
-	accounts := s.List()
+	accounts, err := s.ListContext(ctx)
+	var listErr *store.ListError
+	if err != nil && !errors.As(err, &listErr) {
+		return nil, err
+	}
 
 	for _, a := range accounts {
 		if a.Alias == alias {
 			return a, nil
 		}
 	}
+	if listErr != nil {
+		for _, f := range listErr.Failures {
+			if errors.Is(f, store.ErrLocked) {
+				// the account may be one of the encrypted ones
+				return nil, fmt.Errorf("account.service: %w", store.ErrLocked)
+			}
+		}
+	}
 
-	return nil, fmt.Errorf("account not found")
+	return nil, fmt.Errorf("account.service: %w", ErrAccountNotFound)
 }
 
 func (s *accService) GetByPrefix(prefix string) (*Account, error) {
@@ -214,12 +292,115 @@ func (s *accService) GetByPrefix(prefix string) (*Account, error) {
 	return nil, nil
 }
 
-func (s *accService) Update(*Account) error {
-	// TODO
+func (s *accService) Update(acc *Account) error {
+	b := new(bytes.Buffer)
+	rw, err := encoding.NewRecordWriter(b, encoding.FormatVersionCurrent)
+	if err != nil {
+		return fmt.Errorf("account.service: %w", err)
+	}
+	if err = rw.WriteEncoded(acc); err != nil {
+		return fmt.Errorf("account.service: %w", err)
+	}
+	if err = rw.Close(); err != nil {
+		return fmt.Errorf("account.service: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := store.LockOf(s.store)
+	if err != nil {
+		return fmt.Errorf("account.service: could not lock accounts: %w", err)
+	}
+	defer unlock()
+
+	old, err := s.GetByActualID(acc.ID)
+	if err != nil {
+		return err
+	}
+	counts, err := s.savedTypeCounts()
+	if err != nil {
+		return fmt.Errorf("account.service: could not read accounts counts: %w", err)
+	}
+
+	err = s.store.Put(acc.ID.Hex(), b.Bytes())
+	s.cache.invalidate(acc.ID)
+	if err != nil {
+		return fmt.Errorf("account.service: could not write account file: %w", err)
+	}
+	if err = s.index(old, acc); err != nil {
+		return fmt.Errorf("account.service: account %s updated but not indexed, reindex needed: %w", acc.ID.Short(), err)
+	}
+
+	if counts != nil && old.Type != acc.Type {
+		if old.Type.IsValid() {
+			counts.Types[old.Type.String()]--
+		}
+		if acc.Type.IsValid() {
+			counts.Types[acc.Type.String()]++
+		}
+		if err = s.saveTypeCounts(counts); err != nil {
+			return fmt.Errorf("account.service: could not save accounts counts: %w", err)
+		}
+	}
 	return nil
 }
+
 func (s *accService) Delete(prefixOrAlias string) error {
-	// TODO
+	acc, err := s.Get(prefixOrAlias)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := store.LockOf(s.store)
+	if err != nil {
+		return fmt.Errorf("account.service: could not lock accounts: %w", err)
+	}
+	defer unlock()
+
+	counts, err := s.savedTypeCounts()
+	if err != nil {
+		return fmt.Errorf("account.service: could not read accounts counts: %w", err)
+	}
+
+	err = s.store.Delete(acc.ID.Hex())
+	s.cache.invalidate(acc.ID)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("account.service: %w", ErrAccountNotFound)
+	} else if err != nil {
+		return fmt.Errorf("account.service: could not delete account file: %w", err)
+	}
+	if err = s.index(acc, nil); err != nil {
+		return fmt.Errorf("account.service: account %s deleted but still indexed, reindex needed: %w", acc.ID.Short(), err)
+	}
+
+	if counts != nil {
+		counts.Records--
+		if acc.Type.IsValid() {
+			counts.Types[acc.Type.String()]--
+		}
+		if err = s.saveTypeCounts(counts); err != nil {
+			return fmt.Errorf("account.service: could not save accounts counts: %w", err)
+		}
+	}
+	return nil
+}
+
+// index replaces the search document of old by the one of acc, any of them
+// being nil when the account was registered or deleted.
+func (s *accService) index(old, acc *Account) error {
+	if s.search == nil {
+		return nil
+	}
+	if old != nil {
+		if err := s.search.Remove(SearchDocument(old)); err != nil {
+			return err
+		}
+	}
+	if acc != nil {
+		return s.search.Add(SearchDocument(acc))
+	}
 	return nil
 }
 
@@ -229,3 +410,6 @@ func (s *accService) Cleanup() error {
 
 // ErrUnimplemented is returned from unimplemented functions.
 var ErrUnimplemented = errors.New("unimplemented")
+
+// ErrAccountNotFound is returned when no account matches the search.
+var ErrAccountNotFound = errors.New("account not found")
@@ -1,13 +1,13 @@
 package account
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/fitiavana07/mitrack/pkg/encoding"
+	"github.com/fitiavana07/mitrack/pkg/store"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -83,37 +83,37 @@ func TestAccServiceRegister(t *testing.T) {
 		require.NoError(t, err)
 		defer f.Close()
 
-		r := bufio.NewReader(f)
-
-		decoder := encoding.NewDecoderV3()
+		r, err := encoding.NewRecordReader(f)
+		require.NoError(t, err)
+		assert.Equal(t, encoding.FormatVersionCurrent, r.Version(), "record format version")
 
 		accType := new(Type)
-		err = decoder.ReadDecoded(r, accType)
+		err = r.ReadDecoded(accType)
 		assert.NoError(t, err, "error reading account type")
 		assert.Equal(t, acc.Type, *accType, "account type")
 
 		parentID := new(ID)
-		err = decoder.ReadDecoded(r, parentID)
+		err = r.ReadDecoded(parentID)
 		assert.NoError(t, err, "error reading account parentID")
 		assert.Equal(t, acc.ParentID, *parentID, "account parentID")
 
 		timestamp := new(int64)
-		err = decoder.ReadDecoded(r, timestamp)
+		err = r.ReadDecoded(timestamp)
 		assert.NoError(t, err, "error reading account timestamp")
 		assert.Equal(t, acc.Timestamp, *timestamp, "account timestamp")
 
 		alias := new(string)
-		err = decoder.ReadDecoded(r, alias)
+		err = r.ReadDecoded(alias)
 		assert.NoError(t, err, "error reading account alias")
 		assert.Equal(t, acc.Alias, *alias, "account alias")
 
 		name := new(string)
-		err = decoder.ReadDecoded(r, name)
+		err = r.ReadDecoded(name)
 		assert.NoError(t, err, "error reading account name")
 		assert.Equal(t, acc.Name, *name, "account name")
 
 		description := new(string)
-		err = decoder.ReadDecoded(r, description)
+		err = r.ReadDecoded(description)
 		assert.NoError(t, err, "error reading account description")
 		assert.Equal(t, acc.Description, *description, "account description")
 	})
@@ -123,14 +123,64 @@ func TestAccServiceRegister(t *testing.T) {
 }
 
 func TestAccServiceCount(t *testing.T) {
-	t.Run("0 account", func(t *testing.T) {
-		// TODO
+	dir := t.TempDir()
+	s, cleanup := createFakeService(t, dir)
+	defer cleanup()
+
+	assertCounts := func(t *testing.T, s AccService, total, assets, expenses uint64) {
+		t.Helper()
+		count, err := s.Count()
+		require.NoError(t, err)
+		assert.Equal(t, total, count)
+
+		count, err = s.CountByType(TypeAsset)
+		require.NoError(t, err)
+		assert.Equal(t, assets, count)
+
+		count, err = s.CountByType(TypeExpense)
+		require.NoError(t, err)
+		assert.Equal(t, expenses, count)
+	}
+
+	assertCounts(t, s, 0, 0, 0)
+
+	cash := NewAccount("Cash", TypeAsset)
+	require.NoError(t, s.Register(cash))
+	require.NoError(t, s.Register(NewAccount("Bank", TypeAsset)))
+	require.NoError(t, s.Register(NewAccount("Food", TypeExpense)))
+	assertCounts(t, s, 3, 2, 1)
+
+	t.Run("registered twice", func(t *testing.T) {
+		require.NoError(t, s.Register(cash))
+		assertCounts(t, s, 3, 2, 1)
 	})
-	t.Run("1 account", func(t *testing.T) {
-		// TODO
+	t.Run("counts not saved", func(t *testing.T) {
+		require.NoError(t, os.Remove(filepath.Join(dir, typeCountsKey)))
+		require.NoError(t, s.Register(NewAccount("Rent", TypeExpense)))
+		assertCounts(t, s, 4, 2, 2)
+		assert.FileExists(t, filepath.Join(dir, typeCountsKey), "counts must be saved once rebuilt")
 	})
-	t.Run("2 account", func(t *testing.T) {
-		// TODO
+	t.Run("stale counts", func(t *testing.T) {
+		// registered by two processes at the same time: the last saved
+		// counts miss the account of the other one
+		stale, err := os.ReadFile(filepath.Join(dir, typeCountsKey))
+		require.NoError(t, err)
+		other, cleanup := createFakeService(t, dir)
+		defer cleanup()
+		require.NoError(t, other.Register(NewAccount("Savings", TypeAsset)))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, typeCountsKey), stale, 0644))
+		assertCounts(t, s, 5, 3, 2)
+
+		// quarantined by db check --repair
+		savings, err := s.GetByAlias("savings")
+		require.NoError(t, err)
+		require.NoError(t, os.Rename(filepath.Join(dir, savings.ID.Hex()), filepath.Join(t.TempDir(), "savings")))
+		assertCounts(t, s, 4, 2, 2)
+	})
+	t.Run("not decoded", func(t *testing.T) {
+		// counting must not read the accounts
+		require.NoError(t, os.WriteFile(filepath.Join(dir, cash.ID.Hex()), []byte{0x42}, 0644))
+		assertCounts(t, s, 4, 2, 2)
 	})
 }
 
@@ -141,7 +191,9 @@ func TestAccServiceList(t *testing.T) {
 		s, cleanup := createFakeService(t, dir)
 		defer cleanup()
 
-		assert.Empty(t, s.List())
+		accounts, err := s.List()
+		require.NoError(t, err)
+		assert.Empty(t, accounts)
 	})
 	t.Run("1 account", func(t *testing.T) {
 		dir := t.TempDir()
@@ -154,7 +206,8 @@ func TestAccServiceList(t *testing.T) {
 		s2, cleanup := createFakeService(t, dir)
 		defer cleanup()
 
-		accounts := s2.List()
+		accounts, err := s2.List()
+		require.NoError(t, err)
 
 		assert.Equal(t, 1, len(accounts), "wrong list length")
 		assert.Containsf(t, accounts, acc, "account not in list")
@@ -175,12 +228,46 @@ func TestAccServiceList(t *testing.T) {
 		s2, cleanup := createFakeService(t, dir)
 		defer cleanup()
 
-		accounts := s2.List()
+		accounts, err := s2.List()
+		require.NoError(t, err)
 
 		assert.Equal(t, 2, len(accounts))
 		assert.Containsf(t, accounts, acc1, "account not in list")
 		assert.Containsf(t, accounts, acc2, "account not in list")
 	})
+	t.Run("unreadable accounts", func(t *testing.T) {
+		dir := t.TempDir()
+
+		s, cleanup := createFakeService(t, dir)
+		defer cleanup()
+
+		acc := NewAccount("Trosa", TypeLiability)
+		require.NoError(t, s.Register(acc))
+
+		corrupted := NewAccount("Vola nampindramina", TypeAsset).ID.Hex()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, corrupted), []byte{0x42}, 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "notes"), []byte("notes"), 0644))
+
+		accounts, err := s.List()
+		assert.Equal(t, []*Account{acc}, accounts, "readable accounts must still be listed")
+
+		var listErr *store.ListError
+		require.ErrorAs(t, err, &listErr)
+		require.Len(t, listErr.Failures, 2)
+		assert.Equal(t, corrupted, listErr.Failures[0].Key)
+		assert.Equal(t, "notes", listErr.Failures[1].Key)
+	})
+	t.Run("unreadable directory", func(t *testing.T) {
+		dir := t.TempDir()
+
+		s, cleanup := createFakeService(t, dir)
+		defer cleanup()
+		require.NoError(t, os.RemoveAll(dir))
+
+		accounts, err := s.List()
+		assert.Nil(t, accounts)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
 }
 
 func TestAccServiceGet(t *testing.T) {
@@ -230,12 +317,89 @@ func TestAccServiceGetByActualID(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, acc, foundAcc)
 	})
-	t.Run("not existing", func(t *testing.T) {})
+	t.Run("v3 account file", func(t *testing.T) {
+		dir := t.TempDir()
+
+		s, cleanup := createFakeService(t, dir)
+		defer cleanup()
+
+		acc := NewAccount("Jiro sy Rano", TypeExpense)
+
+		f, err := os.Create(filepath.Join(dir, acc.ID.Hex()))
+		require.NoError(t, err)
+		rw, err := encoding.NewRecordWriter(f, encoding.FormatVersionV3)
+		require.NoError(t, err)
+		for _, v := range []interface{}{acc.Type, acc.ParentID, acc.Timestamp, acc.Alias, acc.Name, acc.Description} {
+			require.NoError(t, rw.WriteEncoded(v))
+		}
+		require.NoError(t, rw.Close())
+		require.NoError(t, f.Close())
+
+		foundAcc, err := s.GetByActualID(acc.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, acc, foundAcc)
+	})
+	t.Run("not existing", func(t *testing.T) {
+		s, cleanup := createFakeService(t, t.TempDir())
+		defer cleanup()
+
+		_, err := s.GetByActualID(NewAccount("Jiro sy Rano", TypeExpense).ID)
+
+		assert.ErrorIs(t, err, ErrAccountNotFound)
+	})
+	t.Run("corrupted account file", func(t *testing.T) {
+		dir := t.TempDir()
+
+		s, cleanup := createFakeService(t, dir)
+		defer cleanup()
+
+		acc := NewAccount("Jiro sy Rano", TypeExpense)
+		require.NoError(t, s.Register(acc))
+
+		path := filepath.Join(dir, acc.ID.Hex())
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		// the last byte is in the checksum
+		b[len(b)-1] ^= 0xff
+		require.NoError(t, os.WriteFile(path, b, 0644))
+
+		_, err = s.GetByActualID(acc.ID)
+
+		var decodeErr *encoding.DecodeError
+		require.ErrorAs(t, err, &decodeErr)
+		assert.Equal(t, path, decodeErr.Path)
+		assert.ErrorIs(t, err, encoding.ErrChecksumMismatch)
+	})
 }
 
 func TestAccServiceGetByAlias(t *testing.T) {
 	t.Run("existing", func(t *testing.T) {})
-	t.Run("not existing", func(t *testing.T) {})
+	t.Run("not existing", func(t *testing.T) {
+		s, cleanup := createFakeService(t, t.TempDir())
+		defer cleanup()
+
+		_, err := s.GetByAlias("nothing")
+
+		assert.ErrorIs(t, err, ErrAccountNotFound)
+	})
+	t.Run("locked store", func(t *testing.T) {
+		dir := t.TempDir()
+		encrypted, err := store.NewEncryptedStore(store.NewDirStore(dir), make([]byte, store.KeySize))
+		require.NoError(t, err)
+		s, err := NewAccServiceWithStore(encrypted)
+		require.NoError(t, err)
+		require.NoError(t, s.Register(NewAccount("Jiro sy Rano", TypeExpense)))
+
+		locked, err := store.NewEncryptedStore(store.NewDirStore(dir), nil)
+		require.NoError(t, err)
+		s, err = NewAccServiceWithStore(locked)
+		require.NoError(t, err)
+
+		_, err = s.GetByAlias("jiro-sy-rano")
+
+		assert.ErrorIs(t, err, store.ErrLocked)
+	})
 }
 
 func TestAccServiceGetByPrefix(t *testing.T) {
@@ -255,11 +419,34 @@ func TestAccServiceUpdate(t *testing.T) {
 	t.Run("update type impossible", func(t *testing.T) {})
 	t.Run("update parent", func(t *testing.T) {})
 	t.Run("update timestamp impossible", func(t *testing.T) {})
-	t.Run("not existing", func(t *testing.T) {})
+	t.Run("not existing", func(t *testing.T) {
+		s, cleanup := createFakeService(t, t.TempDir())
+		defer cleanup()
+
+		err := s.Update(NewAccount("Jiro sy Rano", TypeExpense))
+
+		assert.ErrorIs(t, err, ErrAccountNotFound)
+	})
 }
 
 func TestAccServiceDelete(t *testing.T) {
-	t.Run("delete success on unused account", func(t *testing.T) {})
+	t.Run("delete success on unused account", func(t *testing.T) {
+		s, cleanup := createFakeService(t, t.TempDir())
+		defer cleanup()
+
+		acc := NewAccount("Jiro sy Rano", TypeExpense)
+		require.NoError(t, s.Register(acc))
+		_, err := s.GetByActualID(acc.ID)
+		require.NoError(t, err)
+
+		require.NoError(t, s.Delete(acc.Alias))
+
+		_, err = s.GetByActualID(acc.ID)
+		assert.ErrorIs(t, err, ErrAccountNotFound)
+		count, err := s.CountByType(TypeExpense)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
 	t.Run("delete errors on used account", func(t *testing.T) {
 		// TODO for example set "used" to true once used in transaction.
 	})
@@ -0,0 +1,26 @@
+package account
+
+// Descendants returns the IDs of the accounts of the tree rooted at id:
+// id itself, then its children, their children, and so on. The tree is
+// given by the ParentID of accounts.
+func Descendants(accounts []*Account, id ID) []ID {
+	children := map[ID][]ID{}
+	for _, acc := range accounts {
+		if acc.ParentID != (ID{}) {
+			children[acc.ParentID] = append(children[acc.ParentID], acc.ID)
+		}
+	}
+
+	ids := []ID{id}
+	seen := map[ID]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			// a corrupted tree may have cycles
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
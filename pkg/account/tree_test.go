@@ -0,0 +1,28 @@
+package account
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescendants(t *testing.T) {
+	expenses := &Account{ID: ID{1}}
+	food := &Account{ID: ID{2}, ParentID: expenses.ID}
+	restaurant := &Account{ID: ID{3}, ParentID: food.ID}
+	rent := &Account{ID: ID{4}, ParentID: expenses.ID}
+	cash := &Account{ID: ID{5}}
+	accounts := []*Account{restaurant, rent, cash, food, expenses}
+
+	assert.Equal(t, []ID{expenses.ID, rent.ID, food.ID, restaurant.ID}, Descendants(accounts, expenses.ID))
+	assert.Equal(t, []ID{food.ID, restaurant.ID}, Descendants(accounts, food.ID))
+	assert.Equal(t, []ID{cash.ID}, Descendants(accounts, cash.ID))
+	assert.Equal(t, []ID{{9}}, Descendants(accounts, ID{9}), "unknown account")
+
+	t.Run("cycle", func(t *testing.T) {
+		a := &Account{ID: ID{1}, ParentID: ID{2}}
+		b := &Account{ID: ID{2}, ParentID: ID{1}}
+
+		assert.Equal(t, []ID{a.ID, b.ID}, Descendants([]*Account{a, b}, a.ID))
+	})
+}
@@ -77,6 +77,12 @@ func (t Type) Initial() string {
 	return initialMap[t]
 }
 
+// IsDebitNormal returns whether debits increase the balance of the accounts
+// of type t, as for assets and expenses; credits increase the others.
+func (t Type) IsDebitNormal() bool {
+	return t == TypeAsset || t == TypeExpense
+}
+
 // TypeFromString returns the account type corresponding to a given string.
 func TypeFromString(s string) (t Type, err error) {
 	switch s {
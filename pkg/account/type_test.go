@@ -42,3 +42,22 @@ func TestTypeIsValid(t *testing.T) {
 		}
 	}
 }
+
+func TestTypeIsDebitNormal(t *testing.T) {
+	tt := []struct {
+		accType         Type
+		wantDebitNormal bool
+	}{
+		{TypeAsset, true},
+		{TypeLiability, false},
+		{TypeEquity, false},
+		{TypeExpense, true},
+		{TypeRevenue, false},
+	}
+
+	for _, tc := range tt {
+		if got := tc.accType.IsDebitNormal(); got != tc.wantDebitNormal {
+			t.Errorf("wrong IsDebitNormal for %s, got %v, want %v", tc.accType, got, tc.wantDebitNormal)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Archives are gzipped tarballs whose first entry is the manifest,
+// followed by the backed up files.
+
+// ManifestName is the name of the manifest entry in archives.
+const ManifestName = "MANIFEST.json"
+
+// manifestVersion is the version of the manifest format.
+const manifestVersion = 1
+
+// Manifest lists the files of an archive, with their hashes.
+type Manifest struct {
+	Version int            `json:"version"`
+	Created time.Time      `json:"created"`
+	Files   []ManifestFile `json:"files"`
+}
+
+// ManifestFile is a file of an archive.
+type ManifestFile struct {
+	// Path is the slash-separated path of the file, relative to the root
+	// of the archive.
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Create writes into w an archive of the given paths of root, which may be
+// files or directories. Paths that do not exist are skipped.
+func Create(w io.Writer, root string, paths []string) (*Manifest, error) {
+	return CreateDirs(w, []Dir{{Root: root, Paths: paths}})
+}
+
+// Dir is a directory some paths of which are archived, relative to the
+// root of the archive.
+type Dir struct {
+	Root  string
+	Paths []string
+}
+
+// CreateDirs is like Create, archiving the paths of several directories
+// together. A path found in two of them is an error.
+func CreateDirs(w io.Writer, dirs []Dir) (*Manifest, error) {
+	m := &Manifest{Version: manifestVersion, Created: time.Now().UTC()}
+
+	// sources are the paths of the files, by archive path
+	sources := map[string]string{}
+	for _, dir := range dirs {
+		root := dir.Root
+		for _, p := range dir.Paths {
+			err := filepath.WalkDir(filepath.Join(root, p), func(path string, d fs.DirEntry, err error) error {
+				if errors.Is(err, os.ErrNotExist) && path == filepath.Join(root, p) {
+					return fs.SkipDir
+				} else if err != nil {
+					return err
+				}
+				if !d.Type().IsRegular() {
+					return nil
+				}
+
+				rel, err := filepath.Rel(root, path)
+				if err != nil {
+					return err
+				}
+				f, err := hashFile(path)
+				if err != nil {
+					return err
+				}
+				f.Path = filepath.ToSlash(rel)
+				if _, ok := sources[f.Path]; ok {
+					return fmt.Errorf("%s: archived twice", f.Path)
+				}
+				sources[f.Path] = path
+				m.Files = append(m.Files, f)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Path < m.Files[j].Path })
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeEntry(tw, ManifestName, m.Created, manifest); err != nil {
+		return nil, err
+	}
+
+	for _, f := range m.Files {
+		b, err := os.ReadFile(sources[f.Path])
+		if err != nil {
+			return nil, err
+		}
+		if hashBytes(b) != f.SHA256 {
+			return nil, fmt.Errorf("%s: file changed while creating the backup", f.Path)
+		}
+		if err := writeEntry(tw, f.Path, m.Created, b); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Extract extracts the archive read from r into the directory dst, which
+// must not exist, after having verified that the archive matches its
+// manifest. On error, nothing is left in dst.
+func Extract(r io.Reader, dst string) (m *Manifest, err error) {
+	if err := os.Mkdir(dst, 0755); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dst)
+		}
+	}()
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+	}
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != ManifestName {
+		return nil, fmt.Errorf("%w: missing manifest", ErrInvalidArchive)
+	}
+	m = &Manifest{}
+	if err := json.NewDecoder(tr).Decode(m); err != nil {
+		return nil, fmt.Errorf("%w: invalid manifest: %s", ErrInvalidArchive, err)
+	}
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("%w: unsupported manifest version %d", ErrInvalidArchive, m.Version)
+	}
+
+	expected := map[string]ManifestFile{}
+	for _, f := range m.Files {
+		expected[f.Path] = f
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+		}
+
+		f, ok := expected[hdr.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not in the manifest", ErrInvalidArchive, hdr.Name)
+		}
+		delete(expected, hdr.Name)
+
+		if hdr.Typeflag != tar.TypeReg || hdr.Size != f.Size {
+			return nil, fmt.Errorf("%w: %s does not match the manifest", ErrInvalidArchive, hdr.Name)
+		}
+		target, err := safeJoin(dst, hdr.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := io.ReadAll(io.LimitReader(tr, f.Size))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, err)
+		}
+		if hashBytes(b) != f.SHA256 {
+			return nil, fmt.Errorf("%w: %s does not match its hash", ErrInvalidArchive, hdr.Name)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(target, b, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	for p := range expected {
+		return nil, fmt.Errorf("%w: %s is missing", ErrInvalidArchive, p)
+	}
+	return m, nil
+}
+
+// safeJoin joins dst and the slash-separated name of an archive entry,
+// refusing names escaping dst.
+func safeJoin(dst, name string) (string, error) {
+	clean := path.Clean(name)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || clean != name {
+		return "", fmt.Errorf("%w: invalid path %q", ErrInvalidArchive, name)
+	}
+	return filepath.Join(dst, filepath.FromSlash(clean)), nil
+}
+
+func writeEntry(tw *tar.Writer, name string, modTime time.Time, b []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(b)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(b)
+	return err
+}
+
+func hashFile(path string) (ManifestFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ManifestFile{}, err
+	}
+	return ManifestFile{Size: int64(len(b)), SHA256: hashBytes(b)}, nil
+}
+
+func hashBytes(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// ErrInvalidArchive is returned when an archive is corrupted or does not
+// match its manifest.
+var ErrInvalidArchive = errors.New("invalid backup archive")
@@ -0,0 +1,120 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateExtract(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "accounts/.dbinfo", "quick:v0.4")
+	writeTestFile(t, root, "accounts/f04de23d", "account")
+	writeTestFile(t, root, "transactions/a1b2c3", "transaction")
+
+	b := new(bytes.Buffer)
+	m, err := Create(b, root, []string{"accounts", "transactions", "config"})
+	require.NoError(t, err)
+	assert.Len(t, m.Files, 3)
+
+	dst := filepath.Join(t.TempDir(), "restored")
+	got, err := Extract(b, dst)
+
+	require.NoError(t, err)
+	assert.Equal(t, m.Files, got.Files)
+	for _, f := range []string{"accounts/.dbinfo", "accounts/f04de23d", "transactions/a1b2c3"} {
+		want, err := os.ReadFile(filepath.Join(root, f))
+		require.NoError(t, err)
+		restored, err := os.ReadFile(filepath.Join(dst, f))
+		assert.NoError(t, err)
+		assert.Equal(t, want, restored)
+	}
+}
+
+func TestCreateDirs(t *testing.T) {
+	workdir := t.TempDir()
+	ledger := filepath.Join(workdir, "ledgers", "business")
+	writeTestFile(t, ledger, "accounts/f04de23d", "account")
+	writeTestFile(t, workdir, "accounts/a1b2c3", "other account")
+	writeTestFile(t, workdir, "config/config.yaml", "ledger: business")
+
+	b := new(bytes.Buffer)
+	_, err := CreateDirs(b, []Dir{
+		{Root: ledger, Paths: []string{"accounts"}},
+		{Root: workdir, Paths: []string{"config"}},
+	})
+	require.NoError(t, err)
+
+	dst := filepath.Join(t.TempDir(), "restored")
+	m, err := Extract(b, dst)
+	require.NoError(t, err)
+	paths := []string{}
+	for _, f := range m.Files {
+		paths = append(paths, f.Path)
+	}
+	assert.Equal(t, []string{"accounts/f04de23d", "config/config.yaml"}, paths)
+
+	_, err = CreateDirs(new(bytes.Buffer), []Dir{
+		{Root: ledger, Paths: []string{"accounts"}},
+		{Root: ledger, Paths: []string{"accounts"}},
+	})
+	assert.Error(t, err, "archived twice")
+}
+
+func TestExtractInvalid(t *testing.T) {
+	manifest := `{"version":1,"files":[{"path":"accounts/a","size":1,"sha256":"ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"}]}`
+
+	tests := []struct {
+		name    string
+		entries [][2]string
+	}{
+		{"missing manifest", [][2]string{{"accounts/a", "a"}}},
+		{"tampered file", [][2]string{{ManifestName, manifest}, {"accounts/a", "b"}}},
+		{"missing file", [][2]string{{ManifestName, manifest}}},
+		{"extra file", [][2]string{{ManifestName, manifest}, {"accounts/a", "a"}, {"accounts/b", "b"}}},
+		{"path traversal", [][2]string{
+			{ManifestName, `{"version":1,"files":[{"path":"../a","size":1,"sha256":"ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"}]}`},
+			{"../a", "a"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "restored")
+			_, err := Extract(makeTestArchive(t, tt.entries), dst)
+
+			assert.ErrorIs(t, err, ErrInvalidArchive)
+			assert.NoDirExists(t, dst, "partially extracted archive was not removed")
+		})
+	}
+}
+
+func writeTestFile(t testing.TB, root, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(root, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func makeTestArchive(t testing.TB, entries [][2]string) *bytes.Buffer {
+	t.Helper()
+
+	b := new(bytes.Buffer)
+	gz := gzip.NewWriter(b)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: e[0], Mode: 0644, Size: int64(len(e[1]))}))
+		_, err := tw.Write([]byte(e[1]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return b
+}
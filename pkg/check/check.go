@@ -0,0 +1,322 @@
+// Package check verifies the integrity of the mitrack databases.
+package check
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+)
+
+// QuarantineDirName is the directory of a database into which Repair
+// moves the bad files.
+const QuarantineDirName = ".quarantine"
+
+const (
+	dbInfoFileName = ".dbinfo"
+	dbInfoContent  = "quick:v0.4"
+)
+
+// Kind is the kind of a Problem.
+type Kind uint8
+
+const (
+	// KindUnreadable is a record which can not be read or decoded.
+	KindUnreadable Kind = iota + 1
+
+	// KindNameMismatch is a record whose file name is not its transaction
+	// hash. Accounts can not be told so, as their ID is kept when they are
+	// updated.
+	KindNameMismatch
+
+	// KindOrphan is a file which is neither a record nor metadata (dot-file)
+	// of the database.
+	KindOrphan
+
+	// KindMissingAccount is a record referencing an account which does not
+	// exist (transaction entry or parent account).
+	KindMissingAccount
+
+	// KindUnbalanced is a transaction whose debits do not equal its credits.
+	KindUnbalanced
+
+	// KindBadDBInfo is a missing or invalid .dbinfo file.
+	KindBadDBInfo
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindUnreadable:
+		return "unreadable"
+	case KindNameMismatch:
+		return "name mismatch"
+	case KindOrphan:
+		return "orphan"
+	case KindMissingAccount:
+		return "missing account"
+	case KindUnbalanced:
+		return "unbalanced"
+	case KindBadDBInfo:
+		return "bad .dbinfo"
+	default:
+		return "unknown"
+	}
+}
+
+// Problem is an integrity problem found by Check.
+type Problem struct {
+	Kind Kind
+
+	// Path is the path of the file having the problem.
+	Path string
+
+	// Detail describes the problem.
+	Detail string
+
+	// Packed is whether the record is in a pack file (see store.PackDir),
+	// Path being then the one of the pack followed by #key.
+	Packed bool
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.Path, p.Kind, p.Detail)
+}
+
+// Repairable returns whether Repair quarantines the file of the problem.
+// Only files which are not valid records are quarantined: the other
+// problems need a human decision. Packed records can not be quarantined.
+func (p Problem) Repairable() bool {
+	if p.Packed {
+		return false
+	}
+	switch p.Kind {
+	case KindUnreadable, KindNameMismatch, KindOrphan:
+		return true
+	default:
+		return false
+	}
+}
+
+// Database is a database to check.
+type Database struct {
+	// Dir is the directory of the database.
+	Dir string
+
+	// Store is the store of the records of Dir.
+	Store store.Store
+}
+
+// Report is the result of Check.
+type Report struct {
+	// Accounts and Transactions are the numbers of valid records.
+	Accounts     int
+	Transactions int
+
+	Problems []Problem
+}
+
+// OK returns whether no problem was found.
+func (r *Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// Check walks every file of the accounts and transactions databases and
+// reports the problems found.
+// It returns an error, and no report, if a database can not be read at all
+// (ex: an encrypted store without key).
+func Check(accounts, transactions Database) (*Report, error) {
+	r := &Report{}
+
+	accountIDs := map[account.ID]bool{}
+	parents := map[record]account.ID{}
+
+	err := walk(r, accounts, func(rec record, b []byte) error {
+		id, _ := account.DecodeID(rec.key)
+		acc, err := account.Decode(id, b)
+		if err != nil {
+			return err
+		}
+
+		accountIDs[id] = true
+		if acc.ParentID != (account.ID{}) {
+			parents[rec] = acc.ParentID
+		}
+		r.Accounts++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	children := make([]record, 0, len(parents))
+	for rec := range parents {
+		children = append(children, rec)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].key < children[j].key })
+	for _, rec := range children {
+		if parent := parents[rec]; !accountIDs[parent] {
+			r.addRecord(rec, KindMissingAccount, "parent account %s does not exist", parent.Hex())
+		}
+	}
+
+	err = walk(r, transactions, func(rec record, b []byte) error {
+		tx, err := transaction.Decode(b)
+		if err != nil {
+			return err
+		}
+		if hash := tx.Hash(); hex.EncodeToString(hash[:]) != rec.key {
+			r.addRecord(rec, KindNameMismatch, "the hash of the transaction is %x", hash)
+			return nil
+		}
+
+		for _, e := range tx.Entries() {
+			if !accountIDs[e.AccountID()] {
+				r.addRecord(rec, KindMissingAccount, "account %s does not exist", e.AccountID().Hex())
+			}
+		}
+		if debits, credits := transaction.Totals(tx); debits != credits {
+			r.addRecord(rec, KindUnbalanced, "debits %d != credits %d", debits, credits)
+		}
+		r.Transactions++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// walk checks the .dbinfo and the files of db, then the records of its
+// packs, calling check on each record.
+// An error returned by check is reported as an unreadable record.
+func walk(r *Report, db Database, check func(rec record, b []byte) error) error {
+	dbInfoPath := filepath.Join(db.Dir, dbInfoFileName)
+	b, err := os.ReadFile(dbInfoPath)
+	if errors.Is(err, os.ErrNotExist) {
+		r.add(KindBadDBInfo, dbInfoPath, "missing")
+	} else if err != nil {
+		r.add(KindBadDBInfo, dbInfoPath, "%s", err)
+	} else if string(b) != dbInfoContent {
+		r.add(KindBadDBInfo, dbInfoPath, "unsupported content %q, want %q", b, dbInfoContent)
+	}
+
+	dirEntries, err := os.ReadDir(db.Dir)
+	if err != nil {
+		return err
+	}
+
+	files := map[string]bool{}
+	for _, entry := range dirEntries {
+		name := entry.Name()
+		path := filepath.Join(db.Dir, name)
+
+		switch {
+		case name == QuarantineDirName:
+			continue
+		case strings.HasPrefix(name, ".") && !entry.IsDir():
+			// metadata of the store, or pack files
+			continue
+		case entry.IsDir():
+			r.add(KindOrphan, path, "unexpected directory")
+			continue
+		case !isRecordName(name):
+			r.add(KindOrphan, path, "not a record file")
+			continue
+		}
+
+		files[name] = true
+		if err := checkRecord(r, db, record{key: name, path: path}, check); err != nil {
+			return err
+		}
+	}
+
+	keys, err := db.Store.Keys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if files[key] || !isRecordName(key) {
+			continue
+		}
+		rec := record{key: key, path: store.PathOf(db.Store, key), packed: true}
+		if err := checkRecord(r, db, rec, check); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record is a record of a database.
+type record struct {
+	key  string
+	path string
+
+	// packed is whether the record is in a pack file.
+	packed bool
+}
+
+// checkRecord reads rec and calls check on it.
+func checkRecord(r *Report, db Database, rec record, check func(rec record, b []byte) error) error {
+	b, err := db.Store.Get(rec.key)
+	if errors.Is(err, store.ErrLocked) {
+		return err
+	}
+	if err == nil {
+		err = check(rec, b)
+	}
+	if err != nil {
+		r.addRecord(rec, KindUnreadable, "%s", err)
+	}
+	return nil
+}
+
+// isRecordName returns whether name is the name of a record file: the hex
+// of an account ID or of a transaction hash.
+func isRecordName(name string) bool {
+	if len(name) != 2*sha256.Size || strings.ToLower(name) != name {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
+
+func (r *Report) add(kind Kind, path, format string, args ...interface{}) {
+	r.Problems = append(r.Problems, Problem{Kind: kind, Path: path, Detail: fmt.Sprintf(format, args...)})
+}
+
+func (r *Report) addRecord(rec record, kind Kind, format string, args ...interface{}) {
+	r.add(kind, rec.path, format, args...)
+	r.Problems[len(r.Problems)-1].Packed = rec.packed
+}
+
+// Repair moves the file of every repairable problem of r into the
+// QuarantineDirName directory of its database.
+// It returns the paths of the quarantined files.
+func Repair(r *Report) ([]string, error) {
+	quarantined := []string{}
+	for _, p := range r.Problems {
+		if !p.Repairable() {
+			continue
+		}
+
+		dir := filepath.Join(filepath.Dir(p.Path), QuarantineDirName)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return quarantined, err
+		}
+		target := filepath.Join(dir, filepath.Base(p.Path))
+		if err := os.Rename(p.Path, target); err != nil {
+			return quarantined, err
+		}
+		quarantined = append(quarantined, target)
+	}
+	return quarantined, nil
+}
@@ -0,0 +1,246 @@
+package check
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/encoding"
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDB is a workdir with two accounts and a balanced transaction.
+type testDB struct {
+	accounts, transactions Database
+
+	cash, equity *account.Account
+	tx           transaction.Transaction
+}
+
+func newTestDB(t *testing.T) *testDB {
+	db := &testDB{
+		accounts:     Database{Dir: t.TempDir()},
+		transactions: Database{Dir: t.TempDir()},
+	}
+	db.accounts.Store = store.NewDirStore(db.accounts.Dir)
+	db.transactions.Store = store.NewDirStore(db.transactions.Dir)
+
+	accService, err := account.NewAccServiceWithStore(db.accounts.Store)
+	require.NoError(t, err)
+	txService, err := transaction.NewTxServiceWithStore(db.transactions.Store, accService)
+	require.NoError(t, err)
+
+	db.cash = account.NewAccount("Cash", account.TypeAsset)
+	db.cash.Alias = "cash"
+	db.cash.ID = db.cash.ComputeID()
+	require.NoError(t, accService.Register(db.cash))
+
+	db.equity = account.NewAccount("Equity", account.TypeEquity)
+	db.equity.Alias = "equity"
+	db.equity.ID = db.equity.ComputeID()
+	require.NoError(t, accService.Register(db.equity))
+
+	db.tx, err = txService.RecordFromMaps("initial", map[string]int64{"cash": 100}, map[string]int64{"equity": 100})
+	require.NoError(t, err)
+	return db
+}
+
+func (db *testDB) txPath() string {
+	hash := db.tx.Hash()
+	return filepath.Join(db.transactions.Dir, account.ID(hash).Hex())
+}
+
+func TestCheck(t *testing.T) {
+	t.Run("healthy", func(t *testing.T) {
+		db := newTestDB(t)
+
+		r, err := Check(db.accounts, db.transactions)
+		require.NoError(t, err)
+		assert.True(t, r.OK(), "%v", r.Problems)
+		assert.Equal(t, 2, r.Accounts)
+		assert.Equal(t, 1, r.Transactions)
+	})
+	t.Run("unreadable record", func(t *testing.T) {
+		db := newTestDB(t)
+		require.NoError(t, os.WriteFile(db.txPath(), []byte{0x01}, 0644))
+
+		r, err := Check(db.accounts, db.transactions)
+		require.NoError(t, err)
+		assertProblems(t, r, Problem{Kind: KindUnreadable, Path: db.txPath()})
+		assert.Equal(t, 0, r.Transactions)
+	})
+	t.Run("name mismatch", func(t *testing.T) {
+		db := newTestDB(t)
+		renamed := filepath.Join(db.transactions.Dir, db.cash.ID.Hex())
+		b, err := os.ReadFile(db.txPath())
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(renamed, b, 0644))
+
+		r, err := Check(db.accounts, db.transactions)
+		require.NoError(t, err)
+		assertProblems(t, r, Problem{Kind: KindNameMismatch, Path: renamed})
+	})
+	t.Run("updated account", func(t *testing.T) {
+		db := newTestDB(t)
+		accService, err := account.NewAccServiceWithStore(db.accounts.Store)
+		require.NoError(t, err)
+
+		db.cash.Name, db.cash.Alias, db.cash.Description = "Wallet", "wallet", "pocket money"
+		require.NoError(t, accService.Update(db.cash))
+
+		r, err := Check(db.accounts, db.transactions)
+		require.NoError(t, err)
+		assert.True(t, r.OK(), "%v", r.Problems)
+		assert.Equal(t, 2, r.Accounts)
+	})
+	t.Run("missing parent account", func(t *testing.T) {
+		db := newTestDB(t)
+		accService, err := account.NewAccServiceWithStore(db.accounts.Store)
+		require.NoError(t, err)
+
+		child := account.NewAccount("Child", account.TypeAsset)
+		child.ParentID = account.ID{0x42}
+		child.ID = child.ComputeID()
+		require.NoError(t, accService.Register(child))
+
+		r, err := Check(db.accounts, db.transactions)
+		require.NoError(t, err)
+		assertProblems(t, r, Problem{Kind: KindMissingAccount, Path: filepath.Join(db.accounts.Dir, child.ID.Hex())})
+	})
+	t.Run("unbalanced transaction", func(t *testing.T) {
+		db := newTestDB(t)
+
+		// recorded by a version which did not check the balance
+		b := new(bytes.Buffer)
+		rw, err := encoding.NewRecordWriter(b, encoding.FormatVersionCurrent)
+		require.NoError(t, err)
+		for _, v := range []interface{}{
+			int64(1617000000), uint16(2),
+			transaction.OpDebit, db.cash.ID, int64(100),
+			transaction.OpCredit, db.equity.ID, int64(90),
+			"unbalanced",
+		} {
+			require.NoError(t, rw.WriteEncoded(v))
+		}
+		require.NoError(t, rw.Close())
+		key := account.ID(sha256.Sum256(b.Bytes())).Hex()
+		require.NoError(t, db.transactions.Store.Put(key, b.Bytes()))
+
+		r, err := Check(db.accounts, db.transactions)
+		require.NoError(t, err)
+		assertProblems(t, r, Problem{Kind: KindUnbalanced, Path: filepath.Join(db.transactions.Dir, key)})
+		assert.Equal(t, 2, r.Transactions)
+	})
+	t.Run("orphan files", func(t *testing.T) {
+		db := newTestDB(t)
+		tmp := db.txPath() + ".tmp"
+		other := filepath.Join(db.accounts.Dir, "notes.txt")
+		dir := filepath.Join(db.transactions.Dir, "subdir")
+		require.NoError(t, os.WriteFile(tmp, []byte("partial"), 0644))
+		require.NoError(t, os.WriteFile(other, []byte("notes"), 0644))
+		require.NoError(t, os.Mkdir(dir, 0755))
+
+		r, err := Check(db.accounts, db.transactions)
+		require.NoError(t, err)
+		assertProblems(t, r,
+			Problem{Kind: KindOrphan, Path: other},
+			Problem{Kind: KindOrphan, Path: tmp},
+			Problem{Kind: KindOrphan, Path: dir},
+		)
+	})
+	t.Run("bad dbinfo", func(t *testing.T) {
+		db := newTestDB(t)
+		accDBInfo := filepath.Join(db.accounts.Dir, ".dbinfo")
+		txDBInfo := filepath.Join(db.transactions.Dir, ".dbinfo")
+		require.NoError(t, os.WriteFile(accDBInfo, []byte("quick:v9"), 0644))
+		require.NoError(t, os.Remove(txDBInfo))
+
+		r, err := Check(db.accounts, db.transactions)
+		require.NoError(t, err)
+		assertProblems(t, r,
+			Problem{Kind: KindBadDBInfo, Path: accDBInfo},
+			Problem{Kind: KindBadDBInfo, Path: txDBInfo},
+		)
+	})
+	t.Run("packed records", func(t *testing.T) {
+		db := newTestDB(t)
+		_, err := store.PackDir(db.transactions.Dir, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		db.transactions.Store = store.NewPackStore(db.transactions.Dir)
+		require.NoFileExists(t, db.txPath())
+
+		r, err := Check(db.accounts, db.transactions)
+		require.NoError(t, err)
+		assert.True(t, r.OK(), "%v", r.Problems)
+		assert.Equal(t, 1, r.Transactions)
+
+		// the equity account is gone
+		require.NoError(t, os.Remove(filepath.Join(db.accounts.Dir, db.equity.ID.Hex())))
+		r, err = Check(db.accounts, db.transactions)
+		require.NoError(t, err)
+		require.Len(t, r.Problems, 1)
+		assert.Equal(t, KindMissingAccount, r.Problems[0].Kind)
+		assert.True(t, r.Problems[0].Packed)
+		assert.Equal(t, store.PathOf(db.transactions.Store, filepath.Base(db.txPath())), r.Problems[0].Path)
+	})
+	t.Run("locked store", func(t *testing.T) {
+		db := newTestDB(t)
+		key := make([]byte, store.KeySize)
+		encrypted, err := store.NewEncryptedStore(db.transactions.Store, key)
+		require.NoError(t, err)
+		require.NoError(t, store.Copy(encrypted, db.transactions.Store))
+
+		db.transactions.Store, err = store.NewEncryptedStore(db.transactions.Store, nil)
+		require.NoError(t, err)
+
+		_, err = Check(db.accounts, db.transactions)
+		assert.ErrorIs(t, err, store.ErrLocked)
+	})
+}
+
+func TestRepair(t *testing.T) {
+	db := newTestDB(t)
+	orphan := filepath.Join(db.accounts.Dir, "notes.txt")
+	require.NoError(t, os.WriteFile(orphan, []byte("notes"), 0644))
+	require.NoError(t, os.WriteFile(db.txPath(), []byte{0x01}, 0644))
+	require.NoError(t, os.Remove(filepath.Join(db.transactions.Dir, ".dbinfo")))
+
+	r, err := Check(db.accounts, db.transactions)
+	require.NoError(t, err)
+	require.Len(t, r.Problems, 3)
+
+	quarantined, err := Repair(r)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(db.accounts.Dir, QuarantineDirName, "notes.txt"),
+		filepath.Join(db.transactions.Dir, QuarantineDirName, filepath.Base(db.txPath())),
+	}, quarantined)
+	for _, path := range quarantined {
+		assert.FileExists(t, path)
+	}
+	assert.NoFileExists(t, orphan)
+	assert.NoFileExists(t, db.txPath())
+
+	// only the .dbinfo problem, which is not repairable, is left
+	r, err = Check(db.accounts, db.transactions)
+	require.NoError(t, err)
+	assertProblems(t, r, Problem{Kind: KindBadDBInfo, Path: filepath.Join(db.transactions.Dir, ".dbinfo")})
+}
+
+// assertProblems asserts that r has the expected problems, comparing only
+// their kinds and paths.
+func assertProblems(t *testing.T, r *Report, expected ...Problem) {
+	t.Helper()
+	actual := make([]Problem, len(r.Problems))
+	for i, p := range r.Problems {
+		actual[i] = Problem{Kind: p.Kind, Path: p.Path}
+	}
+	assert.ElementsMatch(t, expected, actual)
+}
@@ -0,0 +1,182 @@
+// Package config handles the mitrack configuration file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the configuration file.
+const FileName = "config.yaml"
+
+// Config holds the defaults of the mitrack commands.
+type Config struct {
+	// Currency is the currency of the amounts, shown along with them.
+	Currency string `yaml:"currency,omitempty"`
+
+	// DateFormat is the Go time layout of the dates shown by the commands.
+	DateFormat string `yaml:"date-format,omitempty"`
+
+	// Output is the default output format of the commands listing things.
+	Output string `yaml:"output,omitempty"`
+
+	// Ledger is the name of the ledger used by default.
+	Ledger string `yaml:"ledger,omitempty"`
+
+	// CheckLedger, if not nil, returns an error if the named ledger does
+	// not exist: setting Ledger to it then fails.
+	CheckLedger func(name string) error `yaml:"-"`
+}
+
+// Default returns the configuration used when nothing is configured.
+func Default() *Config {
+	return &Config{
+		DateFormat: "2006-01-02T15:04:05Z07:00",
+		Output:     "table",
+		Ledger:     "default",
+	}
+}
+
+// OutputFormats are the supported values of Output.
+var OutputFormats = []string{"table", "json", "csv", "tsv", "yaml"}
+
+// ledgerNameRegexp matches the valid ledger names.
+var ledgerNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// IsLedgerName returns whether name is a valid ledger name: lowercase
+// letters, digits, - and _.
+func IsLedgerName(name string) bool {
+	return ledgerNameRegexp.MatchString(name)
+}
+
+// field is a configuration key.
+type field struct {
+	get      func(c *Config) *string
+	validate func(c *Config, value string) error
+}
+
+var fields = map[string]field{
+	"currency": {
+		get:      func(c *Config) *string { return &c.Currency },
+		validate: func(*Config, string) error { return nil },
+	},
+	"date-format": {
+		get: func(c *Config) *string { return &c.DateFormat },
+		validate: func(_ *Config, value string) error {
+			return validateDateFormat(value)
+		},
+	},
+	"ledger": {
+		get: func(c *Config) *string { return &c.Ledger },
+		validate: func(c *Config, value string) error {
+			if !IsLedgerName(value) {
+				return fmt.Errorf("invalid ledger name %q", value)
+			}
+			if c.CheckLedger != nil {
+				return c.CheckLedger(value)
+			}
+			return nil
+		},
+	},
+	"output": {
+		get: func(c *Config) *string { return &c.Output },
+		validate: func(_ *Config, value string) error {
+			for _, f := range OutputFormats {
+				if value == f {
+					return nil
+				}
+			}
+			return fmt.Errorf("unsupported output format %q, want one of %v", value, OutputFormats)
+		},
+	},
+}
+
+// validateDateFormat returns an error if value is not a Go time layout: a
+// layout formats a time into something else than itself, which it parses.
+func validateDateFormat(value string) error {
+	if value == "" {
+		return errors.New("empty date format")
+	}
+	formatted := time.Date(2021, time.March, 14, 15, 9, 26, 0, time.UTC).Format(value)
+	if formatted == value {
+		return fmt.Errorf("invalid date format %q: no date nor time element, see https://pkg.go.dev/time#pkg-constants", value)
+	}
+	if _, err := time.Parse(value, formatted); err != nil {
+		return fmt.Errorf("invalid date format %q: %w", value, err)
+	}
+	return nil
+}
+
+// Keys returns the configuration keys, sorted.
+func Keys() []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Get returns the value of key.
+func (c *Config) Get(key string) (string, error) {
+	f, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownKey, key)
+	}
+	return *f.get(c), nil
+}
+
+// Set sets the value of key, once validated.
+func (c *Config) Set(key, value string) error {
+	f, ok := fields[key]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, key)
+	}
+	if err := f.validate(c, value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	*f.get(c) = value
+	return nil
+}
+
+// Load reads the configuration file at path. The keys it does not set
+// keep their default value, and a missing file is the default
+// configuration. The existence of its ledger is not checked.
+func Load(path string) (*Config, error) {
+	c := Default()
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, key := range Keys() {
+		value, _ := c.Get(key)
+		if err := fields[key].validate(c, value); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, key, err)
+		}
+	}
+	return c, nil
+}
+
+// Save writes c into the configuration file at path.
+func (c *Config) Save(path string) error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// ErrUnknownKey is returned when getting or setting an unknown key.
+var ErrUnknownKey = errors.New("unknown configuration key")
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig(t *testing.T) {
+	t.Run("missing file", func(t *testing.T) {
+		c, err := Load(filepath.Join(t.TempDir(), FileName))
+
+		assert.NoError(t, err)
+		assert.Equal(t, Default(), c)
+	})
+	t.Run("set and save", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), FileName)
+		c := Default()
+		require.NoError(t, c.Set("currency", "MGA"))
+		require.NoError(t, c.Save(path))
+
+		loaded, err := Load(path)
+		require.NoError(t, err)
+		currency, err := loaded.Get("currency")
+		assert.NoError(t, err)
+		assert.Equal(t, "MGA", currency)
+		assert.Equal(t, Default().Output, loaded.Output)
+	})
+	t.Run("partial file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), FileName)
+		require.NoError(t, os.WriteFile(path, []byte("date-format: 2006-01-02\n"), 0644))
+
+		c, err := Load(path)
+		require.NoError(t, err)
+		assert.Equal(t, "2006-01-02", c.DateFormat)
+		assert.Equal(t, Default().Output, c.Output)
+	})
+	t.Run("invalid values", func(t *testing.T) {
+		c := Default()
+		assert.NoError(t, c.Set("output", "json"))
+		assert.Error(t, c.Set("output", "xml"))
+		assert.Error(t, c.Set("date-format", ""))
+		assert.Error(t, c.Set("date-format", "YYYY-MM-DD"), "no layout element")
+		assert.Error(t, c.Set("date-format", "01-02 002"), "does not parse back")
+		assert.NoError(t, c.Set("date-format", "02/01/2006"))
+		assert.NoError(t, c.Set("date-format", "Jan 2"))
+		assert.ErrorIs(t, c.Set("colour", "red"), ErrUnknownKey)
+		_, err := c.Get("colour")
+		assert.ErrorIs(t, err, ErrUnknownKey)
+
+		path := filepath.Join(t.TempDir(), FileName)
+		require.NoError(t, os.WriteFile(path, []byte("output: xml\n"), 0644))
+		_, err = Load(path)
+		assert.Error(t, err)
+	})
+	t.Run("ledger", func(t *testing.T) {
+		errMissing := errors.New("missing")
+		c := Default()
+		c.CheckLedger = func(name string) error {
+			if name != "business" {
+				return errMissing
+			}
+			return nil
+		}
+		for _, name := range []string{"", "..", "a/b", "Business"} {
+			assert.Error(t, c.Set("ledger", name), name)
+		}
+		assert.ErrorIs(t, c.Set("ledger", "nope"), errMissing)
+		assert.NoError(t, c.Set("ledger", "business"))
+		assert.Equal(t, "business", c.Ledger)
+
+		assert.True(t, IsLedgerName("my_ledger-2"))
+		assert.False(t, IsLedgerName("-a"))
+	})
+}
@@ -1,11 +1,16 @@
 package encoding
 
-import "io"
+import (
+	"errors"
+	"fmt"
+	"io"
+)
 
 // Encoding versions.
 const (
-	FormatVersionCurrent uint32 = FormatVersionV3
+	FormatVersionCurrent uint32 = FormatVersionV4
 
+	FormatVersionV4 uint32 = 4
 	FormatVersionV3 uint32 = 3
 	FormatVersionV2 uint32 = 2
 	FormatVersionV1 uint32 = 1
@@ -22,3 +27,92 @@ type Encoder interface {
 type Decoder interface {
 	ReadDecoded(r io.Reader, data interface{}) error
 }
+
+// Marshaler is implemented by types that can encode themselves,
+// typically by writing each of their fields with the given Encoder.
+type Marshaler interface {
+	MarshalEncoded(w io.Writer, e Encoder) error
+}
+
+// Unmarshaler is implemented by types that can decode themselves,
+// typically by reading each of their fields with the given Decoder.
+type Unmarshaler interface {
+	UnmarshalDecoded(r io.Reader, d Decoder) error
+}
+
+// Codec groups the Encoder and Decoder constructors of a format version.
+type Codec struct {
+	Version    uint32
+	NewEncoder func() Encoder
+	NewDecoder func() Decoder
+}
+
+// codecs is the version->codec registry, filled by RegisterCodec.
+var codecs = map[uint32]Codec{}
+
+// RegisterCodec registers c as the codec of c.Version.
+// It panics if a codec was already registered for that version.
+func RegisterCodec(c Codec) {
+	if _, ok := codecs[c.Version]; ok {
+		panic(fmt.Sprintf("encoding: codec already registered for version %d", c.Version))
+	}
+	codecs[c.Version] = c
+}
+
+// CodecFor returns the codec registered for the given format version.
+func CodecFor(version uint32) (Codec, error) {
+	c, ok := codecs[version]
+	if !ok {
+		return Codec{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+	return c, nil
+}
+
+var (
+	// ErrUnsupportedVersion is returned when no codec is registered for a format version.
+	ErrUnsupportedVersion = errors.New("unsupported format version")
+
+	// ErrChecksumMismatch is returned when a record does not match its checksum.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+
+	// ErrRecordTooLarge is returned when a record or a value is larger than MaxRecordSize.
+	ErrRecordTooLarge = errors.New("record too large")
+
+	// ErrOverflow is returned when a decoded integer does not fit in the target.
+	ErrOverflow = errors.New("integer overflow")
+
+	// ErrInvalidTarget is returned when decoding into something that is not a non-nil pointer.
+	ErrInvalidTarget = errors.New("invalid decode target")
+)
+
+// DecodeError is returned when a record can not be decoded.
+type DecodeError struct {
+	// Path is the path of the record file, if known.
+	Path string
+
+	// Offset is the offset in the record at which decoding failed.
+	Offset int64
+
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("decode error at offset %d: %s", e.Offset, e.Err)
+	}
+	return fmt.Sprintf("%s: decode error at offset %d: %s", e.Path, e.Offset, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// SetDecodePath sets the Path of the *DecodeError wrapped by err, if any,
+// and returns err.
+func SetDecodePath(err error, path string) error {
+	var decodeErr *DecodeError
+	if errors.As(err, &decodeErr) && decodeErr.Path == "" {
+		decodeErr.Path = path
+	}
+	return err
+}
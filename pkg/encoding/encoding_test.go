@@ -0,0 +1,52 @@
+package encoding
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type point struct {
+	label string
+	x, y  int64
+}
+
+func (p *point) MarshalEncoded(w io.Writer, e Encoder) error {
+	for _, v := range []interface{}{p.label, p.x, p.y} {
+		if err := e.WriteEncoded(w, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *point) UnmarshalDecoded(r io.Reader, d Decoder) error {
+	for _, v := range []interface{}{&p.label, &p.x, &p.y} {
+		if err := d.ReadDecoded(r, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestMarshaler(t *testing.T) {
+	for _, version := range []uint32{FormatVersionV3, FormatVersionV4} {
+		codec, err := CodecFor(version)
+		require.NoError(t, err)
+
+		p := &point{"origin", -3, 12}
+		b := new(bytes.Buffer)
+		err = codec.NewEncoder().WriteEncoded(b, p)
+
+		assert.NoError(t, err)
+
+		p2 := &point{}
+		err = codec.NewDecoder().ReadDecoded(b, p2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, p, p2)
+	}
+}
@@ -0,0 +1,147 @@
+package encoding
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// maxAllocPerInputByte and allocSlack bound the memory a decoder may
+// allocate for a given input: what a file says must never make us allocate
+// more than what the file actually contains (give or take some overhead).
+const (
+	maxAllocPerInputByte = 64
+	allocSlack           = 1 << 20
+)
+
+// checkAllocBound fails the test if f allocates more than allowed
+// for an input of inputLen bytes.
+func checkAllocBound(t *testing.T, inputLen int, f func()) {
+	t.Helper()
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+
+	allocated := after.TotalAlloc - before.TotalAlloc
+	if max := uint64(maxAllocPerInputByte*inputLen + allocSlack); allocated > max {
+		t.Errorf("decoding %d bytes allocated %d bytes (max %d)", inputLen, allocated, max)
+	}
+}
+
+// decodeValues decodes the values of an account and of a transaction record
+// from r, stopping at the first error.
+func decodeValues(r interface{ ReadDecoded(interface{}) error }) {
+	var (
+		t8   uint8
+		id   [32]byte
+		ts   int64
+		n    uint16
+		text string
+	)
+	for _, v := range []interface{}{&t8, &id, &ts, &text, &ts, &n, &t8, &id, &ts, &text} {
+		if r.ReadDecoded(v) != nil {
+			return
+		}
+	}
+}
+
+type decoderReader struct {
+	d Decoder
+	r *bytes.Reader
+}
+
+func (dr decoderReader) ReadDecoded(data interface{}) error {
+	return dr.d.ReadDecoded(dr.r, data)
+}
+
+func FuzzDecoderV3(f *testing.F) {
+	f.Add([]byte{0x10, 0x00, 'c', 'a', 's', 'h'})
+	f.Add([]byte{0xff, 0xff, 'x'})
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		checkAllocBound(t, len(b), func() {
+			decodeValues(decoderReader{NewDecoderV3(), bytes.NewReader(b)})
+		})
+	})
+}
+
+func FuzzDecoderV4(f *testing.F) {
+	f.Add([]byte{0x04, 'c', 'a', 's', 'h'})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff, 0x0f, 'x'})
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		checkAllocBound(t, len(b), func() {
+			decodeValues(decoderReader{NewDecoderV4(), bytes.NewReader(b)})
+		})
+	})
+}
+
+func FuzzRecordReader(f *testing.F) {
+	for _, version := range []uint32{FormatVersionV3, FormatVersionV4} {
+		b := new(bytes.Buffer)
+		writeTestRecord(f, b, version, []interface{}{uint8(1), [32]byte{1}, int64(1617000000), "cash-in-wallet"})
+		f.Add(b.Bytes())
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		checkAllocBound(t, len(b), func() {
+			rr, err := NewRecordReader(bytes.NewReader(b))
+			if err != nil {
+				return
+			}
+			decodeValues(rr)
+		})
+	})
+}
+
+func FuzzEncoderV4RoundTrip(f *testing.F) {
+	f.Add("naka vola sabotsy namehana", int64(-46000), uint64(900), uint8(2))
+
+	f.Fuzz(func(t *testing.T, s string, i int64, u uint64, b uint8) {
+		values := []interface{}{s, i, u, b, [4]byte{b, b, b, b}}
+
+		buf := new(bytes.Buffer)
+		writeTestRecord(t, buf, FormatVersionV4, values)
+
+		rr, err := NewRecordReader(buf)
+		require.NoError(t, err)
+		for _, want := range values {
+			got := newValueOf(want)
+			require.NoError(t, rr.ReadDecoded(got))
+			assert.Equal(t, want, derefValue(got))
+		}
+	})
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	seed, err := Marshal(recordV2{
+		Timestamp: 1617000000,
+		Lines:     []lineV2{{Account: [8]byte{1}, Amount: 900, Currency: "MGA"}},
+		Note:      "vary",
+		Tags:      []string{"food"},
+	})
+	require.NoError(f, err)
+	f.Add(seed)
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var r recordV2
+		checkAllocBound(t, len(b), func() {
+			err = Unmarshal(b, &r)
+		})
+		if err != nil {
+			return
+		}
+
+		// whatever was decoded must survive a round trip
+		b2, err := Marshal(r)
+		require.NoError(t, err)
+		var r2 recordV2
+		require.NoError(t, Unmarshal(b2, &r2))
+		assert.Equal(t, r, r2)
+	})
+}
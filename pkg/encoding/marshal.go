@@ -0,0 +1,353 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Marshal and Unmarshal implement a reflection-based codec, as an alternative
+// to hand-written field lists. Only exported struct fields tagged with an
+// ordinal are encoded:
+//
+//	type Account struct {
+//		Name  string `mitrack:"1"`
+//		Alias string `mitrack:"2"`
+//	}
+//
+// A struct is encoded as a sequence of fields, each of them being:
+//
+//	uvarint ordinal | uvarint length | value
+//
+// so that a field unknown to the decoder (added in a later version) is
+// skipped, and a field missing from the data (added after the data was
+// written) is left untouched. Ordinals must thus never be reused.
+//
+// Values are encoded as follows:
+//   - booleans: 1 byte
+//   - integers: varint (zig-zag for signed ones)
+//   - floats: their IEEE 754 bits, little-endian
+//   - strings and []byte: uvarint length, then the bytes
+//   - arrays: their elements, one after the other
+//   - slices: uvarint count, then their elements
+//   - structs: uvarint length, then their fields
+//   - pointers: the value they point to; nil pointer fields are omitted.
+
+const tagName = "mitrack"
+
+// Marshal returns the encoding of v, which must be a struct or a pointer
+// to a struct.
+func Marshal(v interface{}) ([]byte, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("encoding: cannot marshal %T, not a struct", v)
+	}
+
+	b := new(bytes.Buffer)
+	if err := marshalFields(b, rv); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Unmarshal decodes b into the struct pointed to by v.
+func Unmarshal(b []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrInvalidTarget
+	}
+	return unmarshalFields(b, rv.Elem())
+}
+
+// taggedField is a struct field with a mitrack ordinal.
+type taggedField struct {
+	ordinal uint64
+	index   int
+}
+
+// taggedFields returns the tagged fields of the struct type t,
+// sorted by ordinal.
+func taggedFields(t reflect.Type) ([]taggedField, error) {
+	fields := []taggedField{}
+	seen := map[uint64]string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup(tagName)
+		if !ok || tag == "-" || f.PkgPath != "" {
+			continue
+		}
+
+		ordinal, err := strconv.ParseUint(tag, 10, 64)
+		if err != nil || ordinal == 0 {
+			return nil, fmt.Errorf("encoding: invalid ordinal %q on %s.%s", tag, t, f.Name)
+		}
+		if other, ok := seen[ordinal]; ok {
+			return nil, fmt.Errorf("encoding: ordinal %d used by both %s.%s and %s.%s", ordinal, t, other, t, f.Name)
+		}
+		seen[ordinal] = f.Name
+
+		fields = append(fields, taggedField{ordinal, i})
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].ordinal < fields[j].ordinal })
+	return fields, nil
+}
+
+func marshalFields(b *bytes.Buffer, v reflect.Value) error {
+	fields, err := taggedFields(v.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		fv := v.Field(f.index)
+		if fv.Kind() == reflect.Ptr && fv.IsNil() {
+			continue
+		}
+
+		value := new(bytes.Buffer)
+		if err := marshalValue(value, fv); err != nil {
+			return fmt.Errorf("%s: %w", v.Type().Field(f.index).Name, err)
+		}
+
+		putUvarint(b, f.ordinal)
+		putUvarint(b, uint64(value.Len()))
+		value.WriteTo(b)
+	}
+	return nil
+}
+
+func marshalValue(b *bytes.Buffer, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			b.WriteByte(1)
+		} else {
+			b.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		putVarint(b, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		putUvarint(b, v.Uint())
+	case reflect.Float32:
+		binary.Write(b, binary.LittleEndian, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		binary.Write(b, binary.LittleEndian, math.Float64bits(v.Float()))
+	case reflect.String:
+		putUvarint(b, uint64(v.Len()))
+		b.WriteString(v.String())
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			for i := 0; i < v.Len(); i++ {
+				b.WriteByte(byte(v.Index(i).Uint()))
+			}
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := marshalValue(b, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		putUvarint(b, uint64(v.Len()))
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b.Write(v.Bytes())
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := marshalValue(b, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		fields := new(bytes.Buffer)
+		if err := marshalFields(fields, v); err != nil {
+			return err
+		}
+		putUvarint(b, uint64(fields.Len()))
+		fields.WriteTo(b)
+	case reflect.Ptr:
+		if v.IsNil() {
+			return fmt.Errorf("encoding: cannot marshal nil %s", v.Type())
+		}
+		return marshalValue(b, v.Elem())
+	default:
+		return fmt.Errorf("encoding: cannot marshal %s", v.Type())
+	}
+	return nil
+}
+
+func unmarshalFields(b []byte, v reflect.Value) error {
+	fields, err := taggedFields(v.Type())
+	if err != nil {
+		return err
+	}
+	byOrdinal := map[uint64]int{}
+	for _, f := range fields {
+		byOrdinal[f.ordinal] = f.index
+	}
+
+	r := bytes.NewReader(b)
+	for r.Len() > 0 {
+		ordinal, err := binary.ReadUvarint(r)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		value, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+
+		index, ok := byOrdinal[ordinal]
+		if !ok {
+			// field added in a later version
+			continue
+		}
+
+		vr := bytes.NewReader(value)
+		if err := unmarshalValue(vr, v.Field(index)); err != nil {
+			return fmt.Errorf("%s: %w", v.Type().Field(index).Name, err)
+		}
+	}
+	return nil
+}
+
+func unmarshalValue(r *bytes.Reader, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Bool:
+		x, err := r.ReadByte()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		v.SetBool(x != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := binary.ReadVarint(r)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if v.OverflowInt(x) {
+			return ErrOverflow
+		}
+		v.SetInt(x)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		x, err := binary.ReadUvarint(r)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if v.OverflowUint(x) {
+			return ErrOverflow
+		}
+		v.SetUint(x)
+	case reflect.Float32:
+		var bits uint32
+		if err := binary.Read(r, binary.LittleEndian, &bits); err != nil {
+			return unexpectedEOF(err)
+		}
+		v.SetFloat(float64(math.Float32frombits(bits)))
+	case reflect.Float64:
+		var bits uint64
+		if err := binary.Read(r, binary.LittleEndian, &bits); err != nil {
+			return unexpectedEOF(err)
+		}
+		v.SetFloat(math.Float64frombits(bits))
+	case reflect.String:
+		b, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+		v.SetString(string(b))
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			for i := 0; i < v.Len(); i++ {
+				x, err := r.ReadByte()
+				if err != nil {
+					return unexpectedEOF(err)
+				}
+				v.Index(i).SetUint(uint64(x))
+			}
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := unmarshalValue(r, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := readBytes(r)
+			if err != nil {
+				return err
+			}
+			if len(b) == 0 {
+				b = nil
+			}
+			v.SetBytes(b)
+			return nil
+		}
+		count, err := binary.ReadUvarint(r)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		if count == 0 {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		// every element takes at least one byte
+		if count > uint64(r.Len()) {
+			return io.ErrUnexpectedEOF
+		}
+		s := reflect.MakeSlice(v.Type(), int(count), int(count))
+		for i := 0; i < int(count); i++ {
+			if err := unmarshalValue(r, s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Struct:
+		b, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+		return unmarshalFields(b, v)
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return unmarshalValue(r, v.Elem())
+	default:
+		return fmt.Errorf("encoding: cannot unmarshal %s", v.Type())
+	}
+	return nil
+}
+
+// readBytes reads a uvarint length followed by that many bytes from r.
+func readBytes(r *bytes.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if length > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, length)
+	r.Read(b)
+	return b, nil
+}
+
+func putUvarint(b *bytes.Buffer, x uint64) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, x)
+	b.Write(buf[:n])
+}
+
+func putVarint(b *bytes.Buffer, x int64) {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutVarint(buf, x)
+	b.Write(buf[:n])
+}
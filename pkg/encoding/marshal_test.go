@@ -0,0 +1,126 @@
+package encoding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type lineV1 struct {
+	Account [8]byte `mitrack:"1"`
+	Amount  int64   `mitrack:"2"`
+}
+
+type recordV1 struct {
+	Timestamp int64    `mitrack:"1"`
+	Lines     []lineV1 `mitrack:"2"`
+	Note      string   `mitrack:"3"`
+	Internal  string
+}
+
+type lineV2 struct {
+	Account  [8]byte `mitrack:"1"`
+	Amount   int64   `mitrack:"2"`
+	Currency string  `mitrack:"3"`
+}
+
+type recordV2 struct {
+	Timestamp int64    `mitrack:"1"`
+	Lines     []lineV2 `mitrack:"2"`
+	Note      string   `mitrack:"3"`
+	Tags      []string `mitrack:"4"`
+	Reversal  *bool    `mitrack:"5"`
+}
+
+func TestMarshal(t *testing.T) {
+	reversal := true
+	r := recordV2{
+		Timestamp: 1617000000,
+		Lines: []lineV2{
+			{Account: [8]byte{1, 2, 3}, Amount: 900, Currency: "MGA"},
+			{Account: [8]byte{4, 5, 6}, Amount: -900, Currency: "MGA"},
+		},
+		Note:     "naka vola sabotsy namehana",
+		Tags:     []string{"cash", "weekend"},
+		Reversal: &reversal,
+	}
+
+	b, err := Marshal(&r)
+	require.NoError(t, err)
+
+	var got recordV2
+	err = Unmarshal(b, &got)
+
+	assert.NoError(t, err)
+	assert.Equal(t, r, got)
+}
+
+func TestMarshalSchemaEvolution(t *testing.T) {
+	t.Run("new fields are skipped by old readers", func(t *testing.T) {
+		r := recordV2{
+			Timestamp: 1617000000,
+			Lines:     []lineV2{{Account: [8]byte{1}, Amount: 900, Currency: "MGA"}},
+			Note:      "vary",
+			Tags:      []string{"food"},
+		}
+		b, err := Marshal(r)
+		require.NoError(t, err)
+
+		var got recordV1
+		err = Unmarshal(b, &got)
+
+		assert.NoError(t, err)
+		assert.Equal(t, recordV1{
+			Timestamp: 1617000000,
+			Lines:     []lineV1{{Account: [8]byte{1}, Amount: 900}},
+			Note:      "vary",
+		}, got)
+	})
+	t.Run("missing fields are left zero", func(t *testing.T) {
+		r := recordV1{
+			Timestamp: 1617000000,
+			Lines:     []lineV1{{Account: [8]byte{1}, Amount: 900}},
+			Note:      "vary",
+			Internal:  "not encoded",
+		}
+		b, err := Marshal(r)
+		require.NoError(t, err)
+
+		var got recordV2
+		err = Unmarshal(b, &got)
+
+		assert.NoError(t, err)
+		assert.Equal(t, recordV2{
+			Timestamp: 1617000000,
+			Lines:     []lineV2{{Account: [8]byte{1}, Amount: 900}},
+			Note:      "vary",
+		}, got)
+	})
+}
+
+func TestMarshalErrors(t *testing.T) {
+	t.Run("not a struct", func(t *testing.T) {
+		_, err := Marshal("string")
+		assert.Error(t, err)
+	})
+	t.Run("duplicate ordinal", func(t *testing.T) {
+		_, err := Marshal(struct {
+			A string `mitrack:"1"`
+			B string `mitrack:"1"`
+		}{})
+		assert.Error(t, err)
+	})
+	t.Run("invalid target", func(t *testing.T) {
+		err := Unmarshal([]byte{}, recordV1{})
+		assert.ErrorIs(t, err, ErrInvalidTarget)
+	})
+	t.Run("truncated", func(t *testing.T) {
+		b, err := Marshal(recordV1{Note: "truncated"})
+		require.NoError(t, err)
+
+		var got recordV1
+		err = Unmarshal(b[:len(b)-1], &got)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,205 @@
+package encoding
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// Records are the unit of storage: one account or one transaction.
+//
+// v3 records are the bare concatenation of their encoded values.
+// Records of later versions are framed as follows:
+//
+//	magic | uvarint version | uvarint payload length | payload | crc32c(payload)
+//
+// where the checksum is a little-endian uint32.
+// An account v3 record starts with its type (1 to 5), and a transaction one
+// with a little-endian timestamp whose high bytes are zero, so neither of
+// them can be mistaken for the magic.
+var magic = []byte("\x89MTRK\r\n\x1a")
+
+// MaxRecordSize is the maximum size of a record payload.
+const MaxRecordSize = 1 << 24
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// RecordWriter encodes values into a single record.
+// Nothing is written into the underlying writer until Close is called.
+type RecordWriter struct {
+	w       io.Writer
+	version uint32
+	encoder Encoder
+	payload bytes.Buffer
+}
+
+// NewRecordWriter returns a RecordWriter writing a record of the given
+// format version into w.
+func NewRecordWriter(w io.Writer, version uint32) (*RecordWriter, error) {
+	codec, err := CodecFor(version)
+	if err != nil {
+		return nil, err
+	}
+	return &RecordWriter{w: w, version: version, encoder: codec.NewEncoder()}, nil
+}
+
+// WriteEncoded encodes data as the next value of the record.
+func (rw *RecordWriter) WriteEncoded(data interface{}) error {
+	return rw.encoder.WriteEncoded(&rw.payload, data)
+}
+
+// Close writes the whole record into the underlying writer.
+func (rw *RecordWriter) Close() error {
+	if rw.version <= FormatVersionV3 {
+		_, err := rw.payload.WriteTo(rw.w)
+		return err
+	}
+
+	if rw.payload.Len() > MaxRecordSize {
+		return ErrRecordTooLarge
+	}
+
+	header := make([]byte, 0, len(magic)+2*binary.MaxVarintLen64)
+	header = append(header, magic...)
+	header = appendUvarint(header, uint64(rw.version))
+	header = appendUvarint(header, uint64(rw.payload.Len()))
+
+	checksum := make([]byte, 4)
+	binary.LittleEndian.PutUint32(checksum, crc32.Checksum(rw.payload.Bytes(), crcTable))
+
+	for _, b := range [][]byte{header, rw.payload.Bytes(), checksum} {
+		if _, err := rw.w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordReader decodes the values of a single record,
+// whatever its format version.
+//
+// Its errors are *DecodeError, giving the offset in the record at which
+// decoding failed.
+type RecordReader struct {
+	r       *countingReader
+	version uint32
+	decoder Decoder
+
+	// base is the offset in the record of the first byte of r.
+	base int64
+}
+
+// NewRecordReader reads the record header from r and returns a RecordReader
+// for its values. Framed records are entirely read and their checksum is
+// verified before any value is decoded.
+func NewRecordReader(r io.Reader) (*RecordReader, error) {
+	buffered := bufio.NewReader(r)
+	br := &countingReader{r: buffered}
+
+	head, err := buffered.Peek(len(magic))
+	if err != nil || !bytes.Equal(head, magic) {
+		// legacy record, without header
+		return &RecordReader{r: br, version: FormatVersionV3, decoder: NewDecoderV3()}, nil
+	}
+	if _, err := io.ReadFull(br, make([]byte, len(magic))); err != nil {
+		return nil, &DecodeError{Offset: br.n, Err: err}
+	}
+
+	version, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, &DecodeError{Offset: br.n, Err: unexpectedEOF(err)}
+	}
+	if version > math.MaxUint32 {
+		return nil, &DecodeError{Offset: br.n, Err: fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)}
+	}
+	codec, err := CodecFor(uint32(version))
+	if err != nil {
+		return nil, &DecodeError{Offset: br.n, Err: err}
+	}
+
+	length, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, &DecodeError{Offset: br.n, Err: unexpectedEOF(err)}
+	}
+	if length > MaxRecordSize {
+		return nil, &DecodeError{Offset: br.n, Err: ErrRecordTooLarge}
+	}
+	base := br.n
+
+	payload, err := readFull(br, length)
+	if err != nil {
+		return nil, &DecodeError{Offset: br.n, Err: unexpectedEOF(err)}
+	}
+	var checksum uint32
+	if err := binary.Read(br, binary.LittleEndian, &checksum); err != nil {
+		return nil, &DecodeError{Offset: br.n, Err: unexpectedEOF(err)}
+	}
+	if checksum != crc32.Checksum(payload, crcTable) {
+		return nil, &DecodeError{Offset: base, Err: ErrChecksumMismatch}
+	}
+
+	return &RecordReader{
+		r:       &countingReader{r: bytes.NewReader(payload)},
+		version: codec.Version,
+		decoder: codec.NewDecoder(),
+		base:    base,
+	}, nil
+}
+
+// Version returns the format version of the record.
+func (rr *RecordReader) Version() uint32 {
+	return rr.version
+}
+
+// Offset returns the offset in the record of the next value to decode.
+func (rr *RecordReader) Offset() int64 {
+	return rr.base + rr.r.n
+}
+
+// ReadDecoded decodes the next value of the record into data.
+func (rr *RecordReader) ReadDecoded(data interface{}) error {
+	if err := rr.decoder.ReadDecoded(rr.r, data); err != nil {
+		return &DecodeError{Offset: rr.Offset(), Err: err}
+	}
+	return nil
+}
+
+// countingReader counts the bytes read from r, which must be an
+// io.ByteReader as well.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+func (cr *countingReader) ReadByte() (byte, error) {
+	b, err := cr.r.(io.ByteReader).ReadByte()
+	if err == nil {
+		cr.n++
+	}
+	return b, err
+}
+
+func appendUvarint(b []byte, x uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, x)
+	return append(b, buf[:n]...)
+}
+
+// unexpectedEOF turns io.EOF into io.ErrUnexpectedEOF, as the record
+// header has already been read when it is called.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
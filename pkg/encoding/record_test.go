@@ -0,0 +1,108 @@
+package encoding
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecFor(t *testing.T) {
+	for _, version := range []uint32{FormatVersionV3, FormatVersionV4, FormatVersionCurrent} {
+		c, err := CodecFor(version)
+		assert.NoError(t, err)
+		assert.Equal(t, version, c.Version)
+	}
+
+	_, err := CodecFor(FormatVersionV1)
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+}
+
+func TestRecord(t *testing.T) {
+	values := []interface{}{uint8(4), [4]byte{9, 8, 7, 6}, int64(1617000000), "cash-in-wallet", ""}
+
+	for _, version := range []uint32{FormatVersionV3, FormatVersionV4} {
+		b := new(bytes.Buffer)
+		writeTestRecord(t, b, version, values)
+
+		rr, err := NewRecordReader(b)
+		require.NoError(t, err)
+		assert.Equal(t, version, rr.Version())
+
+		for _, want := range values {
+			got := newValueOf(want)
+			assert.NoError(t, rr.ReadDecoded(got))
+			assert.Equal(t, want, derefValue(got))
+		}
+	}
+}
+
+func TestRecordReaderErrors(t *testing.T) {
+	values := []interface{}{int64(1617000000), "naka vola"}
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		b := new(bytes.Buffer)
+		writeTestRecord(t, b, FormatVersionV4, values)
+		corrupted := b.Bytes()
+		corrupted[len(magic)+3] ^= 0xff
+
+		_, err := NewRecordReader(bytes.NewReader(corrupted))
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+	})
+	t.Run("truncated", func(t *testing.T) {
+		b := new(bytes.Buffer)
+		writeTestRecord(t, b, FormatVersionV4, values)
+
+		_, err := NewRecordReader(bytes.NewReader(b.Bytes()[:b.Len()-2]))
+		assert.Error(t, err)
+	})
+	t.Run("unsupported version", func(t *testing.T) {
+		record := append(append([]byte{}, magic...), 99, 0, 0, 0, 0, 0)
+
+		_, err := NewRecordReader(bytes.NewReader(record))
+		assert.ErrorIs(t, err, ErrUnsupportedVersion)
+	})
+	t.Run("decode error offset", func(t *testing.T) {
+		for _, version := range []uint32{FormatVersionV3, FormatVersionV4} {
+			b := new(bytes.Buffer)
+			writeTestRecord(t, b, version, values)
+
+			rr, err := NewRecordReader(b)
+			require.NoError(t, err)
+			var timestamp int64
+			require.NoError(t, rr.ReadDecoded(&timestamp))
+			var note string
+			require.NoError(t, rr.ReadDecoded(&note))
+			offset := rr.Offset()
+
+			err = rr.ReadDecoded(&note)
+			var decodeErr *DecodeError
+			require.ErrorAs(t, err, &decodeErr, "version %d", version)
+			assert.Equal(t, offset, decodeErr.Offset, "version %d", version)
+			assert.ErrorIs(t, err, io.EOF)
+		}
+	})
+}
+
+func writeTestRecord(t testing.TB, b *bytes.Buffer, version uint32, values []interface{}) {
+	t.Helper()
+
+	rw, err := NewRecordWriter(b, version)
+	require.NoError(t, err)
+	for _, v := range values {
+		require.NoError(t, rw.WriteEncoded(v))
+	}
+	require.NoError(t, rw.Close())
+}
+
+func derefValue(ptr interface{}) interface{} {
+	return reflect.ValueOf(ptr).Elem().Interface()
+}
+
+// newValueOf returns a pointer to a new zero value of the type of v.
+func newValueOf(v interface{}) interface{} {
+	return reflect.New(reflect.TypeOf(v)).Interface()
+}
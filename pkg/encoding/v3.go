@@ -2,7 +2,9 @@ package encoding
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
+	"math"
 )
 
 // EncoderV3 is the encoder used in v3.
@@ -24,14 +26,21 @@ func NewDecoderV3() Decoder {
 }
 
 // WriteEncoded writes encoded bytes into w.
+// Pointers are encoded as the value they point to.
 func (e *EncoderV3) WriteEncoded(w io.Writer, data interface{}) error {
+	switch data.(type) {
+	case Marshaler:
+		return data.(Marshaler).MarshalEncoded(w, e)
+	case *string:
+		data = *data.(*string)
+	}
+
 	switch data.(type) {
 	case string:
 		str := data.(string)
 		if err := e.writeString(w, str); err != nil {
 			return err
 		}
-	// TODO support marshal interface
 	default:
 		if err := e.writeNumeric(w, data); err != nil {
 			return err
@@ -42,8 +51,12 @@ func (e *EncoderV3) WriteEncoded(w io.Writer, data interface{}) error {
 
 // writeString writes an encoded string into w.
 // It uses the format: len value, where len is an uint16.
-// Thus, it only supports strings up to 2^16 long.
+// Thus, it only supports strings up to 2^16-1 bytes long, and returns an
+// ErrRecordTooLarge for longer ones.
 func (e *EncoderV3) writeString(w io.Writer, str string) error {
+	if len(str) > math.MaxUint16 {
+		return fmt.Errorf("%w: string of %d bytes, at most %d in v3", ErrRecordTooLarge, len(str), math.MaxUint16)
+	}
 	if err := e.writeNumeric(w, uint16(len(str))); err != nil {
 		return err
 	}
@@ -63,12 +76,13 @@ func (e *EncoderV3) writeNumeric(w io.Writer, num interface{}) error {
 // and writes the value into data.
 func (d *DecoderV3) ReadDecoded(r io.Reader, data interface{}) error {
 	switch data.(type) {
+	case Unmarshaler:
+		return data.(Unmarshaler).UnmarshalDecoded(r, d)
 	case *string:
 		ptr := data.(*string)
 		if err := d.readString(r, ptr); err != nil {
 			return err
 		}
-	// TODO support unmarshal interface
 	default:
 		if err := d.readNumeric(r, data); err != nil {
 			return err
@@ -86,7 +100,7 @@ func (d *DecoderV3) readString(r io.Reader, ptr *string) error {
 	}
 
 	b := make([]byte, length)
-	if _, err := r.Read(b); err != nil {
+	if _, err := io.ReadFull(r, b); err != nil {
 		return err
 	}
 
@@ -98,3 +112,11 @@ func (d *DecoderV3) readString(r io.Reader, ptr *string) error {
 func (d *DecoderV3) readNumeric(r io.Reader, num interface{}) error {
 	return binary.Read(r, binary.LittleEndian, num)
 }
+
+func init() {
+	RegisterCodec(Codec{
+		Version:    FormatVersionV3,
+		NewEncoder: NewEncoderV3,
+		NewDecoder: NewDecoderV3,
+	})
+}
@@ -2,7 +2,10 @@ package encoding
 
 import (
 	"bytes"
+	"math"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -24,6 +27,21 @@ func TestEncoderV3String(t *testing.T) {
 	assert.Equal(t, s, s2)
 }
 
+func TestDecoderV3ShortReads(t *testing.T) {
+	encoder := NewEncoderV3()
+	decoder := NewDecoderV3()
+
+	s := "naka vola sabotsy namehana"
+	b := new(bytes.Buffer)
+	assert.NoError(t, encoder.WriteEncoded(b, s))
+
+	var s2 string
+	err := decoder.ReadDecoded(iotest.OneByteReader(b), &s2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, s, s2)
+}
+
 func TestEncoderV3Numeric(t *testing.T) {
 	encoder := NewEncoderV3()
 	decoder := NewDecoderV3()
@@ -40,3 +58,15 @@ func TestEncoderV3Numeric(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, v, v2)
 }
+
+func TestEncoderV3StringTooLong(t *testing.T) {
+	encoder := NewEncoderV3()
+
+	b := new(bytes.Buffer)
+	assert.NoError(t, encoder.WriteEncoded(b, strings.Repeat("a", math.MaxUint16)))
+
+	b.Reset()
+	err := encoder.WriteEncoded(b, strings.Repeat("a", math.MaxUint16+1))
+	assert.ErrorIs(t, err, ErrRecordTooLarge)
+	assert.Zero(t, b.Len(), "nothing written")
+}
@@ -0,0 +1,198 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+)
+
+// EncoderV4 is the encoder used in v4.
+// Integers wider than a byte are written as varints (zig-zag for signed ones)
+// and strings are prefixed with their uvarint length, so there is no limit
+// on their size.
+type EncoderV4 struct {
+}
+
+// DecoderV4 is the decoder used in v4.
+type DecoderV4 struct {
+}
+
+// NewEncoderV4 returns a new v4 encoder.
+func NewEncoderV4() Encoder {
+	return &EncoderV4{}
+}
+
+// NewDecoderV4 returns a new v4 decoder.
+func NewDecoderV4() Decoder {
+	return &DecoderV4{}
+}
+
+func init() {
+	RegisterCodec(Codec{
+		Version:    FormatVersionV4,
+		NewEncoder: NewEncoderV4,
+		NewDecoder: NewDecoderV4,
+	})
+}
+
+// WriteEncoded writes encoded bytes into w.
+// Pointers are encoded as the value they point to.
+func (e *EncoderV4) WriteEncoded(w io.Writer, data interface{}) error {
+	if m, ok := data.(Marshaler); ok {
+		return m.MarshalEncoded(w, e)
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(data))
+	switch v.Kind() {
+	case reflect.String:
+		return e.writeBytes(w, []byte(v.String()))
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return e.writeBytes(w, v.Bytes())
+		}
+	case reflect.Int, reflect.Int16, reflect.Int32, reflect.Int64:
+		return e.writeVarint(w, v.Int())
+	case reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return e.writeUvarint(w, v.Uint())
+	}
+	// bytes, booleans, floats and fixed-size arrays (ex: account.ID)
+	return binary.Write(w, binary.LittleEndian, data)
+}
+
+// writeBytes writes b into w, prefixed with its uvarint length.
+func (e *EncoderV4) writeBytes(w io.Writer, b []byte) error {
+	if err := e.writeUvarint(w, uint64(len(b))); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func (e *EncoderV4) writeUvarint(w io.Writer, x uint64) error {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, x)
+	_, err := w.Write(buf[:n])
+	return err
+}
+
+func (e *EncoderV4) writeVarint(w io.Writer, x int64) error {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutVarint(buf, x)
+	_, err := w.Write(buf[:n])
+	return err
+}
+
+// ReadDecoded decodes the given data using v4 encoding,
+// and writes the value into data, which must be a non-nil pointer.
+func (d *DecoderV4) ReadDecoded(r io.Reader, data interface{}) error {
+	if u, ok := data.(Unmarshaler); ok {
+		return u.UnmarshalDecoded(r, d)
+	}
+
+	ptr := reflect.ValueOf(data)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return ErrInvalidTarget
+	}
+
+	v := ptr.Elem()
+	switch v.Kind() {
+	case reflect.String:
+		b, err := d.readBytes(r)
+		if err != nil {
+			return err
+		}
+		v.SetString(string(b))
+		return nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := d.readBytes(r)
+			if err != nil {
+				return err
+			}
+			v.SetBytes(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := binary.ReadVarint(asByteReader(r))
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(x) {
+			return ErrOverflow
+		}
+		v.SetInt(x)
+		return nil
+	case reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		x, err := binary.ReadUvarint(asByteReader(r))
+		if err != nil {
+			return err
+		}
+		if v.OverflowUint(x) {
+			return ErrOverflow
+		}
+		v.SetUint(x)
+		return nil
+	}
+	return binary.Read(r, binary.LittleEndian, data)
+}
+
+// readBytes reads a uvarint length followed by that many bytes.
+func (d *DecoderV4) readBytes(r io.Reader) ([]byte, error) {
+	length, err := binary.ReadUvarint(asByteReader(r))
+	if err != nil {
+		return nil, err
+	}
+	if length > MaxRecordSize {
+		return nil, ErrRecordTooLarge
+	}
+
+	return readFull(r, length)
+}
+
+// smallReadSize is the size up to which readFull allocates its buffer upfront.
+const smallReadSize = 64 << 10
+
+// readFull reads exactly n bytes from r. Above smallReadSize, the buffer
+// grows as the data is actually read, so that a corrupted length can not
+// make us allocate much more than what r really contains.
+func readFull(r io.Reader, n uint64) ([]byte, error) {
+	if n <= smallReadSize {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+
+	b := new(bytes.Buffer)
+	read, err := b.ReadFrom(io.LimitReader(r, int64(n)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(read) < n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return b.Bytes(), nil
+}
+
+// asByteReader returns r as an io.ByteReader, without buffering
+// (so no byte is consumed past the ones actually read).
+func asByteReader(r io.Reader) io.ByteReader {
+	if br, ok := r.(io.ByteReader); ok {
+		return br
+	}
+	return &byteReader{r}
+}
+
+type byteReader struct {
+	r io.Reader
+}
+
+func (br *byteReader) ReadByte() (byte, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(br.r, b[:]); err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
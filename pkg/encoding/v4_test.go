@@ -0,0 +1,102 @@
+package encoding
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncoderV4String(t *testing.T) {
+	encoder := NewEncoderV4()
+	decoder := NewDecoderV4()
+
+	t.Run("short", func(t *testing.T) {
+		s := "string-to-encode"
+		b := new(bytes.Buffer)
+		err := encoder.WriteEncoded(b, s)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1+len(s), b.Len(), "length prefix should be a 1-byte uvarint")
+
+		var s2 string
+		err = decoder.ReadDecoded(b, &s2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, s, s2)
+	})
+	t.Run("longer than 2^16", func(t *testing.T) {
+		s := strings.Repeat("naka vola sabotsy namehana ", 5000)
+		b := new(bytes.Buffer)
+		require.NoError(t, encoder.WriteEncoded(b, s))
+
+		var s2 string
+		err := decoder.ReadDecoded(b, &s2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, s, s2)
+	})
+	t.Run("truncated", func(t *testing.T) {
+		b := new(bytes.Buffer)
+		require.NoError(t, encoder.WriteEncoded(b, "string-to-encode"))
+		b.Truncate(b.Len() - 1)
+
+		var s2 string
+		err := decoder.ReadDecoded(b, &s2)
+
+		assert.Error(t, err)
+	})
+}
+
+func TestEncoderV4Numeric(t *testing.T) {
+	encoder := NewEncoderV4()
+	decoder := NewDecoderV4()
+
+	type op uint8
+	type id [4]byte
+
+	values := []struct {
+		name string
+		v    interface{}
+		ptr  interface{}
+	}{
+		{"int64", int64(-5123), new(int64)},
+		{"uint16", uint16(900), new(uint16)},
+		{"uint32", uint32(1 << 30), new(uint32)},
+		{"named uint8", op(2), new(op)},
+		{"array", id{1, 2, 3, 4}, new(id)},
+	}
+
+	for _, tt := range values {
+		t.Run(tt.name, func(t *testing.T) {
+			b := new(bytes.Buffer)
+			err := encoder.WriteEncoded(b, tt.v)
+
+			assert.NoError(t, err)
+
+			err = decoder.ReadDecoded(b, tt.ptr)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.v, derefValue(tt.ptr))
+			assert.Zero(t, b.Len(), "all bytes should be consumed")
+		})
+	}
+
+	t.Run("overflow", func(t *testing.T) {
+		b := new(bytes.Buffer)
+		require.NoError(t, encoder.WriteEncoded(b, uint32(70000)))
+
+		var v uint16
+		err := decoder.ReadDecoded(b, &v)
+
+		assert.ErrorIs(t, err, ErrOverflow)
+	})
+	t.Run("invalid target", func(t *testing.T) {
+		var v int64
+		err := decoder.ReadDecoded(new(bytes.Buffer), v)
+
+		assert.ErrorIs(t, err, ErrInvalidTarget)
+	})
+}
@@ -0,0 +1,116 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Value is the value of an expression: an int64, a string or a bool.
+type Value = interface{}
+
+// Query is a parsed query:
+//
+//	SELECT target, ... [FROM table] [WHERE expr] [GROUP BY expr, ...]
+//	[ORDER BY expr [ASC|DESC], ...] [LIMIT n]
+type Query struct {
+	Targets []Target
+	// From is the name of the table, entries if empty.
+	From string
+	// FromPos is the byte offset of From in the query.
+	FromPos int
+	Where   Expr
+	GroupBy []Expr
+	OrderBy []Order
+	// Limit bounds the number of result rows, if not negative.
+	Limit int
+}
+
+// Target is a selected expression. A nil Expr selects every column (*).
+type Target struct {
+	Expr Expr
+	// As names the column of the result, the text of Expr if empty.
+	As string
+}
+
+// Order is an expression of ORDER BY.
+type Order struct {
+	Expr Expr
+	Desc bool
+}
+
+// Expr is an expression.
+type Expr interface {
+	String() string
+}
+
+// Column is a column of the table, or a bare word: a string which is
+// not the name of a column, on the right of a comparison, once planned.
+type Column struct {
+	Name string
+	Pos  int
+
+	// index is the index of the column in the rows of the table, or -1
+	// for bare words. It is set by the planner.
+	index int
+}
+
+func (c *Column) String() string {
+	return c.Name
+}
+
+// Literal is a constant.
+type Literal struct {
+	Value Value
+}
+
+func (l *Literal) String() string {
+	if s, ok := l.Value.(string); ok {
+		return "'" + s + "'"
+	}
+	return fmt.Sprint(l.Value)
+}
+
+// Unary is an operation on one operand: NOT or -.
+type Unary struct {
+	Op  string
+	X   Expr
+	Pos int
+}
+
+func (u *Unary) String() string {
+	if u.Op == "NOT" {
+		return "NOT " + u.X.String()
+	}
+	return u.Op + u.X.String()
+}
+
+// Binary is an operation on two operands: AND, OR, a comparison, a regular
+// expression match (~) or an arithmetic operation.
+type Binary struct {
+	Op   string
+	X, Y Expr
+	Pos  int
+}
+
+func (b *Binary) String() string {
+	return b.X.String() + " " + b.Op + " " + b.Y.String()
+}
+
+// Call is a function call. The argument of count(*) is nil.
+type Call struct {
+	Func string
+	Args []Expr
+	Pos  int
+}
+
+func (c *Call) String() string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		if arg == nil {
+			args[i] = "*"
+		} else {
+			args[i] = arg.String()
+		}
+	}
+	return c.Func + "(" + strings.Join(args, ", ") + ")"
+}
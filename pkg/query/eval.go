@@ -0,0 +1,441 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+)
+
+// Result is the result of a query.
+type Result struct {
+	Columns []string
+	Rows    [][]Value
+}
+
+// Run parses, plans and executes the query q.
+func Run(ctx context.Context, q string, accService account.AccService, txService transaction.TxService) (*Result, error) {
+	query, err := Parse(q)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := NewPlan(query)
+	if err != nil {
+		return nil, err
+	}
+	return plan.Execute(ctx, accService, txService)
+}
+
+// Execute executes the plan over the accounts and transactions of the
+// services. If some records could not be read, the result of the others is
+// returned along with a *store.ListError.
+func (p *Plan) Execute(ctx context.Context, accService account.AccService, txService transaction.TxService) (*Result, error) {
+	rows, err := loadRows(ctx, p.table, p.filter, accService, txService)
+	var listErr *store.ListError
+	if err != nil && !errors.As(err, &listErr) {
+		return nil, err
+	}
+
+	result, evalErr := p.evaluate(rows)
+	if evalErr != nil {
+		return nil, evalErr
+	}
+	return result, err
+}
+
+// ErrOverflow is returned when the result of an integer operation or
+// aggregate does not fit in an int64.
+var ErrOverflow = errors.New("integer overflow")
+
+// evaluator evaluates expressions, caching the compiled regular
+// expressions.
+type evaluator struct {
+	regexps map[string]*regexp.Regexp
+}
+
+// group is a group of rows, with the values of its output row.
+type group struct {
+	rows   [][]Value
+	values []Value
+	keys   []Value
+	sorts  []Value
+}
+
+// evaluate executes the plan over the rows of its table.
+func (p *Plan) evaluate(rows [][]Value) (*Result, error) {
+	ev := &evaluator{regexps: map[string]*regexp.Regexp{}}
+
+	selected := rows[:0:0]
+	for _, row := range rows {
+		if p.where != nil {
+			v, err := ev.eval(p.where, row)
+			if err != nil {
+				return nil, err
+			}
+			if !v.(bool) {
+				continue
+			}
+		}
+		selected = append(selected, row)
+	}
+
+	// without grouping, each row is its own group
+	groups := []*group{}
+	if p.grouped {
+		byKey := map[string]*group{}
+		for _, row := range selected {
+			keys := make([]Value, len(p.groupBy))
+			for i, e := range p.groupBy {
+				v, err := ev.eval(e, row)
+				if err != nil {
+					return nil, err
+				}
+				keys[i] = v
+			}
+			key := fmt.Sprintf("%#v", keys)
+			g, ok := byKey[key]
+			if !ok {
+				g = &group{keys: keys}
+				byKey[key] = g
+				groups = append(groups, g)
+			}
+			g.rows = append(g.rows, row)
+		}
+		// a query aggregating every row has one group, even without rows
+		if len(p.groupBy) == 0 && len(groups) == 0 {
+			groups = append(groups, &group{})
+		}
+	} else {
+		for _, row := range selected {
+			groups = append(groups, &group{rows: [][]Value{row}})
+		}
+	}
+
+	for _, g := range groups {
+		for _, e := range p.targets {
+			v, err := ev.evalGroup(e, g.rows)
+			if err != nil {
+				return nil, err
+			}
+			g.values = append(g.values, v)
+		}
+		for _, order := range p.orderBy {
+			v, err := ev.evalGroup(order.Expr, g.rows)
+			if err != nil {
+				return nil, err
+			}
+			g.sorts = append(g.sorts, v)
+		}
+	}
+
+	// ordered by the ORDER BY expressions, else by the groups, else as
+	// read: by date
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		for k, order := range p.orderBy {
+			if c := compare(a.sorts[k], b.sorts[k]); c != 0 {
+				return (c < 0) != order.Desc
+			}
+		}
+		if len(p.orderBy) > 0 {
+			return false
+		}
+		for k := range a.keys {
+			if c := compare(a.keys[k], b.keys[k]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	result := &Result{Columns: p.names, Rows: [][]Value{}}
+	for _, g := range groups {
+		if p.limit >= 0 && len(result.Rows) == p.limit {
+			break
+		}
+		result.Rows = append(result.Rows, g.values)
+	}
+	return result, nil
+}
+
+// evalGroup evaluates e over the rows of a group: aggregates over all of
+// them, other expressions over the first one, as they have the same value
+// for all of them. The expressions of a nil value (the min or max of no
+// rows) are nil.
+func (ev *evaluator) evalGroup(e Expr, rows [][]Value) (Value, error) {
+	switch e := e.(type) {
+	case *Unary:
+		x, err := ev.evalGroup(e.X, rows)
+		if err != nil || x == nil {
+			return nil, err
+		}
+		return unary(e, x)
+	case *Binary:
+		x, err := ev.evalGroup(e.X, rows)
+		if err != nil || x == nil {
+			return nil, err
+		}
+		y, err := ev.evalGroup(e.Y, rows)
+		if err != nil || y == nil {
+			return nil, err
+		}
+		return ev.binary(e, x, y)
+	case *Call:
+		if aggregates[e.Func] {
+			return ev.aggregate(e, rows)
+		}
+		x, err := ev.evalGroup(e.Args[0], rows)
+		if err != nil || x == nil {
+			return nil, err
+		}
+		return function(e, x)
+	}
+
+	if len(rows) == 0 {
+		return zero(e), nil
+	}
+	return ev.eval(e, rows[0])
+}
+
+// zero returns the value of a column or literal in an empty group.
+func zero(e Expr) Value {
+	if l, ok := e.(*Literal); ok {
+		return l.Value
+	}
+	if c, ok := e.(*Column); ok && c.index < 0 {
+		return c.Name
+	}
+	return nil
+}
+
+// eval evaluates e, without aggregates, over row.
+func (ev *evaluator) eval(e Expr, row []Value) (Value, error) {
+	switch e := e.(type) {
+	case *Column:
+		if e.index < 0 {
+			return e.Name, nil
+		}
+		return row[e.index], nil
+	case *Literal:
+		return e.Value, nil
+	case *Unary:
+		x, err := ev.eval(e.X, row)
+		if err != nil {
+			return nil, err
+		}
+		return unary(e, x)
+	case *Binary:
+		// AND and OR do not evaluate Y when X decides
+		x, err := ev.eval(e.X, row)
+		if err != nil {
+			return nil, err
+		}
+		if (e.Op == "AND" && !x.(bool)) || (e.Op == "OR" && x.(bool)) {
+			return x, nil
+		}
+		y, err := ev.eval(e.Y, row)
+		if err != nil {
+			return nil, err
+		}
+		return ev.binary(e, x, y)
+	case *Call:
+		x, err := ev.eval(e.Args[0], row)
+		if err != nil {
+			return nil, err
+		}
+		return function(e, x)
+	}
+	return nil, fmt.Errorf("query: unexpected expression %T", e)
+}
+
+func unary(e *Unary, x Value) (Value, error) {
+	if e.Op == "NOT" {
+		return !x.(bool), nil
+	}
+	if x.(int64) == math.MinInt64 {
+		return nil, overflowError(e)
+	}
+	return -x.(int64), nil
+}
+
+func (ev *evaluator) binary(e *Binary, x, y Value) (Value, error) {
+	switch e.Op {
+	case "AND":
+		return x.(bool) && y.(bool), nil
+	case "OR":
+		return x.(bool) || y.(bool), nil
+	case "+", "-", "*":
+		z, ok := arithmetic(e.Op, x.(int64), y.(int64))
+		if !ok {
+			return nil, overflowError(e)
+		}
+		return z, nil
+	case "/":
+		if y.(int64) == 0 {
+			return nil, fmt.Errorf("query: %s: division by zero", e)
+		}
+		if x.(int64) == math.MinInt64 && y.(int64) == -1 {
+			return nil, overflowError(e)
+		}
+		return x.(int64) / y.(int64), nil
+	case "~":
+		re, ok := ev.regexps[y.(string)]
+		if !ok {
+			var err error
+			if re, err = regexp.Compile(y.(string)); err != nil {
+				return nil, fmt.Errorf("query: %s: %w", e, err)
+			}
+			ev.regexps[y.(string)] = re
+		}
+		return re.MatchString(x.(string)), nil
+	case "=":
+		return compare(x, y) == 0, nil
+	case "!=":
+		return compare(x, y) != 0, nil
+	case "<":
+		return compare(x, y) < 0, nil
+	case "<=":
+		return compare(x, y) <= 0, nil
+	case ">":
+		return compare(x, y) > 0, nil
+	case ">=":
+		return compare(x, y) >= 0, nil
+	}
+	return nil, fmt.Errorf("query: unexpected operator %s", e.Op)
+}
+
+// arithmetic returns x op y, also returning false if it overflowed.
+func arithmetic(op string, x, y int64) (int64, bool) {
+	switch op {
+	case "+":
+		if y > 0 && x > math.MaxInt64-y || y < 0 && x < math.MinInt64-y {
+			return 0, false
+		}
+		return x + y, true
+	case "-":
+		if y < 0 && x > math.MaxInt64+y || y > 0 && x < math.MinInt64+y {
+			return 0, false
+		}
+		return x - y, true
+	case "*":
+		if x == 0 || y == 0 {
+			return 0, true
+		}
+		z := x * y
+		if z/y != x || x == math.MinInt64 && y == -1 {
+			return 0, false
+		}
+		return z, true
+	}
+	return 0, false
+}
+
+// overflowError is the error of the expression e overflowing an int64.
+func overflowError(e Expr) error {
+	return fmt.Errorf("query: %s: %w", e, ErrOverflow)
+}
+
+func function(e *Call, x Value) (Value, error) {
+	switch e.Func {
+	case "abs":
+		if n := x.(int64); n == math.MinInt64 {
+			return nil, overflowError(e)
+		} else if n < 0 {
+			return -n, nil
+		}
+		return x, nil
+	case "lower":
+		return strings.ToLower(x.(string)), nil
+	case "upper":
+		return strings.ToUpper(x.(string)), nil
+	}
+	return nil, nil
+}
+
+// aggregate computes the aggregate call e over rows. The min and max of
+// no rows are nil, the other aggregates are 0.
+func (ev *evaluator) aggregate(e *Call, rows [][]Value) (Value, error) {
+	if e.Args[0] == nil {
+		return int64(len(rows)), nil
+	}
+
+	var result Value
+	var sum int64
+	for _, row := range rows {
+		v, err := ev.eval(e.Args[0], row)
+		if err != nil {
+			return nil, err
+		}
+		switch e.Func {
+		case "sum", "avg":
+			var ok bool
+			if sum, ok = arithmetic("+", sum, v.(int64)); !ok {
+				return nil, overflowError(e)
+			}
+		case "min":
+			if result == nil || compare(v, result) < 0 {
+				result = v
+			}
+		case "max":
+			if result == nil || compare(v, result) > 0 {
+				result = v
+			}
+		}
+	}
+
+	switch e.Func {
+	case "count":
+		return int64(len(rows)), nil
+	case "sum":
+		return sum, nil
+	case "avg":
+		if len(rows) == 0 {
+			return int64(0), nil
+		}
+		return sum / int64(len(rows)), nil
+	}
+	return result, nil
+}
+
+// compare returns -1, 0 or 1 as x is less than, equal to or greater
+// than y, values of the same type. nil is less than any value.
+func compare(x, y Value) int {
+	switch {
+	case x == nil && y == nil:
+		return 0
+	case x == nil:
+		return -1
+	case y == nil:
+		return 1
+	}
+
+	switch x := x.(type) {
+	case int64:
+		y := y.(int64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	case string:
+		return strings.Compare(x, y.(string))
+	case bool:
+		y := y.(bool)
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,142 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind is the kind of a token of a query.
+type tokenKind uint8
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenKeyword
+	tokenInt
+	tokenString
+	tokenSymbol
+)
+
+// token is a lexeme of a query. Keywords are in upper case, and strings
+// without their quotes.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenString:
+		return fmt.Sprintf("'%s'", t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+var keywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "ORDER": true,
+	"BY": true, "ASC": true, "DESC": true, "LIMIT": true, "AS": true,
+	"AND": true, "OR": true, "NOT": true, "TRUE": true, "FALSE": true,
+}
+
+// symbols are the operators and punctuation, longest first.
+var symbols = []string{"!=", "<>", "<=", ">=", ",", "(", ")", "*", "+", "-", "/", "=", "<", ">", "~"}
+
+// lex splits q into tokens, ending with a tokenEOF.
+func lex(q string) ([]token, error) {
+	tokens := []token{}
+	for i := 0; i < len(q); {
+		c := rune(q[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(q[i+1:], q[i])
+			if end < 0 {
+				return nil, &SyntaxError{Pos: i, Msg: "unterminated string"}
+			}
+			tokens = append(tokens, token{tokenString, q[i+1 : i+1+end], i})
+			i += end + 2
+		case isDigit(c):
+			j := i
+			for j < len(q) && isDigit(rune(q[j])) {
+				j++
+			}
+			// dates (YYYY-MM-DD) and months (YYYY-MM) are strings
+			if n := dateLength(q[i:]); n > 0 {
+				tokens = append(tokens, token{tokenString, q[i : i+n], i})
+				i += n
+				continue
+			}
+			tokens = append(tokens, token{tokenInt, q[i:j], i})
+			i = j
+		case c == '_' || unicode.IsLetter(c) || c >= 0x80:
+			j := i
+			for j < len(q) && (q[j] == '_' || q[j] == '-' || isDigit(rune(q[j])) || unicode.IsLetter(rune(q[j])) || q[j] >= 0x80) {
+				j++
+			}
+			word := q[i:j]
+			if keywords[strings.ToUpper(word)] {
+				tokens = append(tokens, token{tokenKeyword, strings.ToUpper(word), i})
+			} else {
+				tokens = append(tokens, token{tokenIdent, word, i})
+			}
+			i = j
+		default:
+			matched := false
+			for _, s := range symbols {
+				if strings.HasPrefix(q[i:], s) {
+					tokens = append(tokens, token{tokenSymbol, s, i})
+					i += len(s)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &SyntaxError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+			}
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(q)}), nil
+}
+
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
+
+// dateLength returns the length of the date (YYYY-MM-DD) or month
+// (YYYY-MM) starting s, 0 if none.
+func dateLength(s string) int {
+	digits := func(s string, n int) bool {
+		if len(s) < n {
+			return false
+		}
+		for _, c := range s[:n] {
+			if !isDigit(c) {
+				return false
+			}
+		}
+		return len(s) == n || !isDigit(rune(s[n]))
+	}
+	if !digits(s, 4) || len(s) < 5 || s[4] != '-' || !digits(s[5:], 2) {
+		return 0
+	}
+	if len(s) > 7 && s[7] == '-' && digits(s[8:], 2) {
+		return 10
+	}
+	return 7
+}
+
+// SyntaxError is returned when parsing an invalid query.
+type SyntaxError struct {
+	// Pos is the byte offset of the error in the query.
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("query: syntax error at offset %d: %s", e.Pos, e.Msg)
+}
@@ -0,0 +1,298 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse parses the query q. The keywords are case insensitive.
+func Parse(q string) (*Query, error) {
+	tokens, err := lex(q)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	return p.query()
+}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the keyword or symbol text.
+func (p *parser) accept(text string) bool {
+	t := p.peek()
+	if (t.kind == tokenKeyword || t.kind == tokenSymbol) && t.text == text {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return errorAt(p.peek(), "expected %s, got %s", text, p.peek())
+	}
+	return nil
+}
+
+func errorAt(t token, format string, args ...interface{}) error {
+	return &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) query() (*Query, error) {
+	q := &Query{Limit: -1}
+	if err := p.expect("SELECT"); err != nil {
+		return nil, err
+	}
+	for {
+		target, err := p.target()
+		if err != nil {
+			return nil, err
+		}
+		q.Targets = append(q.Targets, target)
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	if p.accept("FROM") {
+		t := p.next()
+		if t.kind != tokenIdent {
+			return nil, errorAt(t, "expected a table, got %s", t)
+		}
+		q.From = strings.ToLower(t.text)
+		q.FromPos = t.pos
+	}
+
+	if p.accept("WHERE") {
+		where, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		q.Where = where
+	}
+
+	if p.accept("GROUP") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			q.GroupBy = append(q.GroupBy, e)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if p.accept("ORDER") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			order := Order{Expr: e}
+			if p.accept("DESC") {
+				order.Desc = true
+			} else {
+				p.accept("ASC")
+			}
+			q.OrderBy = append(q.OrderBy, order)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if p.accept("LIMIT") {
+		t := p.next()
+		if t.kind != tokenInt {
+			return nil, errorAt(t, "expected a number, got %s", t)
+		}
+		n, err := strconv.Atoi(t.text)
+		if err != nil {
+			return nil, &SyntaxError{Pos: t.pos, Msg: err.Error()}
+		}
+		q.Limit = n
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, errorAt(t, "unexpected %s", t)
+	}
+	return q, nil
+}
+
+func (p *parser) target() (Target, error) {
+	if p.accept("*") {
+		return Target{}, nil
+	}
+	e, err := p.expr()
+	if err != nil {
+		return Target{}, err
+	}
+	target := Target{Expr: e}
+	if p.accept("AS") {
+		t := p.next()
+		if t.kind != tokenIdent && t.kind != tokenString {
+			return Target{}, errorAt(t, "expected a column name, got %s", t)
+		}
+		target.As = t.text
+	}
+	return target, nil
+}
+
+// The operators, by increasing precedence:
+//
+//	OR
+//	AND
+//	NOT
+//	= != <> < <= > >= ~
+//	+ -
+//	* /
+//	- (unary)
+
+func (p *parser) expr() (Expr, error) {
+	return p.binary(0)
+}
+
+var precedences = [][]string{
+	{"OR"},
+	{"AND"},
+	nil, // NOT
+	{"=", "!=", "<>", "<", "<=", ">", ">=", "~"},
+	{"+", "-"},
+	{"*", "/"},
+}
+
+func (p *parser) binary(level int) (Expr, error) {
+	if level == len(precedences) {
+		return p.unary()
+	}
+	if precedences[level] == nil {
+		if t := p.peek(); p.accept("NOT") {
+			x, err := p.binary(level)
+			if err != nil {
+				return nil, err
+			}
+			return &Unary{Op: "NOT", X: x, Pos: t.pos}, nil
+		}
+		return p.binary(level + 1)
+	}
+
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		op := ""
+		for _, candidate := range precedences[level] {
+			if p.accept(candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return x, nil
+		}
+		if op == "<>" {
+			op = "!="
+		}
+		y, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = &Binary{Op: op, X: x, Y: y, Pos: t.pos}
+	}
+}
+
+func (p *parser) unary() (Expr, error) {
+	if t := p.peek(); p.accept("-") {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{Op: "-", X: x, Pos: t.pos}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenInt:
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, &SyntaxError{Pos: t.pos, Msg: err.Error()}
+		}
+		return &Literal{Value: n}, nil
+	case tokenString:
+		return &Literal{Value: t.text}, nil
+	case tokenKeyword:
+		switch t.text {
+		case "TRUE":
+			return &Literal{Value: true}, nil
+		case "FALSE":
+			return &Literal{Value: false}, nil
+		}
+	case tokenSymbol:
+		if t.text == "(" {
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+	case tokenIdent:
+		if !p.accept("(") {
+			return &Column{Name: t.text, Pos: t.pos, index: -1}, nil
+		}
+		call := &Call{Func: strings.ToLower(t.text), Pos: t.pos}
+		if p.accept(")") {
+			return call, nil
+		}
+		for {
+			if p.accept("*") {
+				call.Args = append(call.Args, nil)
+			} else {
+				arg, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+				call.Args = append(call.Args, arg)
+			}
+			if !p.accept(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return call, nil
+	}
+	return nil, errorAt(t, "unexpected %s", t)
+}
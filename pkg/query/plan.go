@@ -0,0 +1,437 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+)
+
+// Plan is a checked query, ready to be executed.
+type Plan struct {
+	table   string
+	columns []ColumnInfo
+
+	// names are the names of the result columns, targets their
+	// expressions.
+	names   []string
+	targets []Expr
+
+	where Expr
+
+	// grouped is true if the rows are grouped, by groupBy: the GROUP BY
+	// expressions and the targets which are not aggregated.
+	grouped bool
+	groupBy []Expr
+
+	orderBy []Order
+	limit   int
+
+	// filter selects the transactions which may match where, so that
+	// the others are not read.
+	filter transaction.Filter
+}
+
+// Columns returns the names of the columns of the result.
+func (p *Plan) Columns() []string {
+	return p.names
+}
+
+// aggregates are the aggregate functions: they are computed over the rows
+// of a group.
+var aggregates = map[string]bool{"count": true, "sum": true, "min": true, "max": true, "avg": true}
+
+// PlanError is returned when planning an invalid query: unknown table or
+// function, mistyped expression, misplaced aggregate.
+type PlanError struct {
+	// Pos is the byte offset of the erroneous expression in the query.
+	Pos int
+	Msg string
+}
+
+func (e *PlanError) Error() string {
+	return fmt.Sprintf("query: at offset %d: %s", e.Pos, e.Msg)
+}
+
+func planErrorf(pos int, format string, args ...interface{}) error {
+	return &PlanError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// NewPlan checks q and returns its plan.
+//
+// The names which are not columns are errors, except on the right of a
+// comparison where they are bare words: strings, so that `type = expense`
+// means `type = 'expense'`.
+func NewPlan(q *Query) (*Plan, error) {
+	p := &Plan{table: q.From, limit: q.Limit}
+	if p.table == "" {
+		p.table = TableEntries
+	}
+	columns, ok := Tables[p.table]
+	if !ok {
+		return nil, planErrorf(q.FromPos, "unknown table %q", p.table)
+	}
+	p.columns = columns
+
+	for _, target := range q.Targets {
+		if target.Expr == nil {
+			for _, c := range columns {
+				p.names = append(p.names, c.Name)
+				p.targets = append(p.targets, &Column{Name: c.Name, index: -1})
+			}
+			continue
+		}
+		name := target.As
+		if name == "" {
+			name = target.Expr.String()
+		}
+		p.names = append(p.names, name)
+		p.targets = append(p.targets, target.Expr)
+	}
+
+	for _, e := range p.targets {
+		if _, err := p.check(e, true, false); err != nil {
+			return nil, err
+		}
+		if hasAggregate(e) {
+			p.grouped = true
+		}
+	}
+
+	if q.Where != nil {
+		kind, err := p.check(q.Where, false, false)
+		if err != nil {
+			return nil, err
+		}
+		if kind != kindBool {
+			return nil, planErrorf(pos(q.Where), "WHERE %s is a %s, not a condition", q.Where, kind)
+		}
+		p.where = q.Where
+		p.filter = dateFilter(q.Where)
+	}
+
+	for _, e := range q.GroupBy {
+		e = p.resolveName(e)
+		if _, err := p.check(e, false, false); err != nil {
+			return nil, err
+		}
+		p.grouped = true
+		p.groupBy = append(p.groupBy, e)
+	}
+	if p.grouped {
+		for _, e := range p.targets {
+			if !hasAggregate(e) && !containsExpr(p.groupBy, e) {
+				p.groupBy = append(p.groupBy, e)
+			}
+		}
+	}
+
+	for _, order := range q.OrderBy {
+		order.Expr = p.resolveName(order.Expr)
+		if _, err := p.check(order.Expr, p.grouped, false); err != nil {
+			return nil, err
+		}
+		p.orderBy = append(p.orderBy, order)
+	}
+	return p, nil
+}
+
+// checkOperand checks the right operand of e like check does, except that
+// a name which is not a column is a bare word if e is a comparison.
+func (p *Plan) checkOperand(e *Binary, aggregate, inAggregate bool) (valueKind, error) {
+	if c, ok := e.Y.(*Column); ok && comparisons[e.Op] {
+		if kind, ok := p.resolve(c); ok {
+			return kind, nil
+		}
+		return kindString, nil
+	}
+	return p.check(e.Y, aggregate, inAggregate)
+}
+
+// resolve sets the index of c and returns its type, or returns false if c
+// is not a column of the table.
+func (p *Plan) resolve(c *Column) (valueKind, bool) {
+	c.index = -1
+	for i, col := range p.columns {
+		if col.Name == c.Name {
+			c.index = i
+			return col.kind, true
+		}
+	}
+	return 0, false
+}
+
+// comparisons are the operators whose right operand may be a bare word.
+var comparisons = map[string]bool{
+	"=": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true, "~": true,
+}
+
+// resolveName returns the target named by e, if e is the name of a result
+// column, or e.
+func (p *Plan) resolveName(e Expr) Expr {
+	c, ok := e.(*Column)
+	if !ok {
+		return e
+	}
+	for i, name := range p.names {
+		if name == c.Name {
+			return p.targets[i]
+		}
+	}
+	return e
+}
+
+// check resolves the columns of e and returns its type. Aggregates are
+// allowed if aggregate is true, and not inside another one.
+func (p *Plan) check(e Expr, aggregate, inAggregate bool) (valueKind, error) {
+	switch e := e.(type) {
+	case *Column:
+		if kind, ok := p.resolve(e); ok {
+			return kind, nil
+		}
+		return 0, planErrorf(e.Pos, "unknown column %q", e.Name)
+
+	case *Literal:
+		switch e.Value.(type) {
+		case int64:
+			return kindInt, nil
+		case bool:
+			return kindBool, nil
+		}
+		return kindString, nil
+
+	case *Unary:
+		kind, err := p.check(e.X, aggregate, inAggregate)
+		if err != nil {
+			return 0, err
+		}
+		want := kindInt
+		if e.Op == "NOT" {
+			want = kindBool
+		}
+		if kind != want {
+			return 0, planErrorf(e.Pos, "%s of a %s", e.Op, kind)
+		}
+		return kind, nil
+
+	case *Binary:
+		x, err := p.check(e.X, aggregate, inAggregate)
+		if err != nil {
+			return 0, err
+		}
+		y, err := p.checkOperand(e, aggregate, inAggregate)
+		if err != nil {
+			return 0, err
+		}
+		switch e.Op {
+		case "AND", "OR":
+			if x != kindBool || y != kindBool {
+				return 0, planErrorf(e.Pos, "%s of a %s and a %s", e.Op, x, y)
+			}
+			return kindBool, nil
+		case "+", "-", "*", "/":
+			if x != kindInt || y != kindInt {
+				return 0, planErrorf(e.Pos, "%s of a %s and a %s", e.Op, x, y)
+			}
+			return kindInt, nil
+		case "~":
+			if x != kindString || y != kindString {
+				return 0, planErrorf(e.Pos, "~ of a %s and a %s", x, y)
+			}
+			if l, ok := e.Y.(*Literal); ok {
+				if _, err := regexp.Compile(l.Value.(string)); err != nil {
+					return 0, planErrorf(e.Pos, "invalid regular expression: %s", err)
+				}
+			}
+			return kindBool, nil
+		default:
+			if x != y {
+				return 0, planErrorf(e.Pos, "%s of a %s and a %s", e.Op, x, y)
+			}
+			return kindBool, nil
+		}
+
+	case *Call:
+		if aggregates[e.Func] {
+			if !aggregate {
+				return 0, planErrorf(e.Pos, "aggregate %s not allowed here", e)
+			}
+			if inAggregate {
+				return 0, planErrorf(e.Pos, "aggregate %s inside another one", e)
+			}
+		}
+		if len(e.Args) != 1 {
+			return 0, planErrorf(e.Pos, "%s takes 1 argument", e.Func)
+		}
+		if e.Args[0] == nil {
+			if e.Func != "count" {
+				return 0, planErrorf(e.Pos, "%s(*)", e.Func)
+			}
+			return kindInt, nil
+		}
+		kind, err := p.check(e.Args[0], aggregate, inAggregate || aggregates[e.Func])
+		if err != nil {
+			return 0, err
+		}
+		switch e.Func {
+		case "count":
+			return kindInt, nil
+		case "sum", "avg", "abs":
+			if kind != kindInt {
+				return 0, planErrorf(e.Pos, "%s of a %s", e.Func, kind)
+			}
+			return kindInt, nil
+		case "min", "max":
+			if kind == kindBool {
+				return 0, planErrorf(e.Pos, "%s of a %s", e.Func, kind)
+			}
+			return kind, nil
+		case "lower", "upper":
+			if kind != kindString {
+				return 0, planErrorf(e.Pos, "%s of a %s", e.Func, kind)
+			}
+			return kindString, nil
+		}
+		return 0, planErrorf(e.Pos, "unknown function %s", e.Func)
+	}
+	return 0, fmt.Errorf("query: unexpected expression %T", e)
+}
+
+// pos returns the offset of e in the query, or 0.
+func pos(e Expr) int {
+	switch e := e.(type) {
+	case *Column:
+		return e.Pos
+	case *Unary:
+		return e.Pos
+	case *Binary:
+		return e.Pos
+	case *Call:
+		return e.Pos
+	}
+	return 0
+}
+
+func hasAggregate(e Expr) bool {
+	switch e := e.(type) {
+	case *Unary:
+		return hasAggregate(e.X)
+	case *Binary:
+		return hasAggregate(e.X) || hasAggregate(e.Y)
+	case *Call:
+		if aggregates[e.Func] {
+			return true
+		}
+		for _, arg := range e.Args {
+			if arg != nil && hasAggregate(arg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsExpr(exprs []Expr, e Expr) bool {
+	for _, x := range exprs {
+		if x.String() == e.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// dateFilter returns the filter selecting the transactions of the date
+// range required by where: the conditions on date and year joined by AND.
+func dateFilter(where Expr) transaction.Filter {
+	f := transaction.Filter{}
+	from := func(t time.Time) {
+		if f.From.IsZero() || t.After(f.From) {
+			f.From = t
+		}
+	}
+	to := func(t time.Time) {
+		if f.To.IsZero() || t.Before(f.To) {
+			f.To = t
+		}
+	}
+
+	var walk func(e Expr)
+	walk = func(e Expr) {
+		b, ok := e.(*Binary)
+		if !ok {
+			return
+		}
+		if b.Op == "AND" {
+			walk(b.X)
+			walk(b.Y)
+			return
+		}
+		c, lit, op := b.X, b.Y, b.Op
+		if _, ok := c.(*Column); !ok {
+			c, lit, op = b.Y, b.X, flip(op)
+		}
+		col, ok := c.(*Column)
+		if !ok || col.index < 0 {
+			return
+		}
+		l, ok := lit.(*Literal)
+		if !ok {
+			return
+		}
+
+		var start, end time.Time
+		switch col.Name {
+		case "date":
+			s, ok := l.Value.(string)
+			if !ok {
+				return
+			}
+			t, err := time.Parse(dateLayout, s)
+			if err != nil {
+				return
+			}
+			start, end = t, t.AddDate(0, 0, 1)
+		case "year":
+			y, ok := l.Value.(int64)
+			if !ok || y < 1 || y > 9999 {
+				return
+			}
+			start = time.Date(int(y), time.January, 1, 0, 0, 0, 0, time.UTC)
+			end = start.AddDate(1, 0, 0)
+		default:
+			return
+		}
+
+		switch op {
+		case "=":
+			from(start)
+			to(end)
+		case ">=":
+			from(start)
+		case ">":
+			from(end)
+		case "<":
+			to(start)
+		case "<=":
+			to(end)
+		}
+	}
+	walk(where)
+	return f
+}
+
+// flip returns the comparison operator op once its operands are swapped.
+func flip(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
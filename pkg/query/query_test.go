@@ -0,0 +1,327 @@
+package query
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	q, err := Parse(`select account, sum(amount) AS total
+		WHERE type = expense AND date >= 2026-01-01 AND NOT note ~ '^tip'
+		group by month order by total desc, account limit 10`)
+	require.NoError(t, err)
+
+	require.Len(t, q.Targets, 2)
+	assert.Equal(t, "account", q.Targets[0].Expr.String())
+	assert.Equal(t, "sum(amount)", q.Targets[1].Expr.String())
+	assert.Equal(t, "total", q.Targets[1].As)
+	assert.Equal(t, "", q.From)
+	assert.Equal(t, "type = expense AND date >= '2026-01-01' AND NOT note ~ '^tip'", q.Where.String())
+	require.Len(t, q.GroupBy, 1)
+	assert.Equal(t, "month", q.GroupBy[0].String())
+	require.Len(t, q.OrderBy, 2)
+	assert.Equal(t, Order{Expr: q.OrderBy[0].Expr, Desc: true}, q.OrderBy[0])
+	assert.False(t, q.OrderBy[1].Desc)
+	assert.Equal(t, 10, q.Limit)
+
+	t.Run("precedence", func(t *testing.T) {
+		q, err := Parse("SELECT * FROM accounts WHERE a = 1 OR b = 2 AND NOT c = -3 + 4 * 5")
+		require.NoError(t, err)
+		assert.Equal(t, TableAccounts, q.From)
+		assert.Equal(t, Target{}, q.Targets[0])
+		assert.Equal(t, -1, q.Limit)
+
+		or := q.Where.(*Binary)
+		assert.Equal(t, "OR", or.Op)
+		and := or.Y.(*Binary)
+		assert.Equal(t, "AND", and.Op)
+		not := and.Y.(*Unary)
+		eq := not.X.(*Binary)
+		assert.Equal(t, "=", eq.Op)
+		plus := eq.Y.(*Binary)
+		assert.Equal(t, "+", plus.Op)
+		assert.Equal(t, "-3", plus.X.String())
+		assert.Equal(t, "4 * 5", plus.Y.String())
+	})
+	t.Run("bare words", func(t *testing.T) {
+		q, err := Parse("SELECT note WHERE alias = cash-in-wallet AND month = 2026-03")
+		require.NoError(t, err)
+		assert.Equal(t, "alias = cash-in-wallet AND month = '2026-03'", q.Where.String())
+	})
+	t.Run("syntax errors", func(t *testing.T) {
+		for q, pos := range map[string]int{
+			"":                          0,
+			"SELEC account":             0,
+			"SELECT":                    6,
+			"SELECT account,":           15,
+			"SELECT sum(amount":         17,
+			"SELECT account WHERE":      20,
+			"SELECT a LIMIT x":          15,
+			"SELECT a GROUP month":      15,
+			"SELECT 'unterminated":      7,
+			"SELECT a WHERE a ! b":      17,
+			"SELECT a FROM 'accounts'":  14,
+			"SELECT a b":                9,
+			"SELECT a AS 1":             12,
+			"SELECT count(*) LIMIT 1 2": 24,
+		} {
+			_, err := Parse(q)
+			var syntaxErr *SyntaxError
+			if assert.ErrorAs(t, err, &syntaxErr, q) {
+				assert.Equal(t, pos, syntaxErr.Pos, q)
+			}
+		}
+	})
+}
+
+func TestNewPlan(t *testing.T) {
+	plan := func(q string) (*Plan, error) {
+		query, err := Parse(q)
+		require.NoError(t, err, q)
+		return NewPlan(query)
+	}
+
+	t.Run("columns", func(t *testing.T) {
+		p, err := plan("SELECT account, sum(amount) AS total, count(*) GROUP BY month")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"account", "total", "count(*)"}, p.Columns())
+		assert.True(t, p.grouped)
+		assert.Equal(t, "[month account]", exprsString(p.groupBy), "selected columns are grouped")
+
+		p, err = plan("SELECT * FROM accounts")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"id", "account", "alias", "description", "type", "parent_id", "created"}, p.Columns())
+		assert.False(t, p.grouped)
+	})
+	t.Run("errors", func(t *testing.T) {
+		for _, q := range []string{
+			"SELECT a FROM budgets",
+			"SELECT account WHERE amount",
+			"SELECT account WHERE sum(amount) > 0",
+			"SELECT account GROUP BY sum(amount)",
+			"SELECT sum(sum(amount))",
+			"SELECT sum(note)",
+			"SELECT amount + note",
+			"SELECT amount WHERE amount = 'x'",
+			"SELECT amount WHERE note ~ '('",
+			"SELECT amount WHERE NOT amount",
+			"SELECT -note",
+			"SELECT round(amount)",
+			"SELECT sum(amount, debit)",
+			"SELECT sum(*)",
+			"SELECT amount ORDER BY count(*)",
+			"SELECT foo",
+			"SELECT note WHERE expense = type",
+			"SELECT note WHERE type = expense + 1",
+		} {
+			_, err := plan(q)
+			var planErr *PlanError
+			assert.ErrorAs(t, err, &planErr, q)
+		}
+	})
+	t.Run("error offsets", func(t *testing.T) {
+		for q, expected := range map[string]int{
+			"SELECT a FROM budgets":                   14,
+			"SELECT note, foo":                        13,
+			"SELECT note WHERE tpye = expense":        18,
+			"SELECT note GROUP BY month, foo":         28,
+			"SELECT note WHERE note = x AND y = note": 31,
+		} {
+			_, err := plan(q)
+			var planErr *PlanError
+			if assert.ErrorAs(t, err, &planErr, q) {
+				assert.Equal(t, expected, planErr.Pos, q)
+			}
+		}
+	})
+	t.Run("date filter", func(t *testing.T) {
+		day := func(y int, m time.Month, d int) time.Time {
+			return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		}
+		for q, expected := range map[string]transaction.Filter{
+			"SELECT note WHERE date >= 2026-01-01":                        {From: day(2026, 1, 1)},
+			"SELECT note WHERE date > 2026-01-01 AND date < 2026-02-01":   {From: day(2026, 1, 2), To: day(2026, 2, 1)},
+			"SELECT note WHERE 2026-01-31 >= date":                        {To: day(2026, 2, 1)},
+			"SELECT note WHERE date = 2026-01-15":                         {From: day(2026, 1, 15), To: day(2026, 1, 16)},
+			"SELECT note WHERE year = 2025 AND date >= 2025-06-01":        {From: day(2025, 6, 1), To: day(2026, 1, 1)},
+			"SELECT note WHERE date >= 2026-01-01 OR note = x":            {},
+			"SELECT note WHERE NOT date >= 2026-01-01":                    {},
+			"SELECT note WHERE date >= 'soon'":                            {},
+			"SELECT note WHERE (type = expense) AND (date <= 2026-01-01)": {To: day(2026, 1, 2)},
+		} {
+			p, err := plan(q)
+			require.NoError(t, err, q)
+			assert.Equal(t, expected, p.filter, q)
+		}
+	})
+}
+
+func exprsString(exprs []Expr) string {
+	s := "["
+	for i, e := range exprs {
+		if i > 0 {
+			s += " "
+		}
+		s += e.String()
+	}
+	return s + "]"
+}
+
+// entry returns a row of the entries table.
+func entry(date, note, acc, accType string, amount int64) []Value {
+	op, debit, credit := "debit", amount, int64(0)
+	if amount < 0 {
+		op, debit, credit = "credit", 0, -amount
+	}
+	year := int64(2000 + int(date[2]-'0')*10 + int(date[3]-'0'))
+	return []Value{date, date[:7], year, "h-" + date, note, op, acc, acc, "id-" + acc, accType, "", amount, debit, credit}
+}
+
+func TestEvaluate(t *testing.T) {
+	rows := [][]Value{
+		entry("2026-01-01", "Salary", "Bank", "asset", 1000),
+		entry("2026-01-01", "Salary", "Salary", "revenue", -1000),
+		entry("2026-01-05", "Lunch", "Food", "expense", 15),
+		entry("2026-01-05", "Lunch", "Bank", "asset", -15),
+		entry("2026-02-03", "Rent", "Rent", "expense", 400),
+		entry("2026-02-03", "Rent", "Bank", "asset", -400),
+		entry("2026-02-10", "Dinner", "Food", "expense", 30),
+		entry("2026-02-10", "Dinner", "Bank", "asset", -30),
+	}
+
+	tests := []struct {
+		query    string
+		expected [][]Value
+	}{
+		{
+			"SELECT note, amount WHERE account = Food",
+			[][]Value{{"Lunch", int64(15)}, {"Dinner", int64(30)}},
+		},
+		{
+			"SELECT account, sum(amount) WHERE type = expense AND date >= 2026-01-01 GROUP BY month",
+			[][]Value{{"Food", int64(15)}, {"Food", int64(30)}, {"Rent", int64(400)}},
+		},
+		{
+			"SELECT month, sum(amount) AS total WHERE type = expense GROUP BY month ORDER BY total DESC",
+			[][]Value{{"2026-02", int64(430)}, {"2026-01", int64(15)}},
+		},
+		{
+			"SELECT account, sum(amount) AS balance GROUP BY account",
+			[][]Value{{"Bank", int64(555)}, {"Food", int64(45)}, {"Rent", int64(400)}, {"Salary", int64(-1000)}},
+		},
+		{
+			"SELECT count(*), sum(debit), sum(credit), min(amount), max(note), avg(debit)",
+			[][]Value{{int64(8), int64(1445), int64(1445), int64(-1000), "Salary", int64(180)}},
+		},
+		{
+			"SELECT count(*), max(amount), max(amount) + 1 WHERE note = nothing",
+			[][]Value{{int64(0), nil, nil}},
+		},
+		{
+			"SELECT note WHERE operation = 'debit' AND (note ~ '^L' OR amount > 100) ORDER BY amount DESC",
+			[][]Value{{"Salary"}, {"Rent"}, {"Lunch"}},
+		},
+		{
+			"SELECT lower(note), abs(amount) / 10 * 2 WHERE account = Bank LIMIT 2",
+			[][]Value{{"salary", int64(200)}, {"lunch", int64(2)}},
+		},
+		{
+			"SELECT date WHERE year = 2026 AND month != 2026-01 AND account = 'Rent'",
+			[][]Value{{"2026-02-03"}},
+		},
+		{
+			"SELECT type, count(*) WHERE amount < 0 GROUP BY type ORDER BY count(*), type LIMIT 1",
+			[][]Value{{"revenue", int64(1)}},
+		},
+		{
+			"SELECT note WHERE NOT -amount >= 15",
+			[][]Value{{"Salary"}, {"Lunch"}, {"Rent"}, {"Dinner"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := Parse(tt.query)
+			require.NoError(t, err)
+			p, err := NewPlan(query)
+			require.NoError(t, err)
+
+			result, err := p.evaluate(rows)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Rows)
+		})
+	}
+
+	t.Run("division by zero", func(t *testing.T) {
+		query, err := Parse("SELECT amount / (debit - debit)")
+		require.NoError(t, err)
+		p, err := NewPlan(query)
+		require.NoError(t, err)
+
+		_, err = p.evaluate(rows)
+		assert.Error(t, err)
+	})
+
+	t.Run("integer overflow", func(t *testing.T) {
+		for _, q := range []string{
+			"SELECT 9223372036854775807 + 1",
+			"SELECT -9223372036854775807 - 2",
+			"SELECT 4611686018427387904 * 2",
+			"SELECT abs(-9223372036854775807 - 1)",
+			"SELECT -(-9223372036854775807 - 1)",
+			"SELECT (-9223372036854775807 - 1) / -1",
+			"SELECT sum(9223372036854775807)",
+			"SELECT avg(9223372036854775807) WHERE amount > 0",
+		} {
+			query, err := Parse(q)
+			require.NoError(t, err, q)
+			p, err := NewPlan(query)
+			require.NoError(t, err, q)
+
+			_, err = p.evaluate(rows)
+			assert.ErrorIs(t, err, ErrOverflow, q)
+		}
+	})
+}
+
+func TestRun(t *testing.T) {
+	accService, err := account.NewAccService(t.TempDir())
+	require.NoError(t, err)
+	txService, err := transaction.NewTxService(t.TempDir(), accService)
+	require.NoError(t, err)
+
+	cash := account.NewAccount("Cash", account.TypeAsset)
+	food := account.NewAccount("Food", account.TypeExpense)
+	food.ParentID = cash.ID
+	for _, acc := range []*account.Account{cash, food} {
+		require.NoError(t, accService.Register(acc))
+	}
+	_, err = txService.RecordFromMaps("Lunch", map[string]int64{food.Alias: 15}, map[string]int64{cash.Alias: 15})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	result, err := Run(ctx, "SELECT account, type, amount, debit, credit ORDER BY amount", accService, txService)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"account", "type", "amount", "debit", "credit"}, result.Columns)
+	assert.Equal(t, [][]Value{
+		{"Cash", "asset", int64(-15), int64(0), int64(15)},
+		{"Food", "expense", int64(15), int64(15), int64(0)},
+	}, result.Rows)
+
+	today := time.Now().UTC().Format(dateLayout)
+	result, err = Run(ctx, "SELECT date, count(*) WHERE date = "+today, accService, txService)
+	require.NoError(t, err)
+	assert.Equal(t, [][]Value{{today, int64(2)}}, result.Rows)
+
+	result, err = Run(ctx, "SELECT account, parent_id FROM accounts WHERE parent_id != '' ", accService, txService)
+	require.NoError(t, err)
+	assert.Equal(t, [][]Value{{"Food", cash.ID.Hex()}}, result.Rows)
+
+	_, err = Run(ctx, "SELECT nothing FROM", accService, txService)
+	assert.Error(t, err)
+}
@@ -0,0 +1,170 @@
+package query
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/fitiavana07/mitrack/pkg/transaction"
+)
+
+// valueKind is the type of a value.
+type valueKind uint8
+
+const (
+	kindInt valueKind = iota + 1
+	kindString
+	kindBool
+)
+
+func (k valueKind) String() string {
+	switch k {
+	case kindInt:
+		return "number"
+	case kindString:
+		return "string"
+	case kindBool:
+		return "boolean"
+	}
+	return "unknown"
+}
+
+// ColumnInfo describes a column of a table.
+type ColumnInfo struct {
+	Name string
+	Doc  string
+	kind valueKind
+}
+
+// Tables.
+const (
+	TableEntries  = "entries"
+	TableAccounts = "accounts"
+)
+
+// Tables are the columns of the queryable tables, by name.
+var Tables = map[string][]ColumnInfo{
+	TableEntries: {
+		{"date", "date of the transaction, YYYY-MM-DD (UTC)", kindString},
+		{"month", "month of the transaction, YYYY-MM", kindString},
+		{"year", "year of the transaction", kindInt},
+		{"hash", "hash of the transaction", kindString},
+		{"note", "note of the transaction", kindString},
+		{"operation", "debit or credit", kindString},
+		{"account", "name of the account", kindString},
+		{"alias", "alias of the account", kindString},
+		{"account_id", "ID of the account", kindString},
+		{"type", "type of the account: asset, liability, equity, expense or revenue", kindString},
+		{"parent_id", "ID of the parent of the account, empty for root accounts", kindString},
+		{"amount", "amount of the entry: positive for debits, negative for credits", kindInt},
+		{"debit", "amount of the entry if a debit, else 0", kindInt},
+		{"credit", "amount of the entry if a credit, else 0", kindInt},
+	},
+	TableAccounts: {
+		{"id", "ID of the account", kindString},
+		{"account", "name of the account", kindString},
+		{"alias", "alias of the account", kindString},
+		{"description", "description of the account", kindString},
+		{"type", "type of the account: asset, liability, equity, expense or revenue", kindString},
+		{"parent_id", "ID of the parent of the account, empty for root accounts", kindString},
+		{"created", "creation date of the account, YYYY-MM-DD (UTC)", kindString},
+	},
+}
+
+// dateLayout is the layout of the date values.
+const dateLayout = "2006-01-02"
+
+// loadRows returns the rows of table, the transactions being selected by f.
+// If some records could not be read, the rows of the others are returned
+// along with a *store.ListError.
+func loadRows(ctx context.Context, table string, f transaction.Filter, accService account.AccService, txService transaction.TxService) ([][]Value, error) {
+	listErr := &store.ListError{}
+	addFailures := func(err error) error {
+		var e *store.ListError
+		if !errors.As(err, &e) {
+			return err
+		}
+		listErr.Failures = append(listErr.Failures, e.Failures...)
+		return nil
+	}
+
+	accs, err := accService.ListContext(ctx)
+	if err := addFailures(err); err != nil {
+		return nil, err
+	}
+
+	rows := [][]Value{}
+	if table == TableAccounts {
+		for _, acc := range accs {
+			rows = append(rows, []Value{
+				acc.ID.Hex(),
+				acc.Name,
+				acc.Alias,
+				acc.Description,
+				typeName(acc.Type),
+				parentID(acc),
+				time.Unix(acc.Timestamp, 0).UTC().Format(dateLayout),
+			})
+		}
+		return rows, listErr.ErrOrNil()
+	}
+
+	accounts := map[account.ID]*account.Account{}
+	for _, acc := range accs {
+		accounts[acc.ID] = acc
+	}
+	it := txService.Iter(ctx, f)
+	defer it.Close()
+	for it.Next() {
+		tx := it.Tx()
+		date := time.Unix(tx.Timestamp(), 0).UTC()
+		hash := tx.Hash()
+		for _, e := range tx.Entries() {
+			acc, ok := accounts[e.AccountID()]
+			if !ok {
+				acc = &account.Account{ID: e.AccountID(), Name: e.AccountID().Short()}
+			}
+			amount, debit, credit := e.Amount(), e.Amount(), int64(0)
+			if e.Operation() == transaction.OpCredit {
+				amount, debit, credit = -e.Amount(), 0, e.Amount()
+			}
+			rows = append(rows, []Value{
+				date.Format(dateLayout),
+				date.Format("2006-01"),
+				int64(date.Year()),
+				hex.EncodeToString(hash[:]),
+				tx.Note(),
+				e.Operation().String(),
+				acc.Name,
+				acc.Alias,
+				acc.ID.Hex(),
+				typeName(acc.Type),
+				parentID(acc),
+				amount,
+				debit,
+				credit,
+			})
+		}
+	}
+	if err := addFailures(it.Err()); err != nil {
+		return nil, err
+	}
+	return rows, listErr.ErrOrNil()
+}
+
+func typeName(t account.Type) string {
+	if !t.IsValid() {
+		return ""
+	}
+	return t.String()
+}
+
+func parentID(acc *account.Account) string {
+	if acc.ParentID == (account.ID{}) {
+		return ""
+	}
+	return acc.ParentID.Hex()
+}
@@ -0,0 +1,348 @@
+// Package search provides a full-text index of the ledger: the notes of
+// transactions, and the names, aliases and descriptions of accounts.
+package search
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fitiavana07/mitrack/pkg/store"
+)
+
+// The index maps each term to the documents containing it. Its terms are
+// spread over 256 buckets, by the first byte of their SHA-256, so that the
+// store keys do not reveal the words of an encrypted ledger:
+//
+//	search-XX  the postings of the terms of bucket XX
+//
+// Each bucket is made of lines "term\tposting posting...", a posting being
+// "kind:id:fields", fields the bitmask of the fields holding the term.
+// The index is only complete once built (by Rebuild), which is recorded by
+// the infoKey metadata.
+
+const (
+	infoKey     = ".search"
+	infoContent = "v1"
+
+	bucketPrefix = "search-"
+)
+
+// Kinds of documents.
+const (
+	KindAccount     = "account"
+	KindTransaction = "transaction"
+)
+
+// Field is a searchable field of a document.
+type Field uint8
+
+// Fields of the documents, by increasing weight in the ranking.
+const (
+	FieldDescription Field = 1 << iota
+	FieldNote
+	FieldName
+	FieldAlias
+)
+
+// weights are the scores of a term found in a field.
+var weights = map[Field]int{
+	FieldDescription: 1,
+	FieldNote:        2,
+	FieldName:        3,
+	FieldAlias:       4,
+}
+
+// Document is an indexed account or transaction.
+type Document struct {
+	// Kind is KindAccount or KindTransaction.
+	Kind string
+
+	// ID is the ID of the account or the hash of the transaction, in hex.
+	ID string
+
+	// Fields are the texts of the document.
+	Fields map[Field]string
+}
+
+// postings returns the postings of d by term.
+func (d *Document) postings() map[string]posting {
+	postings := map[string]posting{}
+	for field, text := range d.Fields {
+		for _, term := range Terms(text) {
+			p := postings[term]
+			p.kind, p.id = d.Kind, d.ID
+			p.fields |= field
+			postings[term] = p
+		}
+	}
+	return postings
+}
+
+// posting is a document containing a term.
+type posting struct {
+	kind, id string
+	fields   Field
+}
+
+func (p posting) ref() string {
+	return p.kind + ":" + p.id
+}
+
+func (p posting) score() int {
+	score := 0
+	for field, weight := range weights {
+		if p.fields&field != 0 {
+			score += weight
+		}
+	}
+	return score
+}
+
+// Result is a document matching a search.
+type Result struct {
+	Kind string
+	ID   string
+
+	// Score ranks the results: the higher, the better the match.
+	Score int
+}
+
+// Index is the full-text index. It is safe for concurrent use by multiple
+// goroutines.
+type Index struct {
+	store store.Store
+
+	// mu serializes the updates of the buckets.
+	mu sync.Mutex
+}
+
+// NewIndex returns the index kept in st.
+func NewIndex(st store.Store) *Index {
+	return &Index{store: st}
+}
+
+func bucketKey(term string) string {
+	sum := sha256.Sum256([]byte(term))
+	return fmt.Sprintf("%s%02x", bucketPrefix, sum[0])
+}
+
+// Built returns whether the index was built, and so holds every document.
+func (ix *Index) Built() (bool, error) {
+	b, err := ix.store.Get(infoKey)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return string(b) == infoContent, nil
+}
+
+// Add indexes d, replacing the postings of its terms.
+func (ix *Index) Add(d Document) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.update(d.postings(), func(postings []posting, p posting) []posting {
+		return append(removePosting(postings, p), p)
+	})
+}
+
+// Remove removes d from the index. d must hold the texts it was indexed
+// with.
+func (ix *Index) Remove(d Document) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.update(d.postings(), removePosting)
+}
+
+func removePosting(postings []posting, p posting) []posting {
+	kept := postings[:0]
+	for _, q := range postings {
+		if q.ref() != p.ref() {
+			kept = append(kept, q)
+		}
+	}
+	return kept
+}
+
+// update applies f to the postings of each term, bucket by bucket.
+func (ix *Index) update(postings map[string]posting, f func([]posting, posting) []posting) error {
+	byBucket := map[string][]string{}
+	for term := range postings {
+		key := bucketKey(term)
+		byBucket[key] = append(byBucket[key], term)
+	}
+	for key, terms := range byBucket {
+		bucket, err := ix.bucket(key)
+		if err != nil {
+			return err
+		}
+		for _, term := range terms {
+			bucket[term] = f(bucket[term], postings[term])
+		}
+		if err := ix.store.Put(key, encodeBucket(bucket)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bucket returns the postings by term of the bucket stored under key.
+func (ix *Index) bucket(key string) (map[string][]posting, error) {
+	b, err := ix.store.Get(key)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string][]posting{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	bucket, err := decodeBucket(b)
+	if err != nil {
+		return nil, fmt.Errorf("corrupted search index %s, run reindex: %w", key, err)
+	}
+	return bucket, nil
+}
+
+func encodeBucket(bucket map[string][]posting) []byte {
+	terms := make([]string, 0, len(bucket))
+	for term, postings := range bucket {
+		if len(postings) > 0 {
+			terms = append(terms, term)
+		}
+	}
+	sort.Strings(terms)
+
+	var b bytes.Buffer
+	for _, term := range terms {
+		b.WriteString(term)
+		for i, p := range bucket[term] {
+			if i == 0 {
+				b.WriteByte('\t')
+			} else {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(&b, "%s:%s:%d", p.kind, p.id, p.fields)
+		}
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+func decodeBucket(b []byte) (map[string][]posting, error) {
+	bucket := map[string][]posting{}
+	for _, line := range strings.Split(strings.TrimSuffix(string(b), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		term, list, ok := strings.Cut(line, "\t")
+		if !ok {
+			return nil, fmt.Errorf("invalid line %q", line)
+		}
+		for _, s := range strings.Fields(list) {
+			parts := strings.Split(s, ":")
+			if len(parts) != 3 {
+				return nil, fmt.Errorf("invalid posting %q", s)
+			}
+			fields, err := strconv.ParseUint(parts[2], 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid posting %q", s)
+			}
+			bucket[term] = append(bucket[term], posting{parts[0], parts[1], Field(fields)})
+		}
+	}
+	return bucket, nil
+}
+
+// Search returns the documents containing every term of query, best
+// matches first. It returns no results for a query without terms.
+func (ix *Index) Search(query string) ([]Result, error) {
+	terms := Terms(query)
+	if len(terms) == 0 {
+		return []Result{}, nil
+	}
+
+	var scores map[string]*Result
+	for _, term := range terms {
+		bucket, err := ix.bucket(bucketKey(term))
+		if err != nil {
+			return nil, err
+		}
+		matches := map[string]*Result{}
+		for _, p := range bucket[term] {
+			r := &Result{Kind: p.kind, ID: p.id, Score: p.score()}
+			if scores != nil {
+				prev, ok := scores[p.ref()]
+				if !ok {
+					continue
+				}
+				r.Score += prev.Score
+			}
+			matches[p.ref()] = r
+		}
+		scores = matches
+	}
+
+	results := make([]Result, 0, len(scores))
+	for _, r := range scores {
+		results = append(results, *r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.ID < b.ID
+	})
+	return results, nil
+}
+
+// Rebuild replaces the index by the one of docs.
+func (ix *Index) Rebuild(ctx context.Context, docs []Document) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if err := ix.store.Delete(infoKey); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	buckets := map[string]map[string][]posting{}
+	for _, d := range docs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for term, p := range d.postings() {
+			key := bucketKey(term)
+			if buckets[key] == nil {
+				buckets[key] = map[string][]posting{}
+			}
+			buckets[key][term] = append(buckets[key][term], p)
+		}
+	}
+
+	old, err := ix.store.Keys()
+	if err != nil {
+		return err
+	}
+	for _, key := range old {
+		if _, ok := buckets[key]; strings.HasPrefix(key, bucketPrefix) && !ok {
+			if err := ix.store.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+	for key, bucket := range buckets {
+		if err := ix.store.Put(key, encodeBucket(bucket)); err != nil {
+			return err
+		}
+	}
+	return ix.store.Put(infoKey, []byte(infoContent))
+}
@@ -0,0 +1,131 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestText(t *testing.T) {
+	assert.Equal(t, "fiaran-dalana", Fold("Fiaran-dalàna"))
+	assert.Equal(t, "noel a l'ecole", Fold("Noël à l'École"))
+	assert.Equal(t, "coeur", Fold("Cœur"))
+
+	assert.Equal(t, []string{"naka", "vola", "sabotsy", "namehana"}, Terms("Naka vola, sabotsy Namehana"))
+	assert.Equal(t, []string{"cafe", "2"}, Terms("café Café 2"))
+	assert.Equal(t, []string{}, Terms(" - , "))
+
+	mark := func(word string) string { return "[" + word + "]" }
+	assert.Equal(t, "[Naka] vola, sabotsy [Namehana]!", Highlight("Naka vola, sabotsy Namehana!", "namehana NAKA", mark))
+	assert.Equal(t, "[Fàrany] [farany] faranany", Highlight("Fàrany farany faranany", "farany", mark))
+	assert.Equal(t, "nothing", Highlight("nothing", "", mark))
+}
+
+func TestIndex(t *testing.T) {
+	cash := Document{Kind: KindAccount, ID: "01", Fields: map[Field]string{
+		FieldName:        "Vola an-tanana",
+		FieldAlias:       "vola",
+		FieldDescription: "Cash in the wallet",
+	}}
+	withdraw := Document{Kind: KindTransaction, ID: "a1", Fields: map[Field]string{
+		FieldNote: "naka vola sabotsy namehana",
+	}}
+	bread := Document{Kind: KindTransaction, ID: "a2", Fields: map[Field]string{
+		FieldNote: "Mofo, paiement en espèces",
+	}}
+
+	newIndex := func(t *testing.T) (store.Store, *Index) {
+		st := store.NewDirStore(t.TempDir())
+		ix := NewIndex(st)
+		for _, d := range []Document{cash, withdraw, bread} {
+			require.NoError(t, ix.Add(d))
+		}
+		return st, ix
+	}
+
+	t.Run("search", func(t *testing.T) {
+		_, ix := newIndex(t)
+
+		for _, tt := range []struct {
+			query    string
+			expected []Result
+		}{
+			{"vola", []Result{{KindAccount, "01", 7}, {KindTransaction, "a1", 2}}},
+			{"Sabotsy VOLA", []Result{{KindTransaction, "a1", 4}}},
+			{"especes", []Result{{KindTransaction, "a2", 2}}},
+			{"espèces", []Result{{KindTransaction, "a2", 2}}},
+			{"wallet", []Result{{KindAccount, "01", 1}}},
+			{"vola mofo", []Result{}},
+			{"unknown", []Result{}},
+			{"", []Result{}},
+		} {
+			results, err := ix.Search(tt.query)
+			assert.NoError(t, err, tt.query)
+			assert.Equal(t, tt.expected, results, tt.query)
+		}
+	})
+	t.Run("update", func(t *testing.T) {
+		_, ix := newIndex(t)
+
+		require.NoError(t, ix.Remove(cash))
+		renamed := cash
+		renamed.Fields = map[Field]string{FieldName: "Kitapo", FieldAlias: "kitapo"}
+		require.NoError(t, ix.Add(renamed))
+
+		results, err := ix.Search("vola")
+		assert.NoError(t, err)
+		assert.Equal(t, []Result{{KindTransaction, "a1", 2}}, results)
+		results, err = ix.Search("kitapo")
+		assert.NoError(t, err)
+		assert.Equal(t, []Result{{KindAccount, "01", 7}}, results)
+
+		// added twice
+		require.NoError(t, ix.Add(withdraw))
+		results, err = ix.Search("namehana")
+		assert.NoError(t, err)
+		assert.Equal(t, []Result{{KindTransaction, "a1", 2}}, results)
+	})
+	t.Run("rebuild", func(t *testing.T) {
+		st, ix := newIndex(t)
+		require.NoError(t, st.Put("date-20220301", []byte("not a bucket")))
+		built, err := ix.Built()
+		require.NoError(t, err)
+		assert.False(t, built)
+
+		require.NoError(t, ix.Rebuild(context.Background(), []Document{bread}))
+		built, err = ix.Built()
+		require.NoError(t, err)
+		assert.True(t, built)
+
+		results, err := ix.Search("vola")
+		assert.NoError(t, err)
+		assert.Empty(t, results)
+		results, err = ix.Search("mofo")
+		assert.NoError(t, err)
+		assert.Equal(t, []Result{{KindTransaction, "a2", 2}}, results)
+
+		_, err = st.Get("date-20220301")
+		assert.NoError(t, err, "other keys of the store are kept")
+	})
+	t.Run("terms are not revealed by keys", func(t *testing.T) {
+		st, _ := newIndex(t)
+
+		keys, err := st.Keys()
+		require.NoError(t, err)
+		for _, key := range keys {
+			assert.True(t, strings.HasPrefix(key, bucketPrefix), key)
+			assert.Len(t, key, len(bucketPrefix)+2, key)
+		}
+	})
+	t.Run("corrupted bucket", func(t *testing.T) {
+		st, ix := newIndex(t)
+		require.NoError(t, st.Put(bucketKey("vola"), []byte("vola\tbad posting\n")))
+
+		_, err := ix.Search("vola")
+		assert.Error(t, err)
+	})
+}
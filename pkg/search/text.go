@@ -0,0 +1,108 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// foldings are the letters folded into several ones, or not folded by
+// removing their diacritics.
+var foldings = map[rune]string{
+	'æ': "ae",
+	'œ': "oe",
+	'ß': "ss",
+	'ø': "o",
+	'đ': "d",
+	'ł': "l",
+}
+
+// diacritics maps the letters with diacritics of Malagasy and French (and
+// of the other Latin scripts) to their base letter.
+var diacritics = map[rune]rune{}
+
+func init() {
+	for base, letters := range map[rune]string{
+		'a': "àáâãäåāăą",
+		'c': "çćĉċč",
+		'e': "èéêëēĕėęě",
+		'i': "ìíîïĩīĭį",
+		'n': "ñńņňŉ",
+		'o': "òóôõöōŏő",
+		'u': "ùúûüũūŭůűų",
+		'y': "ýÿŷ",
+		'z': "źżž",
+		's': "śŝşš",
+		'g': "ĝğġģ",
+		'r': "ŕŗř",
+	} {
+		for _, r := range letters {
+			diacritics[r] = base
+		}
+	}
+}
+
+// Fold returns s in lower case, without diacritics, so that "Fiaran-dalàna"
+// and "fiaran-dalana" are searched alike.
+func Fold(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		r = unicode.ToLower(r)
+		if f, ok := foldings[r]; ok {
+			b.WriteString(f)
+		} else if base, ok := diacritics[r]; ok {
+			b.WriteRune(base)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isWordRune returns whether r is part of a word.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Terms returns the folded words of s, in order, without duplicates.
+func Terms(s string) []string {
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, word := range strings.FieldsFunc(s, func(r rune) bool { return !isWordRune(r) }) {
+		term := Fold(word)
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// Highlight returns text with the words matching a term of query replaced
+// by mark(word).
+func Highlight(text, query string, mark func(word string) string) string {
+	terms := map[string]bool{}
+	for _, term := range Terms(query) {
+		terms[term] = true
+	}
+
+	var b strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			b.WriteRune(runes[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && isWordRune(runes[j]) {
+			j++
+		}
+		word := string(runes[i:j])
+		if terms[Fold(word)] {
+			word = mark(word)
+		}
+		b.WriteString(word)
+		i = j
+	}
+	return b.String()
+}
@@ -0,0 +1,287 @@
+package store
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// KeySize is the size of encryption keys (AES-256).
+const KeySize = 32
+
+// encryptedMagic starts every encrypted value. It is followed by the nonce,
+// then by the AES-GCM sealed value, authenticated together with its key
+// so that two records can not be swapped.
+var encryptedMagic = []byte("\x89MTRKENC")
+
+// NewEncryptedStore returns a Store encrypting every record put in s with
+// AES-256-GCM, using the given key.
+//
+// Records stored in plaintext (ex: before the store was encrypted) are
+// still returned as is by Get, and metadata (keys starting with a dot)
+// are not encrypted, unless registered by RegisterPrivateMetadata.
+// A nil key returns a locked store: getting an encrypted record or putting
+// a record then returns ErrLocked.
+//
+// The returned store is a KeyHasher, so that the keys of the records
+// derived from their content (ex: indexes) reveal nothing.
+func NewEncryptedStore(s Store, key []byte) (Store, error) {
+	if key == nil {
+		return &encryptedStore{Store: s}, nil
+	}
+
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid key size %d, want %d", len(key), KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(hashKeyText))
+	return &encryptedStore{Store: s, aead: aead, hashKey: mac.Sum(nil)}, nil
+}
+
+type encryptedStore struct {
+	Store
+	aead cipher.AEAD
+
+	// hashKey is the key of the HMAC of HashKey, derived from the
+	// encryption key.
+	hashKey []byte
+}
+
+// hashKeyText is the constant from which the key of HashKey is derived.
+const hashKeyText = "mitrack key hashing"
+
+// KeyHasher is implemented by the stores whose keys must not reveal the
+// content of their records.
+type KeyHasher interface {
+	// HashKey returns the key under which to store the record of key.
+	HashKey(key string) (string, error)
+}
+
+// privateMetadata are the metadata encrypted by the encrypted stores,
+// registered by RegisterPrivateMetadata.
+var (
+	privateMetadataMu sync.RWMutex
+	privateMetadata   = map[string]bool{}
+)
+
+// RegisterPrivateMetadata registers key as a metadata encrypted by the
+// encrypted stores, like the records are: it can not be read while the
+// store is locked.
+func RegisterPrivateMetadata(key string) {
+	privateMetadataMu.Lock()
+	defer privateMetadataMu.Unlock()
+	privateMetadata[key] = true
+}
+
+// isPrivateMetadata returns whether key was registered by
+// RegisterPrivateMetadata.
+func isPrivateMetadata(key string) bool {
+	privateMetadataMu.RLock()
+	defer privateMetadataMu.RUnlock()
+	return privateMetadata[key]
+}
+
+// isPlaintext returns whether the value of key is never encrypted.
+func isPlaintext(key string) bool {
+	return isMetadata(key) && !isPrivateMetadata(key)
+}
+
+func (s *encryptedStore) Get(key string) ([]byte, error) {
+	b, err := s.Store.Get(key)
+	if err != nil || isPlaintext(key) || !bytes.HasPrefix(b, encryptedMagic) {
+		return b, err
+	}
+	if s.aead == nil {
+		return nil, ErrLocked
+	}
+
+	b = b[len(encryptedMagic):]
+	if len(b) < s.aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, sealed := b[:s.aead.NonceSize()], b[s.aead.NonceSize():]
+
+	plain, err := s.aead.Open(nil, nonce, sealed, []byte(key))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
+
+func (s *encryptedStore) Put(key string, b []byte) error {
+	if isPlaintext(key) {
+		return s.Store.Put(key, b)
+	}
+	if s.aead == nil {
+		return ErrLocked
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	out := make([]byte, 0, len(encryptedMagic)+len(nonce)+len(b)+s.aead.Overhead())
+	out = append(out, encryptedMagic...)
+	out = append(out, nonce...)
+	out = s.aead.Seal(out, nonce, b, []byte(key))
+
+	return s.Store.Put(key, out)
+}
+
+// HashKey returns the hex HMAC-SHA256 of key, or ErrLocked if the store
+// is locked.
+func (s *encryptedStore) HashKey(key string) (string, error) {
+	if s.hashKey == nil {
+		return "", ErrLocked
+	}
+	mac := hmac.New(sha256.New, s.hashKey)
+	mac.Write([]byte(key))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Path returns the path of the file of key in the underlying store.
+func (s *encryptedStore) Path(key string) string {
+	return PathOf(s.Store, key)
+}
+
+// Lock takes the lock of the underlying store.
+func (s *encryptedStore) Lock() (func() error, error) {
+	return LockOf(s.Store)
+}
+
+// Stat returns the os.FileInfo of the file of key in the underlying store.
+func (s *encryptedStore) Stat(key string) (os.FileInfo, error) {
+	return StatOf(s.Store, key)
+}
+
+func isMetadata(key string) bool {
+	return strings.HasPrefix(key, ".")
+}
+
+// KeyInfo holds what is needed to derive the encryption key of a workdir
+// from a passphrase. It is stored in plaintext, in JSON.
+type KeyInfo struct {
+	// KDF is the key derivation function; only "scrypt" is supported.
+	KDF string `json:"kdf"`
+
+	// Salt, N, R and P are the scrypt parameters.
+	Salt []byte `json:"salt"`
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+
+	// Check is a MAC of a constant string by the key,
+	// used to tell whether a passphrase or a key is the right one.
+	Check []byte `json:"check"`
+}
+
+const (
+	kdfScrypt = "scrypt"
+	checkText = "mitrack key check"
+)
+
+// NewKeyInfo returns a new KeyInfo, with a random salt and the recommended
+// scrypt parameters, whose key is derived from passphrase.
+// It returns the derived key as well.
+func NewKeyInfo(passphrase string) (*KeyInfo, []byte, error) {
+	ki := &KeyInfo{KDF: kdfScrypt, Salt: make([]byte, 16), N: 1 << 15, R: 8, P: 1}
+	if _, err := rand.Read(ki.Salt); err != nil {
+		return nil, nil, err
+	}
+
+	key, err := ki.derive(passphrase)
+	if err != nil {
+		return nil, nil, err
+	}
+	ki.Check = keyCheck(key)
+	return ki, key, nil
+}
+
+// DeriveKey derives the key from passphrase. It returns ErrWrongKey
+// if the passphrase is not the one used with NewKeyInfo.
+func (ki *KeyInfo) DeriveKey(passphrase string) ([]byte, error) {
+	key, err := ki.derive(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if err := ki.Verify(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Verify returns ErrWrongKey if key is not the one of ki.
+func (ki *KeyInfo) Verify(key []byte) error {
+	if !hmac.Equal(keyCheck(key), ki.Check) {
+		return ErrWrongKey
+	}
+	return nil
+}
+
+func (ki *KeyInfo) derive(passphrase string) ([]byte, error) {
+	if ki.KDF != kdfScrypt {
+		return nil, fmt.Errorf("unsupported key derivation function %q", ki.KDF)
+	}
+	return scrypt.Key([]byte(passphrase), ki.Salt, ki.N, ki.R, ki.P, KeySize)
+}
+
+func keyCheck(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(checkText))
+	return mac.Sum(nil)
+}
+
+// ReadKeyInfo reads the KeyInfo stored in the file at path.
+func ReadKeyInfo(path string) (*KeyInfo, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ki := &KeyInfo{}
+	if err := json.Unmarshal(b, ki); err != nil {
+		return nil, fmt.Errorf("invalid key info file: %w", err)
+	}
+	return ki, nil
+}
+
+// WriteKeyInfo writes ki into the file at path.
+func WriteKeyInfo(path string, ki *KeyInfo) error {
+	b, err := json.MarshalIndent(ki, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+var (
+	// ErrLocked is returned by a locked encrypted store, ie: without key.
+	ErrLocked = errors.New("store is encrypted and no key was provided")
+
+	// ErrDecrypt is returned when a record can not be decrypted,
+	// because it was tampered with or encrypted with another key.
+	ErrDecrypt = errors.New("could not decrypt record")
+
+	// ErrWrongKey is returned when a passphrase or a key is not the right one.
+	ErrWrongKey = errors.New("wrong passphrase or key")
+)
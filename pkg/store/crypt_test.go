@@ -0,0 +1,137 @@
+package store
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedStore(t *testing.T) {
+	record := []byte("naka vola sabotsy namehana")
+
+	t.Run("round trip", func(t *testing.T) {
+		plain := NewDirStore(t.TempDir())
+		s := newTestEncryptedStore(t, plain, bytes.Repeat([]byte{1}, KeySize))
+
+		require.NoError(t, s.Put("abcd", record))
+
+		b, err := s.Get("abcd")
+		assert.NoError(t, err)
+		assert.Equal(t, record, b)
+
+		raw, err := plain.Get("abcd")
+		require.NoError(t, err)
+		assert.NotContains(t, string(raw), "vola", "record stored in plaintext")
+	})
+	t.Run("plaintext records and metadata", func(t *testing.T) {
+		plain := NewDirStore(t.TempDir())
+		require.NoError(t, plain.Put("abcd", record))
+		s := newTestEncryptedStore(t, plain, bytes.Repeat([]byte{1}, KeySize))
+
+		require.NoError(t, s.Put(".dbinfo", []byte("quick:v0.4")))
+
+		b, err := s.Get("abcd")
+		assert.NoError(t, err)
+		assert.Equal(t, record, b)
+
+		raw, err := plain.Get(".dbinfo")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("quick:v0.4"), raw)
+	})
+	t.Run("wrong key", func(t *testing.T) {
+		plain := NewDirStore(t.TempDir())
+		require.NoError(t, newTestEncryptedStore(t, plain, bytes.Repeat([]byte{1}, KeySize)).Put("abcd", record))
+
+		_, err := newTestEncryptedStore(t, plain, bytes.Repeat([]byte{2}, KeySize)).Get("abcd")
+		assert.ErrorIs(t, err, ErrDecrypt)
+	})
+	t.Run("swapped records", func(t *testing.T) {
+		plain := NewDirStore(t.TempDir())
+		s := newTestEncryptedStore(t, plain, bytes.Repeat([]byte{1}, KeySize))
+		require.NoError(t, s.Put("abcd", record))
+
+		raw, err := plain.Get("abcd")
+		require.NoError(t, err)
+		require.NoError(t, plain.Put("efgh", raw))
+
+		_, err = s.Get("efgh")
+		assert.ErrorIs(t, err, ErrDecrypt)
+	})
+	t.Run("locked", func(t *testing.T) {
+		plain := NewDirStore(t.TempDir())
+		require.NoError(t, newTestEncryptedStore(t, plain, bytes.Repeat([]byte{1}, KeySize)).Put("abcd", record))
+		s := newTestEncryptedStore(t, plain, nil)
+
+		_, err := s.Get("abcd")
+		assert.ErrorIs(t, err, ErrLocked)
+		assert.ErrorIs(t, s.Put("efgh", record), ErrLocked)
+	})
+	t.Run("private metadata", func(t *testing.T) {
+		RegisterPrivateMetadata(".private")
+		plain := NewDirStore(t.TempDir())
+		s := newTestEncryptedStore(t, plain, bytes.Repeat([]byte{1}, KeySize))
+		require.NoError(t, s.Put(".private", record))
+
+		raw, err := plain.Get(".private")
+		require.NoError(t, err)
+		assert.NotContains(t, string(raw), "vola", "private metadata stored in plaintext")
+		b, err := s.Get(".private")
+		assert.NoError(t, err)
+		assert.Equal(t, record, b)
+
+		_, err = newTestEncryptedStore(t, plain, nil).Get(".private")
+		assert.ErrorIs(t, err, ErrLocked)
+
+		other := NewDirStore(t.TempDir())
+		require.NoError(t, Copy(other, s))
+		b, err = other.Get(".private")
+		assert.NoError(t, err)
+		assert.Equal(t, record, b, "copied")
+	})
+	t.Run("hashed keys", func(t *testing.T) {
+		s := newTestEncryptedStore(t, NewDirStore(t.TempDir()), bytes.Repeat([]byte{1}, KeySize))
+		hashed, err := s.(KeyHasher).HashKey("date-20220301")
+		require.NoError(t, err)
+		assert.Len(t, hashed, 64)
+		assert.NotContains(t, hashed, "2022")
+		again, err := s.(KeyHasher).HashKey("date-20220301")
+		require.NoError(t, err)
+		assert.Equal(t, hashed, again)
+
+		other, err := newTestEncryptedStore(t, NewDirStore(t.TempDir()), bytes.Repeat([]byte{2}, KeySize)).(KeyHasher).HashKey("date-20220301")
+		require.NoError(t, err)
+		assert.NotEqual(t, hashed, other, "depends on the key")
+
+		_, err = newTestEncryptedStore(t, NewDirStore(t.TempDir()), nil).(KeyHasher).HashKey("date-20220301")
+		assert.ErrorIs(t, err, ErrLocked)
+	})
+}
+
+func TestKeyInfo(t *testing.T) {
+	ki, key, err := NewKeyInfo("secret")
+	require.NoError(t, err)
+	assert.Len(t, key, KeySize)
+
+	path := filepath.Join(t.TempDir(), ".keyinfo")
+	require.NoError(t, WriteKeyInfo(path, ki))
+	ki, err = ReadKeyInfo(path)
+	require.NoError(t, err)
+
+	got, err := ki.DeriveKey("secret")
+	assert.NoError(t, err)
+	assert.Equal(t, key, got)
+
+	_, err = ki.DeriveKey("wrong")
+	assert.ErrorIs(t, err, ErrWrongKey)
+}
+
+func newTestEncryptedStore(t testing.TB, s Store, key []byte) Store {
+	t.Helper()
+
+	es, err := NewEncryptedStore(s, key)
+	require.NoError(t, err)
+	return es
+}
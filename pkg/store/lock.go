@@ -0,0 +1,43 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// lockFileName is the file locked by the Lock of a dirStore. As a
+// metadata, it is not a key.
+const lockFileName = ".lock"
+
+// LockOf takes the exclusive lock of s, shared with the other processes,
+// waiting for them to release it, and returns the function releasing it.
+// It serializes the read-modify-writes of records (ex: indexes, counts).
+// A lock is released when its process ends. Nothing is locked if s does
+// not keep its values in files.
+func LockOf(s Store) (unlock func() error, err error) {
+	if l, ok := s.(interface {
+		Lock() (func() error, error)
+	}); ok {
+		return l.Lock()
+	}
+	return func() error { return nil }, nil
+}
+
+// Lock locks the lockFileName file of dir.
+func (s *dirStore) Lock() (func() error, error) {
+	f, err := os.OpenFile(filepath.Join(s.dir, lockFileName), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() error {
+		err := unlockFile(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
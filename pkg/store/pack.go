@@ -0,0 +1,524 @@
+package store
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pack files hold many values of a directory, so that old records do not
+// need a file each. They are written by PackDir, and never modified.
+//
+// A pack is made of two files, named after the hash of the pack content:
+//
+//	.pack-<sha256>.pack: packMagic | zlib(value) | zlib(value) | ...
+//	.pack-<sha256>.idx:  idxMagic | uvarint version | uvarint count | entry... | crc32c
+//
+// where each entry of the index, sorted by key, is
+//
+//	uvarint len(key) | key | uvarint offset | uvarint size | uvarint length | crc32c(value)
+//
+// offset and size being the ones of the compressed value in the pack file,
+// and length the size of the value. Checksums are little-endian uint32.
+// Encrypted values are stored in zlib streams without compression, which
+// would only make them larger.
+// The index is written last: a pack without index is ignored.
+var (
+	packMagic = []byte("\x89MTRKPCK")
+	idxMagic  = []byte("\x89MTRKIDX")
+)
+
+const (
+	packPrefix  = ".pack-"
+	packSuffix  = ".pack"
+	idxSuffix   = ".idx"
+	idxVersion  = 1
+	maxPackSize = 1 << 30
+)
+
+// NewPackStore returns a Store keeping each value in a file of dir, like
+// NewDirStore, and reading as well the values moved into the pack files
+// of dir by PackDir.
+//
+// A value which is both in a file and in a pack is read from the file.
+// Values which are only in a pack can not be deleted.
+func NewPackStore(dir string) Store {
+	return &packStore{dirStore: &dirStore{dir: dir}}
+}
+
+type packStore struct {
+	*dirStore
+
+	// mu protects packs, which are loaded on first use, and reloaded when
+	// another process packs dir.
+	mu    sync.Mutex
+	packs []*pack
+}
+
+func (s *packStore) Get(key string) ([]byte, error) {
+	b, err := s.dirStore.Get(key)
+	if !errors.Is(err, os.ErrNotExist) {
+		return b, err
+	}
+
+	p, e, err := s.find(key)
+	if err != nil {
+		return nil, err
+	} else if p == nil {
+		return nil, fmt.Errorf("%s: %w", key, os.ErrNotExist)
+	}
+	b, err = p.read(e)
+	if errors.Is(err, os.ErrNotExist) {
+		// repacked by another process since loaded
+		if _, err := s.reload(); err != nil {
+			return nil, err
+		}
+		if p, e, err = s.find(key); err != nil {
+			return nil, err
+		} else if p == nil {
+			return nil, fmt.Errorf("%s: %w", key, os.ErrNotExist)
+		}
+		return p.read(e)
+	}
+	return b, err
+}
+
+func (s *packStore) Delete(key string) error {
+	err := s.dirStore.Delete(key)
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if p, _, findErr := s.find(key); findErr == nil && p != nil {
+		return fmt.Errorf("%w: %q", ErrPacked, key)
+	}
+	return err
+}
+
+func (s *packStore) Keys() ([]string, error) {
+	keys, err := s.dirStore.Keys()
+	if err != nil {
+		return nil, err
+	}
+	packs, err := s.reload()
+	if err != nil {
+		return nil, err
+	}
+	if len(packs) == 0 {
+		return keys, nil
+	}
+
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		seen[key] = true
+	}
+	for _, p := range packs {
+		for key := range p.entries {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Path returns the path of the file of key, or the path of the pack
+// holding its value, followed by #key.
+func (s *packStore) Path(key string) string {
+	path := s.dirStore.Path(key)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if p, _, err := s.find(key); err == nil && p != nil {
+			return p.path + "#" + key
+		}
+	}
+	return path
+}
+
+// find returns the pack holding the value of key, and its entry.
+// It returns a nil pack if no pack holds it.
+func (s *packStore) find(key string) (*pack, packEntry, error) {
+	s.mu.Lock()
+	packs := s.packs
+	s.mu.Unlock()
+
+	if packs == nil {
+		var err error
+		if packs, err = s.reload(); err != nil {
+			return nil, packEntry{}, err
+		}
+	}
+	for _, p := range packs {
+		if e, ok := p.entries[key]; ok {
+			return p, e, nil
+		}
+	}
+
+	// the packs may have been replaced by another process
+	packs, err := s.reload()
+	if err != nil {
+		return nil, packEntry{}, err
+	}
+	for _, p := range packs {
+		if e, ok := p.entries[key]; ok {
+			return p, e, nil
+		}
+	}
+	return nil, packEntry{}, nil
+}
+
+// reload loads the indexes of the packs of the directory. The indexes
+// which were already loaded are kept.
+func (s *packStore) reload() ([]*pack, error) {
+	names, err := packNames(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	loaded := make(map[string]*pack, len(s.packs))
+	for _, p := range s.packs {
+		loaded[p.path] = p
+	}
+
+	packs := make([]*pack, 0, len(names))
+	for _, name := range names {
+		path := filepath.Join(s.dir, name+packSuffix)
+		if p, ok := loaded[path]; ok {
+			packs = append(packs, p)
+			continue
+		}
+		p, err := readPackIndex(path, filepath.Join(s.dir, name+idxSuffix))
+		if errors.Is(err, os.ErrNotExist) {
+			// removed by another process since listed
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		packs = append(packs, p)
+	}
+	s.packs = packs
+	return packs, nil
+}
+
+// HasPacks returns whether dir has pack files.
+func HasPacks(dir string) (bool, error) {
+	names, err := packNames(dir)
+	return len(names) > 0, err
+}
+
+// packNames returns the names of the packs of dir, without suffix.
+func packNames(dir string) ([]string, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, entry := range dirEntries {
+		name := entry.Name()
+		if strings.HasPrefix(name, packPrefix) && strings.HasSuffix(name, idxSuffix) {
+			names = append(names, strings.TrimSuffix(name, idxSuffix))
+		}
+	}
+	return names, nil
+}
+
+// pack is a loaded pack index.
+type pack struct {
+	// path is the path of the pack file.
+	path    string
+	entries map[string]packEntry
+}
+
+type packEntry struct {
+	offset, size, length uint64
+	checksum             uint32
+}
+
+// read reads the value of e from the pack file.
+func (p *pack) read(e packEntry) ([]byte, error) {
+	f, err := os.Open(p.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := zlib.NewReader(io.NewSectionReader(f, int64(e.offset), int64(e.size)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", p.path, ErrCorruptedPack, err)
+	}
+	b, err := readFull(zr, e.length)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", p.path, ErrCorruptedPack, err)
+	}
+	if crc32.Checksum(b, crcTable) != e.checksum {
+		return nil, fmt.Errorf("%s: %w: checksum mismatch", p.path, ErrCorruptedPack)
+	}
+	return b, nil
+}
+
+// readFull reads exactly n bytes from r.
+func readFull(r io.Reader, n uint64) ([]byte, error) {
+	if n > maxPackSize {
+		return nil, fmt.Errorf("value too large: %d bytes", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+func readPackIndex(packPath, idxPath string) (*pack, error) {
+	b, err := os.ReadFile(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	corrupted := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%s: %w: %s", idxPath, ErrCorruptedPack, fmt.Sprintf(format, args...))
+	}
+
+	if len(b) < len(idxMagic)+4 || !bytes.Equal(b[:len(idxMagic)], idxMagic) {
+		return nil, corrupted("not a pack index")
+	}
+	content, checksum := b[:len(b)-4], binary.LittleEndian.Uint32(b[len(b)-4:])
+	if crc32.Checksum(content, crcTable) != checksum {
+		return nil, corrupted("checksum mismatch")
+	}
+
+	r := bytes.NewReader(content[len(idxMagic):])
+	version, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, corrupted("%s", err)
+	} else if version != idxVersion {
+		return nil, corrupted("unsupported version %d", version)
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, corrupted("%s", err)
+	}
+
+	p := &pack{path: packPath, entries: map[string]packEntry{}}
+	for i := uint64(0); i < count; i++ {
+		keyLen, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, corrupted("%s", err)
+		}
+		if keyLen > uint64(r.Len()) {
+			return nil, corrupted("key too long")
+		}
+		key := make([]byte, keyLen)
+		if _, err := io.ReadFull(r, key); err != nil {
+			return nil, corrupted("%s", err)
+		}
+
+		var e packEntry
+		for _, v := range []*uint64{&e.offset, &e.size, &e.length} {
+			if *v, err = binary.ReadUvarint(r); err != nil {
+				return nil, corrupted("%s", err)
+			}
+		}
+		if err := binary.Read(r, binary.LittleEndian, &e.checksum); err != nil {
+			return nil, corrupted("%s", err)
+		}
+		p.entries[string(key)] = e
+	}
+	if r.Len() != 0 {
+		return nil, corrupted("trailing data")
+	}
+	return p, nil
+}
+
+// PackDir moves the values of the files of dir last modified before the
+// given time, and the ones of the existing packs, into a single new pack.
+// It returns the number of files moved into the pack.
+//
+// Values being moved can still be read by the stores of dir, from their
+// files or from the new pack. If PackDir is interrupted, it can be run
+// again: values left in both a file and a pack are read from the file.
+func PackDir(dir string, before time.Time) (int, error) {
+	s := &packStore{dirStore: &dirStore{dir: dir}}
+	packs, err := s.reload()
+	if err != nil {
+		return 0, err
+	}
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	loose := []string{}
+	for _, entry := range dirEntries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, tmpSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		if info.ModTime().Before(before) {
+			loose = append(loose, name)
+		}
+	}
+	if len(loose) == 0 && len(packs) <= 1 {
+		// nothing to pack
+		return 0, nil
+	}
+
+	values := map[string]func() ([]byte, error){}
+	for _, p := range packs {
+		p := p
+		for key, e := range p.entries {
+			e := e
+			values[key] = func() ([]byte, error) { return p.read(e) }
+		}
+	}
+	for _, key := range loose {
+		key := key
+		values[key] = func() ([]byte, error) { return s.dirStore.Get(key) }
+	}
+
+	if err := writePack(dir, values); err != nil {
+		return 0, err
+	}
+
+	// the values are in the new pack: the old ones can be removed
+	for _, p := range packs {
+		idxPath := strings.TrimSuffix(p.path, packSuffix) + idxSuffix
+		if err := os.Remove(idxPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, err
+		}
+		if err := os.Remove(p.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, err
+		}
+	}
+	for _, key := range loose {
+		if err := os.Remove(filepath.Join(dir, key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, err
+		}
+	}
+	return len(loose), nil
+}
+
+// writePack writes a pack of the given values into dir.
+func writePack(dir string, values map[string]func() ([]byte, error)) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	f, err := os.CreateTemp(dir, packPrefix+"*"+tmpSuffix)
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	h := sha256.New()
+	w := io.MultiWriter(f, h)
+	if _, err := w.Write(packMagic); err != nil {
+		return err
+	}
+
+	idx := append([]byte{}, idxMagic...)
+	idx = appendUvarint(idx, idxVersion)
+	idx = appendUvarint(idx, uint64(len(keys)))
+	offset := uint64(len(packMagic))
+	compressed := new(bytes.Buffer)
+	zw := zlib.NewWriter(compressed)
+	stored, err := zlib.NewWriterLevel(compressed, zlib.NoCompression)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		b, err := values[key]()
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+
+		enc := zw
+		if bytes.HasPrefix(b, encryptedMagic) {
+			enc = stored
+		}
+		compressed.Reset()
+		enc.Reset(compressed)
+		if _, err := enc.Write(b); err != nil {
+			return err
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+		size := uint64(compressed.Len())
+		if _, err := compressed.WriteTo(w); err != nil {
+			return err
+		}
+
+		idx = appendUvarint(idx, uint64(len(key)))
+		idx = append(idx, key...)
+		idx = appendUvarint(idx, offset)
+		idx = appendUvarint(idx, size)
+		idx = appendUvarint(idx, uint64(len(b)))
+		idx = appendUint32(idx, crc32.Checksum(b, crcTable))
+		offset += size
+	}
+	idx = appendUint32(idx, crc32.Checksum(idx, crcTable))
+
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0644); err != nil {
+		return err
+	}
+
+	name := packPrefix + hex.EncodeToString(h.Sum(nil))
+	if err := os.Rename(tmp, filepath.Join(dir, name+packSuffix)); err != nil {
+		return err
+	}
+	// the index is written last, as it makes the pack visible
+	return (&dirStore{dir: dir}).Put(name+idxSuffix, idx)
+}
+
+func appendUint32(b []byte, x uint32) []byte {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, x)
+	return append(b, buf...)
+}
+
+func appendUvarint(b []byte, x uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, x)
+	return append(b, buf[:n]...)
+}
+
+var (
+	// ErrPacked is returned when deleting a value which is in a pack file.
+	ErrPacked = errors.New("value is in a pack file")
+
+	// ErrCorruptedPack is returned when a pack file or its index can not
+	// be read.
+	ErrCorruptedPack = errors.New("corrupted pack")
+)
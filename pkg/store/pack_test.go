@@ -0,0 +1,158 @@
+package store
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackStore(t *testing.T) {
+	// newPacked returns a pack store of a directory whose values a and b
+	// are packed, and c is not.
+	newPacked := func(t *testing.T) (string, Store) {
+		dir := t.TempDir()
+		s := NewPackStore(dir)
+		for _, key := range []string{"a", "b", ".dbinfo"} {
+			require.NoError(t, s.Put(key, []byte(strings.Repeat(key, 100))))
+		}
+
+		n, err := PackDir(dir, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		require.NoError(t, s.Put("c", []byte("c")))
+		return dir, s
+	}
+
+	t.Run("get", func(t *testing.T) {
+		dir, s := newPacked(t)
+
+		for key, value := range map[string]string{
+			"a":       strings.Repeat("a", 100),
+			"b":       strings.Repeat("b", 100),
+			"c":       "c",
+			".dbinfo": strings.Repeat(".dbinfo", 100),
+		} {
+			b, err := s.Get(key)
+			assert.NoError(t, err, key)
+			assert.Equal(t, value, string(b), key)
+		}
+		assert.NoFileExists(t, filepath.Join(dir, "a"))
+
+		_, err := s.Get("d")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+	t.Run("keys", func(t *testing.T) {
+		_, s := newPacked(t)
+
+		keys, err := s.Keys()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "c"}, keys)
+	})
+	t.Run("files first", func(t *testing.T) {
+		dir, s := newPacked(t)
+		require.NoError(t, s.Put("a", []byte("new a")))
+
+		b, err := s.Get("a")
+		assert.NoError(t, err)
+		assert.Equal(t, "new a", string(b))
+
+		// repacked, the file replaces the packed value
+		_, err = PackDir(dir, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		b, err = s.Get("a")
+		assert.NoError(t, err)
+		assert.Equal(t, "new a", string(b))
+	})
+	t.Run("recent files are not packed", func(t *testing.T) {
+		dir, _ := newPacked(t)
+		old := time.Now().Add(-time.Hour)
+		require.NoError(t, os.Chtimes(filepath.Join(dir, "c"), old, old))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "d"), []byte("d"), 0644))
+
+		n, err := PackDir(dir, time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.NoFileExists(t, filepath.Join(dir, "c"))
+		assert.FileExists(t, filepath.Join(dir, "d"))
+		names, err := packNames(dir)
+		require.NoError(t, err)
+		assert.Len(t, names, 1, "packs are merged")
+	})
+	t.Run("repacked by another store", func(t *testing.T) {
+		dir, s := newPacked(t)
+		_, err := s.Get("a")
+		require.NoError(t, err)
+
+		_, err = PackDir(dir, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+
+		for _, key := range []string{"a", "c"} {
+			_, err = s.Get(key)
+			assert.NoError(t, err, key)
+		}
+	})
+	t.Run("delete", func(t *testing.T) {
+		_, s := newPacked(t)
+
+		assert.NoError(t, s.Delete("c"))
+		assert.ErrorIs(t, s.Delete("a"), ErrPacked)
+		assert.ErrorIs(t, s.Delete("d"), os.ErrNotExist)
+	})
+	t.Run("path", func(t *testing.T) {
+		dir, s := newPacked(t)
+		names, err := packNames(dir)
+		require.NoError(t, err)
+
+		assert.Equal(t, filepath.Join(dir, names[0]+packSuffix)+"#a", PathOf(s, "a"))
+		assert.Equal(t, filepath.Join(dir, "c"), PathOf(s, "c"))
+	})
+	t.Run("corrupted pack", func(t *testing.T) {
+		dir, s := newPacked(t)
+		names, err := packNames(dir)
+		require.NoError(t, err)
+		path := filepath.Join(dir, names[0]+packSuffix)
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		b[len(packMagic)+4] ^= 0xff
+		require.NoError(t, os.WriteFile(path, b, 0644))
+
+		_, err = s.Get("a")
+		assert.ErrorIs(t, err, ErrCorruptedPack)
+	})
+	t.Run("corrupted index", func(t *testing.T) {
+		dir, s := newPacked(t)
+		names, err := packNames(dir)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, names[0]+idxSuffix), []byte("index"), 0644))
+
+		_, err = s.Keys()
+		assert.ErrorIs(t, err, ErrCorruptedPack)
+	})
+	t.Run("encrypted values", func(t *testing.T) {
+		dir := t.TempDir()
+		s := newTestEncryptedStore(t, NewPackStore(dir), bytes.Repeat([]byte{1}, KeySize))
+		value := []byte(strings.Repeat("a", 1000))
+		require.NoError(t, s.Put("a", value))
+		raw, err := os.ReadFile(filepath.Join(dir, "a"))
+		require.NoError(t, err)
+
+		_, err = PackDir(dir, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		b, err := s.Get("a")
+		assert.NoError(t, err)
+		assert.Equal(t, value, b)
+
+		names, err := packNames(dir)
+		require.NoError(t, err)
+		info, err := os.Stat(filepath.Join(dir, names[0]+packSuffix))
+		require.NoError(t, err)
+		assert.Less(t, info.Size(), int64(len(packMagic)+len(raw)+16), "stored without compression")
+	})
+}
@@ -0,0 +1,237 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Store is the storage layer of the mitrack databases: a flat key->bytes
+// store where each record (account, transaction) is stored under its key.
+//
+// Keys starting with a dot are metadata (ex: .dbinfo), they are not
+// returned by Keys.
+type Store interface {
+	// Get returns the bytes stored under key.
+	// The returned error satisfies errors.Is(err, os.ErrNotExist)
+	// when nothing is stored under key.
+	Get(key string) ([]byte, error)
+
+	// Put stores b under key, replacing any previous value.
+	Put(key string, b []byte) error
+
+	// Delete removes the value stored under key.
+	Delete(key string) error
+
+	// Keys returns the keys of all records, in lexical order.
+	Keys() ([]string, error)
+}
+
+// NewDirStore returns a Store keeping each value in a file of dir,
+// named after its key.
+func NewDirStore(dir string) Store {
+	return &dirStore{dir: dir}
+}
+
+type dirStore struct {
+	dir string
+}
+
+// tmpSuffix is the suffix of the temporary files used by Put.
+const tmpSuffix = ".tmp"
+
+func (s *dirStore) Get(key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// Put writes b into a temporary file, then renames it, so that a
+// crash never leaves a partially written record, and concurrent Puts of
+// the same key never mix their values.
+func (s *dirStore) Put(key string, b []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(s.dir, key+".*"+tmpSuffix)
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (s *dirStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (s *dirStore) Keys() ([]string, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(dirEntries))
+	for _, entry := range dirEntries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, tmpSuffix) {
+			continue
+		}
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Path returns the path of the file of key.
+func (s *dirStore) Path(key string) string {
+	return filepath.Join(s.dir, key)
+}
+
+// path returns the path of the file of key, checking that key can be used
+// as a file name.
+func (s *dirStore) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || key == "." || key == ".." {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
+
+// Stat returns the os.FileInfo of the file of key.
+func (s *dirStore) Stat(key string) (os.FileInfo, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(path)
+}
+
+// StatOf returns the os.FileInfo of the file holding the value of key in s.
+// It returns ErrNoFile if s does not keep values in files.
+func StatOf(s Store, key string) (os.FileInfo, error) {
+	if st, ok := s.(interface {
+		Stat(key string) (os.FileInfo, error)
+	}); ok {
+		return st.Stat(key)
+	}
+	return nil, ErrNoFile
+}
+
+// PathOf returns the path of the file holding the value of key in s, or
+// key itself if s does not keep values in files.
+func PathOf(s Store, key string) string {
+	if p, ok := s.(interface{ Path(key string) string }); ok {
+		return p.Path(key)
+	}
+	return key
+}
+
+// Copy copies every record of src into dst.
+// Metadata (keys starting with a dot) are not copied, except the ones
+// registered by RegisterPrivateMetadata.
+func Copy(dst, src Store) error {
+	keys, err := src.Keys()
+	if err != nil {
+		return err
+	}
+	privateMetadataMu.RLock()
+	for key := range privateMetadata {
+		keys = append(keys, key)
+	}
+	privateMetadataMu.RUnlock()
+
+	for _, key := range keys {
+		b, err := src.Get(key)
+		if isPrivateMetadata(key) && errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if err := dst.Put(key, b); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+var (
+	// ErrInvalidKey is returned when a key can not be used as a file name.
+	ErrInvalidKey = errors.New("invalid key")
+
+	// ErrNoFile is returned by StatOf when a store does not keep values
+	// in files.
+	ErrNoFile = errors.New("store does not keep values in files")
+)
+
+// ListError is returned when some records could not be read while listing
+// the records of a store. The records which could be read are still
+// returned along with it.
+type ListError struct {
+	// Failures are the errors of the records which could not be read,
+	// in the order of their keys.
+	Failures []*RecordError
+}
+
+func (e *ListError) Error() string {
+	if len(e.Failures) == 1 {
+		return e.Failures[0].Error()
+	}
+	msgs := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		msgs[i] = f.Error()
+	}
+	return fmt.Sprintf("%d records could not be read: %s", len(e.Failures), strings.Join(msgs, "; "))
+}
+
+// Add adds the failure of the record of the given key.
+func (e *ListError) Add(key string, err error) {
+	e.Failures = append(e.Failures, &RecordError{Key: key, Err: err})
+}
+
+// ErrOrNil returns e if it has failures, nil otherwise.
+func (e *ListError) ErrOrNil() error {
+	if len(e.Failures) == 0 {
+		return nil
+	}
+	return e
+}
+
+// RecordError is the error of a single record.
+type RecordError struct {
+	Key string
+	Err error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
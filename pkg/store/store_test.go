@@ -0,0 +1,109 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirStore(t *testing.T) {
+	t.Run("put and get", func(t *testing.T) {
+		dir := t.TempDir()
+		s := NewDirStore(dir)
+
+		require.NoError(t, s.Put("abcd", []byte("record")))
+
+		b, err := s.Get("abcd")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("record"), b)
+		assert.FileExists(t, filepath.Join(dir, "abcd"))
+	})
+	t.Run("not existing", func(t *testing.T) {
+		s := NewDirStore(t.TempDir())
+
+		_, err := s.Get("abcd")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+	t.Run("keys skip metadata", func(t *testing.T) {
+		s := NewDirStore(t.TempDir())
+		for _, key := range []string{"b", ".dbinfo", "a"} {
+			require.NoError(t, s.Put(key, []byte(key)))
+		}
+
+		keys, err := s.Keys()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "b"}, keys)
+	})
+	t.Run("delete", func(t *testing.T) {
+		s := NewDirStore(t.TempDir())
+		require.NoError(t, s.Put("a", []byte("a")))
+
+		assert.NoError(t, s.Delete("a"))
+
+		_, err := s.Get("a")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+	t.Run("invalid key", func(t *testing.T) {
+		s := NewDirStore(t.TempDir())
+
+		assert.ErrorIs(t, s.Put("../a", []byte("a")), ErrInvalidKey)
+	})
+}
+
+func TestCopy(t *testing.T) {
+	src := NewDirStore(t.TempDir())
+	dst := NewDirStore(t.TempDir())
+	require.NoError(t, src.Put("a", []byte("1")))
+	require.NoError(t, src.Put("b", []byte("2")))
+
+	require.NoError(t, Copy(dst, src))
+
+	keys, err := dst.Keys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, keys)
+}
+
+func TestListError(t *testing.T) {
+	listErr := &ListError{}
+	assert.NoError(t, listErr.ErrOrNil())
+
+	listErr.Add("abcd", os.ErrPermission)
+	err := listErr.ErrOrNil()
+	require.Error(t, err)
+	assert.Equal(t, "abcd: permission denied", err.Error())
+
+	listErr.Add("efgh", ErrDecrypt)
+	assert.Equal(t, "2 records could not be read: abcd: permission denied; efgh: could not decrypt record", err.Error())
+	assert.ErrorIs(t, listErr.Failures[0], os.ErrPermission)
+}
+
+func TestLockOf(t *testing.T) {
+	dir := t.TempDir()
+	unlock, err := LockOf(NewDirStore(dir))
+	require.NoError(t, err)
+
+	locked := make(chan struct{})
+	go func() {
+		// as another process would, through another store
+		unlock, err := LockOf(NewDirStore(dir))
+		assert.NoError(t, err)
+		close(locked)
+		assert.NoError(t, unlock())
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("locked twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+	require.NoError(t, unlock())
+	<-locked
+
+	keys, err := NewDirStore(dir).Keys()
+	require.NoError(t, err)
+	assert.Empty(t, keys, "the lock file is not a key")
+}
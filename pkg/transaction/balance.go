@@ -0,0 +1,35 @@
+package transaction
+
+import "github.com/fitiavana07/mitrack/pkg/account"
+
+// Balances returns the balances of the accounts of the entries of txs, by
+// ID: their debits minus their credits.
+func Balances(txs []Transaction) map[account.ID]int64 {
+	balances := map[account.ID]int64{}
+	for _, tx := range txs {
+		for _, e := range tx.Entries() {
+			balances[e.AccountID()] += signed(e)
+		}
+	}
+	return balances
+}
+
+// Change returns the debits minus the credits of the entries of tx on the
+// accounts of ids.
+func Change(tx Transaction, ids map[account.ID]bool) int64 {
+	var change int64
+	for _, e := range tx.Entries() {
+		if ids[e.AccountID()] {
+			change += signed(e)
+		}
+	}
+	return change
+}
+
+// signed returns the amount of e, negative for a credit.
+func signed(e Entry) int64 {
+	if e.Operation() == OpCredit {
+		return -e.Amount()
+	}
+	return e.Amount()
+}
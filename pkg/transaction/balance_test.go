@@ -0,0 +1,23 @@
+package transaction
+
+import (
+	"testing"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalances(t *testing.T) {
+	cash, bank, food := account.ID{1}, account.ID{2}, account.ID{3}
+	txs := []Transaction{
+		&transaction{entries: []Entry{NewEntry(OpDebit, cash, 1000), NewEntry(OpCredit, bank, 1000)}},
+		&transaction{entries: []Entry{NewEntry(OpDebit, food, 300), NewEntry(OpCredit, cash, 200), NewEntry(OpCredit, bank, 100)}},
+	}
+
+	assert.Equal(t, map[account.ID]int64{cash: 800, bank: -1100, food: 300}, Balances(txs))
+
+	assert.Equal(t, int64(-200), Change(txs[1], map[account.ID]bool{cash: true}))
+	assert.Equal(t, int64(-300), Change(txs[1], map[account.ID]bool{cash: true, bank: true}))
+	assert.Equal(t, int64(0), Change(txs[1], map[account.ID]bool{food: true, cash: true, bank: true}), "balanced")
+	assert.Equal(t, int64(0), Change(txs[0], map[account.ID]bool{food: true}))
+}
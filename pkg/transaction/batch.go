@@ -0,0 +1,208 @@
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/store"
+)
+
+// A batch is committed through a journal: its records are first written
+// together into the batchJournalKey metadata, which the store writes
+// atomically, then each one under its key. The journal is removed once they
+// are all written and indexed, or all removed. A journal left by a crash,
+// or by a failure to remove the records, is replayed when the service is
+// created: the batch is then recorded. The journal is encrypted like the
+// transactions are, and the commits and replays hold the lock of the store
+// (see store.LockOf), as the processes sharing the ledger share it.
+const batchJournalKey = ".batch"
+
+func init() {
+	store.RegisterPrivateMetadata(batchJournalKey)
+}
+
+// Batch is a batch of transactions, recorded together by Commit: either
+// all of them are, or none. Each transaction is checked when added to the
+// batch. A Batch is not safe for concurrent use.
+type Batch struct {
+	s       *txService
+	txs     []*transaction
+	records [][]byte
+}
+
+func (s *txService) Begin() *Batch {
+	return &Batch{s: s}
+}
+
+// RecordFromMapsAt adds to the batch the transaction given by the maps,
+// dated at t, as TxService.RecordFromMapsAt would record it.
+func (b *Batch) RecordFromMapsAt(ctx context.Context, t time.Time, note string, debitsMap, creditsMap map[string]int64) (Transaction, error) {
+	tx, record, err := b.s.prepare(ctx, t, note, debitsMap, creditsMap)
+	if err != nil {
+		return nil, err
+	}
+	b.txs = append(b.txs, tx)
+	b.records = append(b.records, record)
+	return tx, nil
+}
+
+// Transactions returns the transactions of the batch, in the order they
+// were added.
+func (b *Batch) Transactions() []Transaction {
+	txs := make([]Transaction, len(b.txs))
+	for i, tx := range b.txs {
+		txs[i] = tx
+	}
+	return txs
+}
+
+// Len returns the number of transactions of the batch.
+func (b *Batch) Len() int {
+	return len(b.txs)
+}
+
+// Commit records the transactions of the batch. If one of them can not be
+// written, the ones already written are removed and the batch is kept, to
+// be committed again or rolled back; if they can not be removed, the
+// batch is recorded when the service is created again. The batch is
+// emptied once committed.
+func (b *Batch) Commit(ctx context.Context) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s := b.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := store.LockOf(s.store)
+	if err != nil {
+		return fmt.Errorf("transaction.service: could not lock transactions, batch not recorded: %w", err)
+	}
+	defer unlock()
+
+	// the transactions recorded before the batch are not removed on error
+	existed := make([]bool, len(b.txs))
+	for i, tx := range b.txs {
+		_, err := s.store.Get(fmt.Sprintf("%x", tx.hash))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("transaction.service: could not read transaction file, batch not recorded: %w", err)
+		}
+		existed[i] = err == nil
+	}
+
+	if err := s.store.Put(batchJournalKey, encodeJournal(b.records)); err != nil {
+		return fmt.Errorf("transaction.service: could not write batch journal, batch not recorded: %w", err)
+	}
+
+	written := []string{}
+	for i, tx := range b.txs {
+		key := fmt.Sprintf("%x", tx.hash)
+		if err := s.store.Put(key, b.records[i]); err != nil {
+			for _, key := range written {
+				if deleteErr := s.store.Delete(key); deleteErr != nil {
+					// replayed by the next service
+					return fmt.Errorf("transaction.service: could not write transaction file, batch recorded when opened again: %w", err)
+				}
+			}
+			s.store.Delete(batchJournalKey)
+			return fmt.Errorf("transaction.service: could not write transaction file, batch not recorded: %w", err)
+		}
+		if !existed[i] {
+			written = append(written, key)
+		}
+	}
+
+	for _, tx := range b.txs {
+		if indexErr := s.addToIndexes(tx); err == nil {
+			err = indexErr
+		}
+	}
+	if err == nil {
+		// replaying it would only rewrite the same records
+		s.store.Delete(batchJournalKey)
+	}
+	b.Rollback()
+	return err
+}
+
+// Rollback empties the batch, its transactions not being recorded.
+func (b *Batch) Rollback() {
+	b.txs, b.records = nil, nil
+}
+
+// replayJournal records the batch of the journal left by a commit which did
+// not complete, if any. A locked store is not replayed: its transactions
+// can not be read either.
+func (s *txService) replayJournal() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := store.LockOf(s.store)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	journal, err := s.store.Get(batchJournalKey)
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, store.ErrLocked) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	records, err := decodeJournal(journal)
+	if err != nil {
+		return fmt.Errorf("corrupted %s: %w", batchJournalKey, err)
+	}
+
+	for _, record := range records {
+		tx, err := Decode(record)
+		if err != nil {
+			return fmt.Errorf("corrupted %s: %w", batchJournalKey, err)
+		}
+		if err := s.store.Put(fmt.Sprintf("%x", tx.Hash()), record); err != nil {
+			return err
+		}
+		if err := s.addToIndexes(tx.(*transaction)); err != nil {
+			return err
+		}
+	}
+	return s.store.Delete(batchJournalKey)
+}
+
+// encodeJournal returns the journal of records: each one prefixed by its
+// length, as a uvarint.
+func encodeJournal(records [][]byte) []byte {
+	var b bytes.Buffer
+	n := make([]byte, binary.MaxVarintLen64)
+	for _, record := range records {
+		b.Write(n[:binary.PutUvarint(n, uint64(len(record)))])
+		b.Write(record)
+	}
+	return b.Bytes()
+}
+
+// decodeJournal returns the records of a journal encoded by encodeJournal.
+func decodeJournal(journal []byte) ([][]byte, error) {
+	r := bytes.NewReader(journal)
+	records := [][]byte{}
+	for r.Len() > 0 {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		record := make([]byte, n)
+		if _, err := io.ReadFull(r, record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
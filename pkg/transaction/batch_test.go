@@ -0,0 +1,162 @@
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingStore fails to put records once puts records were put, and to
+// delete records if failDeletes.
+type failingStore struct {
+	store.Store
+	puts        int
+	failDeletes bool
+}
+
+func (s *failingStore) Put(key string, b []byte) error {
+	if strings.HasPrefix(key, ".") {
+		return s.Store.Put(key, b)
+	}
+	if s.puts == 0 {
+		return errors.New("disk full")
+	}
+	s.puts--
+	return s.Store.Put(key, b)
+}
+
+func (s *failingStore) Delete(key string) error {
+	if s.failDeletes && !strings.HasPrefix(key, ".") {
+		return errors.New("read-only")
+	}
+	return s.Store.Delete(key)
+}
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	accService, cleanup := createTestAccService(t, t.TempDir())
+	defer cleanup()
+	cash := account.NewAccount("Cash", account.TypeAsset)
+	require.NoError(t, accService.Register(cash))
+	food := account.NewAccount("Food", account.TypeExpense)
+	require.NoError(t, accService.Register(food))
+
+	st := &failingStore{Store: store.NewDirStore(t.TempDir()), puts: 1}
+	indexStore := store.NewDirStore(t.TempDir())
+	s, err := NewTxServiceWithIndex(st, indexStore, accService)
+	require.NoError(t, err)
+	defer s.Cleanup()
+
+	record := func(b *Batch, note string) Transaction {
+		tx, err := b.RecordFromMapsAt(ctx, day, note, map[string]int64{food.Alias: 100}, map[string]int64{cash.Alias: 100})
+		require.NoError(t, err)
+		return tx
+	}
+	count := func() uint64 {
+		n, err := s.Count()
+		require.NoError(t, err)
+		return n
+	}
+
+	t.Run("rollback", func(t *testing.T) {
+		b := s.Begin()
+		record(b, "lunch")
+		assert.Equal(t, 1, b.Len())
+		b.Rollback()
+		assert.Equal(t, 0, b.Len())
+		assert.Equal(t, uint64(0), count())
+	})
+
+	t.Run("checked when added", func(t *testing.T) {
+		b := s.Begin()
+		_, err := b.RecordFromMapsAt(ctx, day, "lunch", map[string]int64{food.Alias: 100}, map[string]int64{cash.Alias: 90})
+		var unbalanced *UnbalancedError
+		assert.ErrorAs(t, err, &unbalanced)
+		_, err = b.RecordFromMapsAt(ctx, day, "lunch", map[string]int64{"nope": 100}, map[string]int64{cash.Alias: 100})
+		assert.Error(t, err)
+		assert.Equal(t, 0, b.Len())
+	})
+
+	t.Run("none recorded if one fails", func(t *testing.T) {
+		b := s.Begin()
+		record(b, "lunch")
+		record(b, "dinner")
+		assert.EqualError(t, b.Commit(ctx), "transaction.service: could not write transaction file, batch not recorded: disk full")
+		assert.Equal(t, uint64(0), count())
+		assert.Equal(t, 2, b.Len(), "kept to be committed again")
+	})
+
+	t.Run("committed", func(t *testing.T) {
+		st.puts = 2
+		b := s.Begin()
+		lunch := record(b, "lunch")
+		dinner := record(b, "dinner")
+		assert.Equal(t, []Transaction{lunch, dinner}, b.Transactions())
+		require.NoError(t, b.Commit(ctx))
+		assert.Equal(t, 0, b.Len())
+
+		assert.Equal(t, uint64(2), count())
+		got, err := s.Get(fmt.Sprintf("%x", lunch.Hash()))
+		require.NoError(t, err)
+		assert.Equal(t, "lunch", got.Note())
+		txs := collect(t, s.Iter(ctx, Filter{Accounts: []account.ID{food.ID}}))
+		assert.Equal(t, txKeys(lunch, dinner), txKeys(txs...), "indexed")
+	})
+	t.Run("recorded transactions kept if one fails", func(t *testing.T) {
+		st.puts = 2
+		b := s.Begin()
+		supper := record(b, "supper")
+		lunch := record(b, "lunch")
+		record(b, "snack")
+		require.Error(t, b.Commit(ctx))
+
+		assert.Equal(t, uint64(2), count())
+		_, err := s.Get(fmt.Sprintf("%x", lunch.Hash()))
+		assert.NoError(t, err, "recorded before the batch")
+		_, err = s.Get(fmt.Sprintf("%x", supper.Hash()))
+		assert.ErrorIs(t, err, ErrTxNotFound)
+	})
+	t.Run("recorded when opened again if not removed", func(t *testing.T) {
+		st.puts, st.failDeletes = 1, true
+		b := s.Begin()
+		breakfast := record(b, "breakfast")
+		brunch := record(b, "brunch")
+		err := b.Commit(ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "batch recorded when opened again")
+		assert.Equal(t, uint64(3), count(), "partially written")
+
+		st.puts, st.failDeletes = 2, false
+		var reopened TxService
+		reopened, err = NewTxServiceWithIndex(st, indexStore, accService)
+		require.NoError(t, err)
+		defer reopened.Cleanup()
+		_, err = st.Get(batchJournalKey)
+		assert.ErrorIs(t, err, os.ErrNotExist, "replayed once")
+		assert.Equal(t, uint64(4), count())
+		txs := collect(t, reopened.Iter(ctx, Filter{Accounts: []account.ID{food.ID}, Note: "br"}))
+		assert.Equal(t, txKeys(breakfast, brunch), txKeys(txs...), "indexed")
+	})
+}
+
+func TestBatchJournal(t *testing.T) {
+	records := [][]byte{[]byte("lunch"), {}, bytes.Repeat([]byte("x"), 300)}
+	got, err := decodeJournal(encodeJournal(records))
+	require.NoError(t, err)
+	assert.Equal(t, records, got)
+
+	_, err = decodeJournal(encodeJournal(records)[:10])
+	assert.Error(t, err)
+}
@@ -0,0 +1,134 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run with -race.
+func TestTxServiceConcurrency(t *testing.T) {
+	ctx := context.Background()
+
+	accService, cleanup := createTestAccService(t, t.TempDir())
+	defer cleanup()
+	s, err := NewTxServiceWithIndex(store.NewDirStore(t.TempDir()), store.NewDirStore(t.TempDir()), accService)
+	require.NoError(t, err)
+	defer s.Cleanup()
+
+	cash := account.NewAccount("Cash", account.TypeAsset)
+	require.NoError(t, accService.Register(cash))
+	equity := account.NewAccount("Equity", account.TypeEquity)
+	require.NoError(t, accService.Register(equity))
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(4)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.RecordFromMaps(fmt.Sprintf("tx %d", i), map[string]int64{cash.Alias: 10}, map[string]int64{equity.Alias: 10})
+			assert.NoError(t, err)
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, accService.Register(account.NewAccount(fmt.Sprintf("Account %d", i), account.TypeExpense)))
+		}(i)
+		go func() {
+			defer wg.Done()
+			_, err := s.List()
+			assert.NoError(t, err)
+		}()
+		go func(i int) {
+			defer wg.Done()
+			if i%5 == 0 {
+				assert.NoError(t, s.Reindex(ctx))
+			} else {
+				collect(t, s.Iter(ctx, Filter{Accounts: []account.ID{cash.ID}}))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	txs, err := s.List()
+	require.NoError(t, err)
+	assert.Len(t, txs, n)
+
+	indexed := collect(t, s.Iter(ctx, Filter{Accounts: []account.ID{equity.ID}}))
+	assert.Len(t, indexed, n, "every transaction must be indexed")
+}
+
+// The services of the processes sharing a ledger do not share their mutex.
+func TestTxServiceSharedLedger(t *testing.T) {
+	ctx := context.Background()
+	accService, cleanup := createTestAccService(t, t.TempDir())
+	defer cleanup()
+	cash := account.NewAccount("Cash", account.TypeAsset)
+	require.NoError(t, accService.Register(cash))
+	equity := account.NewAccount("Equity", account.TypeEquity)
+	require.NoError(t, accService.Register(equity))
+
+	txDir, indexDir := t.TempDir(), t.TempDir()
+	services := make([]TxService, 4)
+	for i := range services {
+		s, err := NewTxServiceWithIndex(store.NewDirStore(txDir), store.NewDirStore(indexDir), accService)
+		require.NoError(t, err)
+		defer s.Cleanup()
+		services[i] = s
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i, s := range services {
+		for j := 0; j < n; j++ {
+			wg.Add(1)
+			go func(s TxService, note string) {
+				defer wg.Done()
+				_, err := s.RecordFromMaps(note, map[string]int64{cash.Alias: 10}, map[string]int64{equity.Alias: 10})
+				assert.NoError(t, err)
+			}(s, fmt.Sprintf("tx %d-%d", i, j))
+		}
+	}
+	wg.Wait()
+
+	indexed := collect(t, services[0].Iter(ctx, Filter{Accounts: []account.ID{cash.ID}}))
+	assert.Len(t, indexed, len(services)*n, "no index update lost")
+	count, err := services[0].CountByDate(time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(len(services)*n), count)
+}
+
+func TestTxServiceContext(t *testing.T) {
+	accService, cleanup := createTestAccService(t, t.TempDir())
+	defer cleanup()
+	s, cleanup := createTestTxService(t, t.TempDir(), accService)
+	defer cleanup()
+
+	cash := account.NewAccount("Cash", account.TypeAsset)
+	require.NoError(t, accService.Register(cash))
+	equity := account.NewAccount("Equity", account.TypeEquity)
+	require.NoError(t, accService.Register(equity))
+	_, err := s.RecordFromMaps("initial", map[string]int64{cash.Alias: 10}, map[string]int64{equity.Alias: 10})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = s.RecordFromMapsContext(ctx, "canceled", map[string]int64{cash.Alias: 10}, map[string]int64{equity.Alias: 10})
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = s.ListContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = s.CountByDateContext(ctx, time.Time{}, time.Now())
+	assert.ErrorIs(t, err, context.Canceled)
+
+	count, err := s.Count()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), count, "nothing must be recorded once ctx is done")
+}
@@ -0,0 +1,122 @@
+package transaction
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+func (s *txService) Count() (uint64, error) {
+	keys, err := s.store.Keys()
+	if err != nil {
+		return 0, fmt.Errorf("transaction.service: could not count transactions: %w", err)
+	}
+
+	var count uint64
+	for _, key := range keys {
+		if b, err := hex.DecodeString(key); err == nil && len(b) == sha256.Size {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *txService) CountByDate(from, to time.Time) (uint64, error) {
+	return s.CountByDateContext(context.Background(), from, to)
+}
+
+func (s *txService) CountByDateContext(ctx context.Context, from, to time.Time) (uint64, error) {
+	f := Filter{From: from, To: to}
+
+	if s.index != nil {
+		built, err := s.index.built()
+		if err != nil {
+			return 0, fmt.Errorf("transaction.service: could not count transactions: %w", err)
+		}
+		if built {
+			count, err := s.countIndexed(ctx, f)
+			if err != nil {
+				return 0, fmt.Errorf("transaction.service: could not count transactions: %w", err)
+			}
+			return count, nil
+		}
+	}
+
+	var count uint64
+	it := s.Iter(ctx, f)
+	defer it.Close()
+	for it.Next() {
+		count++
+	}
+	return count, it.Err()
+}
+
+// countIndexed counts the transactions in the date range of f using the
+// date index: only the transactions of the days partially in the range
+// are read. The index entries of the transactions which no longer exist
+// are skipped.
+func (s *txService) countIndexed(ctx context.Context, f Filter) (uint64, error) {
+	days, err := s.index.days()
+	if err != nil {
+		return 0, err
+	}
+	keys, err := s.store.Keys()
+	if err != nil {
+		return 0, err
+	}
+	exists := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		exists[key] = true
+	}
+
+	var count uint64
+	for _, d := range days {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		if !f.matchDay(d) {
+			continue
+		}
+		key := dateIndexPrefix + d
+		hashes, err := s.index.get(key)
+		if err != nil {
+			return 0, err
+		}
+
+		day, err := time.Parse(dateIndexLayout, d)
+		if err != nil {
+			return 0, fmt.Errorf("invalid date index %s: %w", key, err)
+		}
+		whole := f.matchTimestamp(day.Unix()) && f.matchTimestamp(day.AddDate(0, 0, 1).Unix()-1)
+
+		for i := 0; i < len(hashes); i += sha256.Size {
+			txKey := hex.EncodeToString(hashes[i : i+sha256.Size])
+			if !exists[txKey] {
+				// stale index entry
+				continue
+			}
+			if whole {
+				count++
+				continue
+			}
+			b, err := s.store.Get(txKey)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			} else if err != nil {
+				return 0, err
+			}
+			tx, err := Decode(b)
+			if err != nil {
+				return 0, err
+			}
+			if f.matchTimestamp(tx.Timestamp()) {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
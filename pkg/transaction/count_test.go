@@ -0,0 +1,83 @@
+package transaction
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxServiceCount(t *testing.T) {
+	at := func(d, h int) time.Time {
+		return time.Date(2022, time.March, d, h, 0, 0, 0, time.UTC)
+	}
+	day := func(d int) time.Time {
+		return at(d, 0)
+	}
+
+	accService, cleanup := createTestAccService(t, t.TempDir())
+	defer cleanup()
+
+	st := &countingStore{store.NewDirStore(t.TempDir()), map[string]int{}}
+	entries := []Entry{NewEntry(OpDebit, account.ID{1}, 10), NewEntry(OpCredit, account.ID{2}, 10)}
+	putTestTx(t, st, at(1, 9), "a", entries...)
+	putTestTx(t, st, at(1, 18), "b", entries...)
+	putTestTx(t, st, at(2, 9), "c", entries...)
+	d := putTestTx(t, st, at(5, 9), "d", entries...)
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		expected uint64
+	}{
+		{"all", time.Time{}, time.Time{}, 4},
+		{"whole days", day(1), day(3), 3},
+		{"from", day(2), time.Time{}, 2},
+		{"to", time.Time{}, day(2), 2},
+		{"partial day", at(1, 12), day(5), 2},
+		{"empty", day(3), day(5), 0},
+	}
+
+	for _, indexed := range []bool{false, true} {
+		s, err := NewTxServiceWithIndex(st, store.NewDirStore(t.TempDir()), accService)
+		require.NoError(t, err)
+		name := "not indexed"
+		if indexed {
+			name = "indexed"
+			require.NoError(t, s.Reindex(context.Background()))
+		}
+
+		t.Run(name, func(t *testing.T) {
+			count, err := s.Count()
+			require.NoError(t, err)
+			assert.Equal(t, uint64(4), count)
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					st.readKeys()
+					count, err := s.CountByDate(tt.from, tt.to)
+					require.NoError(t, err)
+					assert.Equal(t, tt.expected, count)
+					if indexed && tt.name != "partial day" {
+						assert.Empty(t, st.readKeys(), "whole days must be counted without reading transactions")
+					}
+				})
+			}
+		})
+	}
+
+	t.Run("stale entries are skipped", func(t *testing.T) {
+		s, err := NewTxServiceWithIndex(st, store.NewDirStore(t.TempDir()), accService)
+		require.NoError(t, err)
+		require.NoError(t, s.Reindex(context.Background()))
+		require.NoError(t, st.Delete(txKeys(d)[0]))
+
+		count, err := s.CountByDate(day(1), day(6))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(3), count)
+	})
+}
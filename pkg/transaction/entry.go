@@ -1,6 +1,11 @@
 package transaction
 
-import "github.com/fitiavana07/mitrack/pkg/account"
+import (
+	"io"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/encoding"
+)
 
 // Entry is a transaction entry. It may be a debit or a credit entry.
 type Entry interface {
@@ -36,3 +41,33 @@ func (e *entry) AccountID() account.ID {
 func (e *entry) Amount() int64 {
 	return e.amount
 }
+
+// fields returns pointers to the encoded fields of the entry, in the order
+// they are stored.
+func (e *entry) fields() []interface{} {
+	return []interface{}{
+		&e.operation,
+		&e.accountID,
+		&e.amount,
+	}
+}
+
+// MarshalEncoded implements encoding.Marshaler.
+func (e *entry) MarshalEncoded(w io.Writer, enc encoding.Encoder) error {
+	for _, v := range e.fields() {
+		if err := enc.WriteEncoded(w, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalDecoded implements encoding.Unmarshaler.
+func (e *entry) UnmarshalDecoded(r io.Reader, dec encoding.Decoder) error {
+	for _, v := range e.fields() {
+		if err := dec.ReadDecoded(r, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
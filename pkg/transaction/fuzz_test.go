@@ -0,0 +1,80 @@
+package transaction
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/encoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func FuzzGetByHash(f *testing.F) {
+	cash := account.NewAccount("Cash in Wallet", account.TypeAsset)
+	tx := &transaction{
+		timestamp: 1617000000,
+		note:      "naka vola",
+		entries:   []Entry{NewEntry(OpDebit, cash.ID, 900), NewEntry(OpCredit, cash.ID, 900)},
+	}
+	for _, version := range []uint32{encoding.FormatVersionV3, encoding.FormatVersionV4} {
+		b := new(bytes.Buffer)
+		rw, err := encoding.NewRecordWriter(b, version)
+		require.NoError(f, err)
+		require.NoError(f, rw.WriteEncoded(tx))
+		require.NoError(f, rw.Close())
+		f.Add(b.Bytes())
+	}
+
+	accService, cleanup := createTestAccService(f, f.TempDir())
+	defer cleanup()
+
+	txDir := f.TempDir()
+	s, cleanup := createTestTxService(f, txDir, accService)
+	defer cleanup()
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		hash := fmt.Sprintf("%x", sha256.Sum256(b))
+		path := filepath.Join(txDir, hash)
+		require.NoError(t, os.WriteFile(path, b, 0644))
+		defer os.Remove(path)
+
+		// must not panic, whatever the content of the file
+		s.GetByHash(hash)
+	})
+}
+
+func FuzzRecordFromMapsRoundTrip(f *testing.F) {
+	f.Add("naka vola sabotsy namehana", int64(900))
+	f.Add("", int64(0))
+	f.Add("", int64(-900))
+
+	accService, cleanup := createTestAccService(f, f.TempDir())
+	defer cleanup()
+
+	s, cleanup := createTestTxService(f, f.TempDir(), accService)
+	defer cleanup()
+
+	cash := account.NewAccount("Cash in Wallet", account.TypeAsset)
+	require.NoError(f, accService.Register(cash))
+	checking := account.NewAccount("Checking Account", account.TypeAsset)
+	require.NoError(f, accService.Register(checking))
+
+	f.Fuzz(func(t *testing.T, note string, amount int64) {
+		tx, err := s.RecordFromMaps(note, map[string]int64{cash.Alias: amount}, map[string]int64{checking.Alias: amount})
+		if amount <= 0 {
+			assert.ErrorIs(t, err, ErrInvalidAmount)
+			return
+		}
+		require.NoError(t, err)
+
+		got, err := s.GetByHash(fmt.Sprintf("%x", tx.Hash()))
+
+		require.NoError(t, err)
+		assert.Equal(t, tx, got)
+	})
+}
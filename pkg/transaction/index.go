@@ -0,0 +1,434 @@
+package transaction
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/store"
+)
+
+// The indexes map the date of the transactions (UTC day) and the accounts
+// of their entries to their hashes, so that Iter only reads the transactions
+// which may match its filter, and iterates day by day when ordering by date.
+//
+// They are kept in their own store, under the keys:
+//
+//	date-YYYYMMDD  the hashes of the transactions of that day
+//	account-<ID>   the hashes of the transactions having an entry on the account
+//
+// each value being the concatenation of the raw hashes.
+// When the store is a store.KeyHasher (ie: encrypted), these keys would
+// reveal the activity of the ledger: they are stored as ix-<hashed key>
+// instead, and the days having transactions, which can no longer be told
+// from the keys, are kept under the days key, as concatenated YYYYMMDD.
+//
+// The indexes are only used once built (by Reindex), which is recorded by
+// the indexInfoKey metadata. As a transaction is indexed after having been
+// stored, an index may miss a transaction after a crash: Reindex fixes it.
+// The updates of the indexes read and rewrite their records: they hold the
+// lock of the index store (see store.LockOf), so that the processes
+// recording into the same ledger do not lose each other's updates.
+
+const (
+	indexInfoKey           = ".index"
+	indexInfoContent       = "v1"
+	hashedIndexInfoContent = "v1-hashed"
+
+	dateIndexPrefix    = "date-"
+	accountIndexPrefix = "account-"
+	hashedIndexPrefix  = "ix-"
+	daysIndexKey       = "days"
+	dateIndexLayout    = "20060102"
+)
+
+type txIndex struct {
+	store store.Store
+}
+
+func dateIndexKey(timestamp int64) string {
+	return dateIndexPrefix + time.Unix(timestamp, 0).UTC().Format(dateIndexLayout)
+}
+
+func accountIndexKey(id account.ID) string {
+	return accountIndexPrefix + id.Hex()
+}
+
+// indexKeys returns the index keys of tx.
+func indexKeys(tx Transaction) []string {
+	keys := []string{dateIndexKey(tx.Timestamp())}
+	seen := map[account.ID]bool{}
+	for _, e := range tx.Entries() {
+		if !seen[e.AccountID()] {
+			seen[e.AccountID()] = true
+			keys = append(keys, accountIndexKey(e.AccountID()))
+		}
+	}
+	return keys
+}
+
+// isIndexKey returns whether key is the store key of one of the indexes,
+// as the store may hold other ones.
+func isIndexKey(key string) bool {
+	return strings.HasPrefix(key, dateIndexPrefix) ||
+		strings.HasPrefix(key, accountIndexPrefix) ||
+		strings.HasPrefix(key, hashedIndexPrefix)
+}
+
+// hashed returns whether the keys of the indexes are hashed.
+func (ix *txIndex) hashed() bool {
+	_, ok := ix.store.(store.KeyHasher)
+	return ok
+}
+
+// infoContent returns the content of indexInfoKey once built: the indexes
+// built with plaintext keys are not used once the store is encrypted, and
+// conversely.
+func (ix *txIndex) infoContent() string {
+	if ix.hashed() {
+		return hashedIndexInfoContent
+	}
+	return indexInfoContent
+}
+
+// storeKey returns the key under which the index key is stored.
+func (ix *txIndex) storeKey(key string) (string, error) {
+	h, ok := ix.store.(store.KeyHasher)
+	if !ok {
+		return key, nil
+	}
+	hashed, err := h.HashKey(key)
+	if err != nil {
+		return "", err
+	}
+	return hashedIndexPrefix + hashed, nil
+}
+
+// built returns whether the indexes were built.
+func (ix *txIndex) built() (bool, error) {
+	b, err := ix.store.Get(indexInfoKey)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return string(b) == ix.infoContent(), nil
+}
+
+// add indexes tx.
+func (ix *txIndex) add(tx Transaction) (err error) {
+	unlock, err := store.LockOf(ix.store)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := unlock(); err == nil {
+			err = unlockErr
+		}
+	}()
+
+	hash := tx.Hash()
+	for i, key := range indexKeys(tx) {
+		hashes, err := ix.get(key)
+		if err != nil {
+			return err
+		}
+		if containsHash(hashes, hash) {
+			continue
+		}
+		if err := ix.put(key, append(hashes, hash[:]...)); err != nil {
+			return err
+		}
+		if i == 0 && len(hashes) == 0 && ix.hashed() {
+			// first transaction of the day
+			if err := ix.addDay(strings.TrimPrefix(key, dateIndexPrefix)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// get returns the concatenated hashes stored under key.
+func (ix *txIndex) get(key string) ([]byte, error) {
+	b, err := ix.getRaw(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(b)%sha256.Size != 0 {
+		return nil, fmt.Errorf("corrupted index %s, run reindex", key)
+	}
+	return b, nil
+}
+
+// getRaw returns the value stored under key, or nil if there is none.
+func (ix *txIndex) getRaw(key string) ([]byte, error) {
+	storeKey, err := ix.storeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ix.store.Get(storeKey)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return b, err
+}
+
+// put stores b under key.
+func (ix *txIndex) put(key string, b []byte) error {
+	storeKey, err := ix.storeKey(key)
+	if err != nil {
+		return err
+	}
+	return ix.store.Put(storeKey, b)
+}
+
+// days returns the days (YYYYMMDD) having transactions, sorted.
+func (ix *txIndex) days() ([]string, error) {
+	if !ix.hashed() {
+		keys, err := ix.store.Keys()
+		if err != nil {
+			return nil, err
+		}
+		days := []string{}
+		for _, key := range keys {
+			if strings.HasPrefix(key, dateIndexPrefix) {
+				days = append(days, strings.TrimPrefix(key, dateIndexPrefix))
+			}
+		}
+		return days, nil
+	}
+
+	b, err := ix.getRaw(daysIndexKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(b)%len(dateIndexLayout) != 0 {
+		return nil, fmt.Errorf("corrupted index %s, run reindex", daysIndexKey)
+	}
+	days := make([]string, 0, len(b)/len(dateIndexLayout))
+	for i := 0; i < len(b); i += len(dateIndexLayout) {
+		days = append(days, string(b[i:i+len(dateIndexLayout)]))
+	}
+	return days, nil
+}
+
+// addDay adds day to the days index.
+func (ix *txIndex) addDay(day string) error {
+	days, err := ix.days()
+	if err != nil {
+		return err
+	}
+	i := sort.SearchStrings(days, day)
+	if i < len(days) && days[i] == day {
+		return nil
+	}
+	days = append(days, "")
+	copy(days[i+1:], days[i:])
+	days[i] = day
+	return ix.put(daysIndexKey, []byte(strings.Join(days, "")))
+}
+
+func containsHash(hashes []byte, hash [sha256.Size]byte) bool {
+	for i := 0; i < len(hashes); i += sha256.Size {
+		if string(hashes[i:i+sha256.Size]) == string(hash[:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// candidates returns the keys of the transactions which may match f, or
+// false if the indexes can not narrow the selection down.
+func (ix *txIndex) candidates(f Filter) ([]string, bool, error) {
+	selected, err := ix.accountCandidates(f)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !f.From.IsZero() || !f.To.IsZero() {
+		dates := map[string]bool{}
+		days, err := ix.days()
+		if err != nil {
+			return nil, false, err
+		}
+		for _, day := range days {
+			if f.matchDay(day) {
+				if err := ix.collect(dateIndexPrefix+day, dates); err != nil {
+					return nil, false, err
+				}
+			}
+		}
+		if selected == nil {
+			selected = dates
+		} else {
+			for key := range selected {
+				if !dates[key] {
+					delete(selected, key)
+				}
+			}
+		}
+	}
+
+	if selected == nil {
+		return nil, false, nil
+	}
+	return sortedKeys(selected), true, nil
+}
+
+// accountCandidates returns the keys of the transactions having an entry
+// on one of the accounts of f, or nil if f does not select accounts.
+func (ix *txIndex) accountCandidates(f Filter) (map[string]bool, error) {
+	if len(f.Accounts) == 0 {
+		return nil, nil
+	}
+	selected := map[string]bool{}
+	for _, id := range f.Accounts {
+		if err := ix.collect(accountIndexKey(id), selected); err != nil {
+			return nil, err
+		}
+	}
+	return selected, nil
+}
+
+// dayCandidates returns the keys of the transactions of the day
+// (YYYYMMDD), keeping the ones of accounts only if not nil.
+func (ix *txIndex) dayCandidates(day string, accounts map[string]bool) ([]string, error) {
+	selected := map[string]bool{}
+	if err := ix.collect(dateIndexPrefix+day, selected); err != nil {
+		return nil, err
+	}
+	if accounts != nil {
+		for key := range selected {
+			if !accounts[key] {
+				delete(selected, key)
+			}
+		}
+	}
+	return sortedKeys(selected), nil
+}
+
+func sortedKeys(selected map[string]bool) []string {
+	keys := make([]string, 0, len(selected))
+	for key := range selected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// collect adds to selected the keys of the transactions indexed under key.
+func (ix *txIndex) collect(key string, selected map[string]bool) error {
+	hashes, err := ix.get(key)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(hashes); i += sha256.Size {
+		selected[hex.EncodeToString(hashes[i:i+sha256.Size])] = true
+	}
+	return nil
+}
+
+// matchDay returns whether the day (YYYYMMDD) overlaps the date range
+// of f.
+func (f *Filter) matchDay(day string) bool {
+	if !f.From.IsZero() && day < f.From.UTC().Format(dateIndexLayout) {
+		return false
+	}
+	if !f.To.IsZero() && day > f.To.Add(-time.Second).UTC().Format(dateIndexLayout) {
+		return false
+	}
+	return true
+}
+
+// rebuild replaces the indexes by the ones of the transactions of txs.
+func (ix *txIndex) rebuild(ctx context.Context, txs store.Store) (err error) {
+	unlock, err := store.LockOf(ix.store)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := unlock(); err == nil {
+			err = unlockErr
+		}
+	}()
+
+	if err := ix.store.Delete(indexInfoKey); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	keys, err := txs.Keys()
+	if err != nil {
+		return err
+	}
+	indexes := map[string][]byte{}
+	listErr := &store.ListError{}
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		b, err := txs.Get(key)
+		if err != nil {
+			listErr.Add(key, err)
+			continue
+		}
+		tx, err := Decode(b)
+		if err != nil {
+			listErr.Add(key, err)
+			continue
+		}
+		hash := tx.Hash()
+		for _, indexKey := range indexKeys(tx) {
+			indexes[indexKey] = append(indexes[indexKey], hash[:]...)
+		}
+	}
+	if ix.hashed() {
+		days := []string{}
+		for key := range indexes {
+			if strings.HasPrefix(key, dateIndexPrefix) {
+				days = append(days, strings.TrimPrefix(key, dateIndexPrefix))
+			}
+		}
+		sort.Strings(days)
+		indexes[daysIndexKey] = []byte(strings.Join(days, ""))
+	}
+
+	stored := make(map[string][]byte, len(indexes))
+	for key, b := range indexes {
+		storeKey, err := ix.storeKey(key)
+		if err != nil {
+			return err
+		}
+		stored[storeKey] = b
+	}
+	old, err := ix.store.Keys()
+	if err != nil {
+		return err
+	}
+	for _, key := range old {
+		// including the ones of the keys of before the store was
+		// encrypted or decrypted
+		if _, ok := stored[key]; isIndexKey(key) && !ok {
+			if err := ix.store.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+	for key, b := range stored {
+		if err := ix.store.Put(key, b); err != nil {
+			return err
+		}
+	}
+
+	if err := ix.store.Put(indexInfoKey, []byte(ix.infoContent())); err != nil {
+		return err
+	}
+	return listErr.ErrOrNil()
+}
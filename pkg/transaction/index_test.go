@@ -0,0 +1,175 @@
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStore counts the records read from a store.
+type countingStore struct {
+	store.Store
+	gets map[string]int
+}
+
+func (s *countingStore) Get(key string) ([]byte, error) {
+	s.gets[key]++
+	return s.Store.Get(key)
+}
+
+// readKeys returns the keys of the records read since the last call.
+func (s *countingStore) readKeys() []string {
+	keys := []string{}
+	for key := range s.gets {
+		if key != dbInfoFileName {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	s.gets = map[string]int{}
+	return keys
+}
+
+func txKeys(txs ...Transaction) []string {
+	keys := []string{}
+	for _, tx := range txs {
+		keys = append(keys, fmt.Sprintf("%x", tx.Hash()))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestTxServiceIndex(t *testing.T) {
+	ctx := context.Background()
+	day := func(d int) time.Time {
+		return time.Date(2022, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	accService, cleanup := createTestAccService(t, t.TempDir())
+	defer cleanup()
+	cash := account.NewAccount("Cash", account.TypeAsset)
+	require.NoError(t, accService.Register(cash))
+	bank := account.NewAccount("Bank", account.TypeAsset)
+	require.NoError(t, accService.Register(bank))
+	food := account.NewAccount("Food", account.TypeExpense)
+	require.NoError(t, accService.Register(food))
+
+	t.Run("updated when recording", func(t *testing.T) {
+		st := &countingStore{store.NewDirStore(t.TempDir()), map[string]int{}}
+		s, err := NewTxServiceWithIndex(st, store.NewDirStore(t.TempDir()), accService)
+		require.NoError(t, err)
+
+		withdraw, err := s.RecordFromMaps("withdraw", map[string]int64{cash.Alias: 20}, map[string]int64{bank.Alias: 20})
+		require.NoError(t, err)
+		lunch, err := s.RecordFromMaps("lunch", map[string]int64{food.Alias: 5}, map[string]int64{cash.Alias: 5})
+		require.NoError(t, err)
+		st.readKeys()
+
+		assert.Equal(t, []Transaction{lunch}, collect(t, s.Iter(ctx, Filter{Accounts: []account.ID{food.ID}})))
+		assert.Equal(t, txKeys(lunch), st.readKeys(), "only the transactions of the account must be read")
+
+		txs := collect(t, s.Iter(ctx, Filter{Accounts: []account.ID{cash.ID}}))
+		assert.ElementsMatch(t, []Transaction{withdraw, lunch}, txs)
+	})
+
+	t.Run("built by reindex", func(t *testing.T) {
+		dir := t.TempDir()
+		st := &countingStore{store.NewDirStore(dir), map[string]int{}}
+
+		// recorded before the indexes existed
+		salary := putTestTx(t, st, day(1), "salary", NewEntry(OpDebit, bank.ID, 100), NewEntry(OpCredit, account.ID{9}, 100))
+		withdraw := putTestTx(t, st, day(10), "withdraw", NewEntry(OpDebit, cash.ID, 20), NewEntry(OpCredit, bank.ID, 20))
+		lunch := putTestTx(t, st, day(12), "lunch", NewEntry(OpDebit, food.ID, 5), NewEntry(OpCredit, cash.ID, 5))
+
+		s, err := NewTxServiceWithIndex(st, store.NewDirStore(t.TempDir()), accService)
+		require.NoError(t, err)
+		st.readKeys()
+
+		// not built yet: every transaction is read
+		filter := Filter{Accounts: []account.ID{food.ID}}
+		assert.Equal(t, []Transaction{lunch}, collect(t, s.Iter(ctx, filter)))
+		assert.Equal(t, txKeys(salary, withdraw, lunch), st.readKeys())
+
+		require.NoError(t, s.Reindex(ctx))
+		st.readKeys()
+
+		assert.Equal(t, []Transaction{lunch}, collect(t, s.Iter(ctx, filter)))
+		assert.Equal(t, txKeys(lunch), st.readKeys())
+
+		filter = Filter{From: day(2), To: day(11)}
+		assert.Equal(t, []Transaction{withdraw}, collect(t, s.Iter(ctx, filter)))
+		assert.Equal(t, txKeys(withdraw), st.readKeys())
+
+		filter = Filter{From: day(2), Accounts: []account.ID{cash.ID}}
+		assert.Equal(t, []Transaction{withdraw, lunch}, collect(t, s.Iter(ctx, filter)))
+		assert.Equal(t, txKeys(withdraw, lunch), st.readKeys())
+
+		filter = Filter{To: day(12).Add(-time.Hour), Accounts: []account.ID{bank.ID}}
+		assert.Equal(t, []Transaction{salary, withdraw}, collect(t, s.Iter(ctx, filter)))
+
+		t.Run("stale entries are skipped", func(t *testing.T) {
+			require.NoError(t, st.Delete(txKeys(withdraw)[0]))
+			assert.Equal(t, []Transaction{salary}, collect(t, s.Iter(ctx, Filter{Accounts: []account.ID{bank.ID}})))
+		})
+	})
+
+	t.Run("hashed keys when encrypted", func(t *testing.T) {
+		key := bytes.Repeat([]byte{1}, store.KeySize)
+		indexDir := t.TempDir()
+		indexStore, err := store.NewEncryptedStore(store.NewDirStore(indexDir), key)
+		require.NoError(t, err)
+		st := store.NewDirStore(t.TempDir())
+		salary := putTestTx(t, st, day(1), "salary", NewEntry(OpDebit, bank.ID, 100), NewEntry(OpCredit, account.ID{9}, 100))
+
+		// indexed with plaintext keys before the encryption
+		s, err := NewTxServiceWithIndex(st, store.NewDirStore(indexDir), accService)
+		require.NoError(t, err)
+		require.NoError(t, s.Reindex(ctx))
+
+		s, err = NewTxServiceWithIndex(st, indexStore, accService)
+		require.NoError(t, err)
+		filter := Filter{From: day(1), To: day(2)}
+		assert.Equal(t, []Transaction{salary}, collect(t, s.Iter(ctx, filter)), "plaintext indexes not used")
+		require.NoError(t, s.Reindex(ctx))
+
+		withdraw, err := s.(*txService).RecordFromMapsAt(ctx, day(10), "withdraw", map[string]int64{cash.Alias: 20}, map[string]int64{bank.Alias: 20})
+		require.NoError(t, err)
+		lunch, err := s.(*txService).RecordFromMapsAt(ctx, day(12), "lunch", map[string]int64{food.Alias: 5}, map[string]int64{cash.Alias: 5})
+		require.NoError(t, err)
+
+		keys, err := store.NewDirStore(indexDir).Keys()
+		require.NoError(t, err)
+		assert.Len(t, keys, 1+3+4, "days, 3 dates, 4 accounts")
+		for _, k := range keys {
+			assert.True(t, strings.HasPrefix(k, hashedIndexPrefix), k)
+		}
+
+		assert.Equal(t, []Transaction{withdraw}, collect(t, s.Iter(ctx, Filter{From: day(2), To: day(11)})))
+		assert.Equal(t, []Transaction{lunch}, collect(t, s.Iter(ctx, Filter{Accounts: []account.ID{food.ID}})))
+		count, err := s.CountByDate(day(1), day(11))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), count)
+
+		locked, err := store.NewEncryptedStore(store.NewDirStore(indexDir), nil)
+		require.NoError(t, err)
+		s, err = NewTxServiceWithIndex(st, locked, accService)
+		require.NoError(t, err, "opened while locked")
+		_, err = s.CountByDate(day(1), day(11))
+		assert.ErrorIs(t, err, store.ErrLocked)
+	})
+
+	t.Run("not indexed", func(t *testing.T) {
+		s, err := NewTxServiceWithStore(store.NewDirStore(t.TempDir()), accService)
+		require.NoError(t, err)
+		assert.Error(t, s.Reindex(ctx))
+	})
+}
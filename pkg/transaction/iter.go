@@ -0,0 +1,390 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/store"
+)
+
+// Filter selects transactions. Its zero value selects every transaction.
+type Filter struct {
+	// From and To select the transactions recorded in [From, To).
+	// A zero time does not bound the range.
+	From, To time.Time
+
+	// Accounts selects the transactions having an entry on one of the
+	// accounts. An empty slice selects every transaction.
+	Accounts []account.ID
+
+	// MinAmount and MaxAmount select the transactions whose amount (the
+	// sum of their debits) is in [MinAmount, MaxAmount].
+	// A nil bound does not bound the range.
+	MinAmount, MaxAmount *int64
+
+	// Note selects the transactions whose note contains it, ignoring case.
+	Note string
+
+	// NoteRegexp selects the transactions whose note matches it, if not nil.
+	NoteRegexp *regexp.Regexp
+
+	// Sort orders the selected transactions, by date if zero. Reverse
+	// reverses the order.
+	Sort    SortKey
+	Reverse bool
+
+	// Offset skips the first selected transactions, once ordered, and
+	// Limit bounds their number if not zero. They are ignored by Match.
+	Offset, Limit int
+}
+
+// SortKey is the key ordering the transactions of an iteration.
+type SortKey uint8
+
+const (
+	// SortByDate orders the transactions by timestamp, then hash.
+	SortByDate SortKey = iota
+
+	// SortByAmount orders the transactions by amount (the sum of their
+	// debits), then timestamp and hash.
+	SortByAmount
+)
+
+// ParseSortKey returns the SortKey named s: "date" or "amount".
+func ParseSortKey(s string) (SortKey, error) {
+	switch s {
+	case "date":
+		return SortByDate, nil
+	case "amount":
+		return SortByAmount, nil
+	}
+	return 0, fmt.Errorf("invalid sort key %q, want date or amount", s)
+}
+
+// Match returns whether tx is selected by f.
+func (f *Filter) Match(tx Transaction) bool {
+	return f.matchTimestamp(tx.Timestamp()) &&
+		f.matchAccounts(tx) &&
+		f.matchAmount(tx) &&
+		f.matchNote(tx.Note())
+}
+
+func (f *Filter) matchTimestamp(ts int64) bool {
+	if !f.From.IsZero() && ts < f.From.Unix() {
+		return false
+	}
+	if !f.To.IsZero() && ts >= f.To.Unix() {
+		return false
+	}
+	return true
+}
+
+func (f *Filter) matchAccounts(tx Transaction) bool {
+	if len(f.Accounts) == 0 {
+		return true
+	}
+	for _, e := range tx.Entries() {
+		for _, id := range f.Accounts {
+			if e.AccountID() == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f *Filter) matchAmount(tx Transaction) bool {
+	if f.MinAmount == nil && f.MaxAmount == nil {
+		return true
+	}
+	amount, _ := Totals(tx)
+	if f.MinAmount != nil && amount < *f.MinAmount {
+		return false
+	}
+	if f.MaxAmount != nil && amount > *f.MaxAmount {
+		return false
+	}
+	return true
+}
+
+func (f *Filter) matchNote(note string) bool {
+	if f.Note != "" && !strings.Contains(strings.ToLower(note), strings.ToLower(f.Note)) {
+		return false
+	}
+	return f.NoteRegexp == nil || f.NoteRegexp.MatchString(note)
+}
+
+// TxIterator iterates over transactions. It is used like sql.Rows:
+//
+//	it := txService.Iter(ctx, filter)
+//	defer it.Close()
+//	for it.Next() {
+//		tx := it.Tx()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type TxIterator interface {
+	// Next advances to the next transaction, which is then returned by Tx.
+	// It returns false when there are no more transactions, or on error.
+	Next() bool
+
+	// Tx returns the current transaction.
+	Tx() Transaction
+
+	// Err returns the error which stopped the iteration, if any.
+	// Transactions which could not be read are skipped, and reported at
+	// the end of the iteration as a *store.ListError.
+	Err() error
+
+	// Close stops the iteration. Calling Next after Close returns false.
+	Close() error
+}
+
+// txIterator iterates over the transactions of a txService.
+//
+// Only the keys and sort keys of the selected transactions are kept, each
+// transaction being read again by the call to Next returning it. Ordered
+// by date, once the indexes are built, the transactions are selected day
+// by day, so that stopping early does not read the next days. Otherwise,
+// the first call to Next reads every candidate, to order them.
+type txIterator struct {
+	ctx    context.Context
+	s      *txService
+	filter Filter
+
+	started bool
+	closed  bool
+	// days are the days (YYYYMMDD) left to select transactions from, in
+	// order, when selecting day by day.
+	days []string
+	// accounts are the keys of the transactions selected by the accounts
+	// of the filter (nil if none) when selecting day by day.
+	accounts map[string]bool
+	// indexed is whether the candidates come from the indexes, which may
+	// list transactions which no longer exist.
+	indexed bool
+	// skip and left are the numbers of selected transactions still to be
+	// skipped by the offset, and returned within the limit.
+	skip, left int
+	refs       []txRef
+	tx         Transaction
+	err        error
+	listErr    store.ListError
+}
+
+// txRef is a selected transaction, with its sort keys.
+type txRef struct {
+	timestamp int64
+	amount    int64
+	key       string
+}
+
+func (s *txService) Iter(ctx context.Context, f Filter) TxIterator {
+	return &txIterator{ctx: ctx, s: s, filter: f}
+}
+
+func (it *txIterator) Next() bool {
+	if it.closed || it.err != nil {
+		return false
+	}
+	if !it.started {
+		it.started = true
+		if it.err = it.start(); it.err != nil {
+			return false
+		}
+	}
+
+	for {
+		if it.err = it.ctx.Err(); it.err != nil {
+			return false
+		}
+		if len(it.refs) == 0 {
+			if len(it.days) == 0 {
+				break
+			}
+			if it.err = it.selectDay(); it.err != nil {
+				return false
+			}
+			continue
+		}
+
+		ref := it.refs[0]
+		it.refs = it.refs[1:]
+		tx, err := it.read(ref.key)
+		if errors.Is(err, os.ErrNotExist) {
+			// removed since selected
+			continue
+		} else if err != nil {
+			it.listErr.Add(ref.key, err)
+			continue
+		}
+		it.tx = tx
+		return true
+	}
+
+	it.tx = nil
+	it.err = it.listErr.ErrOrNil()
+	return false
+}
+
+// start selects the transactions to iterate over: the first day having
+// some if selecting day by day, every one otherwise.
+func (it *txIterator) start() error {
+	it.skip, it.left = it.filter.Offset, it.filter.Limit
+
+	if it.filter.Sort == SortByDate && it.s.index != nil {
+		built, err := it.s.index.built()
+		if err != nil {
+			return err
+		}
+		if built {
+			return it.startByDay()
+		}
+	}
+
+	keys, indexed, err := it.s.candidates(it.filter)
+	if err != nil {
+		return err
+	}
+	it.indexed = indexed
+	return it.selectRefs(keys)
+}
+
+// startByDay lists the days to select the transactions from.
+func (it *txIterator) startByDay() error {
+	days, err := it.s.index.days()
+	if err != nil {
+		return err
+	}
+	for _, day := range days {
+		if it.filter.matchDay(day) {
+			it.days = append(it.days, day)
+		}
+	}
+	if it.filter.Reverse {
+		for i, j := 0, len(it.days)-1; i < j; i, j = i+1, j-1 {
+			it.days[i], it.days[j] = it.days[j], it.days[i]
+		}
+	}
+	it.indexed = true
+	it.accounts, err = it.s.index.accountCandidates(it.filter)
+	return err
+}
+
+// selectDay selects the transactions of the next day.
+func (it *txIterator) selectDay() error {
+	day := it.days[0]
+	it.days = it.days[1:]
+	keys, err := it.s.index.dayCandidates(day, it.accounts)
+	if err != nil {
+		return err
+	}
+	return it.selectRefs(keys)
+}
+
+// selectRefs selects the transactions of keys matching the filter, then
+// orders and pages them.
+func (it *txIterator) selectRefs(keys []string) error {
+	refs := []txRef{}
+	for _, key := range keys {
+		if err := it.ctx.Err(); err != nil {
+			return err
+		}
+
+		tx, err := it.read(key)
+		if it.indexed && errors.Is(err, os.ErrNotExist) {
+			// stale index entry
+			continue
+		} else if err != nil {
+			it.listErr.Add(key, err)
+			continue
+		}
+		if it.filter.Match(tx) {
+			amount, _ := Totals(tx)
+			refs = append(refs, txRef{tx.Timestamp(), amount, key})
+		}
+	}
+
+	sort.Slice(refs, func(i, j int) bool {
+		a, b := refs[i], refs[j]
+		if it.filter.Reverse {
+			a, b = b, a
+		}
+		if it.filter.Sort == SortByAmount && a.amount != b.amount {
+			return a.amount < b.amount
+		}
+		if a.timestamp != b.timestamp {
+			return a.timestamp < b.timestamp
+		}
+		return a.key < b.key
+	})
+
+	skipped := it.skip
+	if skipped > len(refs) {
+		skipped = len(refs)
+	}
+	refs = refs[skipped:]
+	it.skip -= skipped
+	if it.filter.Limit > 0 {
+		if it.left < len(refs) {
+			refs = refs[:it.left]
+		}
+		it.left -= len(refs)
+		if it.left == 0 {
+			it.days = nil
+		}
+	}
+	it.refs = refs
+	return nil
+}
+
+// read returns the transaction stored under key.
+func (it *txIterator) read(key string) (Transaction, error) {
+	b, err := it.s.store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(b)
+}
+
+// candidates returns the keys of the transactions which may match f, and
+// whether they were selected using the indexes.
+func (s *txService) candidates(f Filter) ([]string, bool, error) {
+	if s.index != nil {
+		built, err := s.index.built()
+		if err != nil {
+			return nil, false, err
+		}
+		if built {
+			keys, ok, err := s.index.candidates(f)
+			if err != nil || ok {
+				return keys, ok, err
+			}
+		}
+	}
+	keys, err := s.store.Keys()
+	return keys, false, err
+}
+
+func (it *txIterator) Tx() Transaction {
+	return it.tx
+}
+
+func (it *txIterator) Err() error {
+	return it.err
+}
+
+func (it *txIterator) Close() error {
+	it.closed = true
+	it.refs = nil
+	it.days = nil
+	it.tx = nil
+	return nil
+}
@@ -0,0 +1,163 @@
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/encoding"
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// putTestTx stores a transaction recorded at the given date into st.
+func putTestTx(t *testing.T, st store.Store, date time.Time, note string, entries ...Entry) Transaction {
+	tx := &transaction{timestamp: date.Unix(), note: note, entries: entries}
+
+	b := new(bytes.Buffer)
+	rw, err := encoding.NewRecordWriter(b, encoding.FormatVersionCurrent)
+	require.NoError(t, err)
+	require.NoError(t, rw.WriteEncoded(tx))
+	require.NoError(t, rw.Close())
+
+	tx.hash = sha256.Sum256(b.Bytes())
+	require.NoError(t, st.Put(fmt.Sprintf("%x", tx.hash), b.Bytes()))
+	return tx
+}
+
+func collect(t *testing.T, it TxIterator) []Transaction {
+	defer it.Close()
+	txs := []Transaction{}
+	for it.Next() {
+		txs = append(txs, it.Tx())
+	}
+	require.NoError(t, it.Err())
+	return txs
+}
+
+func int64Ptr(x int64) *int64 {
+	return &x
+}
+
+func TestTxServiceIter(t *testing.T) {
+	txDir := t.TempDir()
+	st := store.NewDirStore(txDir)
+
+	accService, cleanup := createTestAccService(t, t.TempDir())
+	defer cleanup()
+
+	s, err := NewTxServiceWithStore(st, accService)
+	require.NoError(t, err)
+	defer s.Cleanup()
+
+	cash, bank, food := account.ID{1}, account.ID{2}, account.ID{3}
+	day := func(d int) time.Time {
+		return time.Date(2022, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	// recorded out of order
+	withdraw := putTestTx(t, st, day(10), "ATM withdrawal",
+		NewEntry(OpDebit, cash, 200), NewEntry(OpCredit, bank, 200))
+	salary := putTestTx(t, st, day(1), "Salary",
+		NewEntry(OpDebit, bank, 1000), NewEntry(OpCredit, account.ID{4}, 1000))
+	lunch := putTestTx(t, st, day(12), "Lunch at the ATM corner",
+		NewEntry(OpDebit, food, 15), NewEntry(OpCredit, cash, 15))
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []Transaction
+	}{
+		{"all, by date", Filter{}, []Transaction{salary, withdraw, lunch}},
+		{"from", Filter{From: day(10)}, []Transaction{withdraw, lunch}},
+		{"to is exclusive", Filter{To: day(10)}, []Transaction{salary}},
+		{"date range", Filter{From: day(2), To: day(11)}, []Transaction{withdraw}},
+		{"account", Filter{Accounts: []account.ID{cash}}, []Transaction{withdraw, lunch}},
+		{"accounts", Filter{Accounts: []account.ID{food, bank}}, []Transaction{salary, withdraw, lunch}},
+		{"min amount", Filter{MinAmount: int64Ptr(200)}, []Transaction{salary, withdraw}},
+		{"amount range", Filter{MinAmount: int64Ptr(100), MaxAmount: int64Ptr(200)}, []Transaction{withdraw}},
+		{"note", Filter{Note: "atm"}, []Transaction{withdraw, lunch}},
+		{"combined", Filter{Note: "atm", Accounts: []account.ID{bank}}, []Transaction{withdraw}},
+		{"no match", Filter{Note: "rent"}, []Transaction{}},
+		{"note regexp", Filter{NoteRegexp: regexp.MustCompile(`^(Salary|Lunch)`)}, []Transaction{salary, lunch}},
+		{"reversed", Filter{Reverse: true}, []Transaction{lunch, withdraw, salary}},
+		{"by amount", Filter{Sort: SortByAmount}, []Transaction{lunch, withdraw, salary}},
+		{"by amount, reversed", Filter{Sort: SortByAmount, Reverse: true}, []Transaction{salary, withdraw, lunch}},
+		{"limit", Filter{Limit: 2}, []Transaction{salary, withdraw}},
+		{"offset", Filter{Offset: 1, Limit: 1}, []Transaction{withdraw}},
+		{"offset past the end", Filter{Offset: 3}, []Transaction{}},
+		{"paged after filtering", Filter{Accounts: []account.ID{cash}, Reverse: true, Limit: 1}, []Transaction{lunch}},
+	}
+	counting := &countingStore{st, map[string]int{}}
+	indexed, err := NewTxServiceWithIndex(counting, store.NewDirStore(t.TempDir()), accService)
+	require.NoError(t, err)
+	defer indexed.Cleanup()
+	require.NoError(t, indexed.Reindex(context.Background()))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, collect(t, s.Iter(context.Background(), tt.filter)))
+			assert.Equal(t, tt.expected, collect(t, indexed.Iter(context.Background(), tt.filter)), "indexed")
+		})
+	}
+
+	t.Run("streamed day by day", func(t *testing.T) {
+		counting.readKeys()
+		it := indexed.Iter(context.Background(), Filter{})
+		require.True(t, it.Next())
+		assert.Equal(t, salary, it.Tx())
+		require.NoError(t, it.Close())
+		assert.Equal(t, txKeys(salary), counting.readKeys(), "the next days are not read")
+
+		assert.Equal(t, []Transaction{lunch}, collect(t, indexed.Iter(context.Background(), Filter{Reverse: true, Limit: 1})))
+		assert.Equal(t, txKeys(lunch), counting.readKeys())
+	})
+	t.Run("early termination", func(t *testing.T) {
+		it := s.Iter(context.Background(), Filter{})
+		require.True(t, it.Next())
+		assert.Equal(t, salary, it.Tx())
+		require.NoError(t, it.Close())
+
+		assert.False(t, it.Next())
+		assert.Nil(t, it.Tx())
+		assert.NoError(t, it.Err())
+	})
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		it := s.Iter(ctx, Filter{})
+		defer it.Close()
+
+		require.True(t, it.Next())
+		cancel()
+		assert.False(t, it.Next())
+		assert.ErrorIs(t, it.Err(), context.Canceled)
+	})
+	t.Run("unreadable tx", func(t *testing.T) {
+		corrupted := fmt.Sprintf("%x", sha256.Sum256([]byte("corrupted")))
+		path := filepath.Join(txDir, corrupted)
+		require.NoError(t, os.WriteFile(path, []byte{0x42}, 0644))
+		defer os.Remove(path)
+
+		it := s.Iter(context.Background(), Filter{})
+		defer it.Close()
+
+		txs := []Transaction{}
+		for it.Next() {
+			txs = append(txs, it.Tx())
+		}
+		assert.Equal(t, []Transaction{salary, withdraw, lunch}, txs, "readable txs must still be iterated")
+
+		var listErr *store.ListError
+		require.ErrorAs(t, it.Err(), &listErr)
+		require.Len(t, listErr.Failures, 1)
+		assert.Equal(t, corrupted, listErr.Failures[0].Key)
+	})
+}
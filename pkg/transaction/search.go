@@ -0,0 +1,17 @@
+package transaction
+
+import (
+	"encoding/hex"
+
+	"github.com/fitiavana07/mitrack/pkg/search"
+)
+
+// SearchDocument returns the search document of tx: its note.
+func SearchDocument(tx Transaction) search.Document {
+	hash := tx.Hash()
+	return search.Document{
+		Kind:   search.KindTransaction,
+		ID:     hex.EncodeToString(hash[:]),
+		Fields: map[search.Field]string{search.FieldNote: tx.Note()},
+	}
+}
@@ -0,0 +1,42 @@
+package transaction
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/search"
+	"github.com/fitiavana07/mitrack/pkg/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxServiceSearch(t *testing.T) {
+	accService, cleanup := createTestAccService(t, t.TempDir())
+	defer cleanup()
+	cash := account.NewAccount("Cash", account.TypeAsset)
+	require.NoError(t, accService.Register(cash))
+	bank := account.NewAccount("Bank", account.TypeAsset)
+	require.NoError(t, accService.Register(bank))
+
+	indexStore := store.NewDirStore(t.TempDir())
+	ix := search.NewIndex(indexStore)
+	s, err := NewTxServiceWithSearch(store.NewDirStore(t.TempDir()), indexStore, ix, accService)
+	require.NoError(t, err)
+	defer s.Cleanup()
+
+	tx, err := s.RecordFromMaps("Naka vola sabotsy Namehana",
+		map[string]int64{cash.Alias: 100}, map[string]int64{bank.Alias: 100})
+	require.NoError(t, err)
+
+	results, err := ix.Search("namehana")
+	require.NoError(t, err)
+	assert.Equal(t, []search.Result{{Kind: search.KindTransaction, ID: fmt.Sprintf("%x", tx.Hash()), Score: 2}}, results)
+
+	// the search index is kept by the reindex of the other indexes
+	require.NoError(t, s.Reindex(context.Background()))
+	results, err = ix.Search("namehana")
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+}
@@ -1,21 +1,28 @@
 package transaction
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/fitiavana07/mitrack/pkg/account"
 	"github.com/fitiavana07/mitrack/pkg/encoding"
+	"github.com/fitiavana07/mitrack/pkg/search"
+	"github.com/fitiavana07/mitrack/pkg/store"
 )
 
 // TxService provides methods for managing transactions.
+// It is safe for concurrent use by multiple goroutines.
+//
+// The methods taking a context stop when it is done, returning ctx.Err().
 type TxService interface {
 	// ==== CREATE ====
 	// RecordFromMaps records a transaction using the info given in args.
@@ -24,23 +31,52 @@ type TxService interface {
 	// equal sum of credits).
 	// This method returns a pointer to the created transaction.
 	RecordFromMaps(note string, debitsMap, creditsMap map[string]int64) (Transaction, error)
+	// RecordFromMapsContext is like RecordFromMaps, with a context.
+	RecordFromMapsContext(ctx context.Context, note string, debitsMap, creditsMap map[string]int64) (Transaction, error)
+	// RecordFromMapsAt is like RecordFromMapsContext, dating the
+	// transaction at t instead of now.
+	RecordFromMapsAt(ctx context.Context, t time.Time, note string, debitsMap, creditsMap map[string]int64) (Transaction, error)
+	// Begin returns a new batch of transactions, recorded together by its
+	// Commit.
+	Begin() *Batch
 
 	// ==== READ ====
 	// Count returns the total number of transactions in the transactions database.
-	Count() uint64
+	Count() (uint64, error)
+	// CountByDate returns the number of transactions recorded in [from, to).
+	// A zero time does not bound the range.
+	CountByDate(from, to time.Time) (uint64, error)
+	// CountByDateContext is like CountByDate, with a context.
+	CountByDateContext(ctx context.Context, from, to time.Time) (uint64, error)
 	// List returns all transactions in the transactions database.
-	List() []Transaction
+	// If some transactions could not be read, the others are returned along
+	// with a *store.ListError listing the failures.
+	List() ([]Transaction, error)
+	// ListContext is like List, with a context.
+	ListContext(ctx context.Context) ([]Transaction, error)
+	// Iter returns an iterator over the transactions selected by f,
+	// ordered by date. ctx stops the iteration when done. Ordered by
+	// date, once indexed, the transactions are read day by day as
+	// iterated; otherwise, they are all read by the first Next.
+	Iter(ctx context.Context, f Filter) TxIterator
 	// Get returns the transaction given its prefix (short hash) or full hash.
 	// The search is in this order: full hash hex, prefix.
 	Get(prefix string) (Transaction, error)
 	// GetByHash returns a transaction given its hash in  hex.
 	GetByHash(hash string) (Transaction, error)
-	// GetByPrefix returns a transaction given a prefix.
+	// GetByPrefix returns a transaction given a prefix of its hash in hex.
+	// It returns an ErrAmbiguousPrefix if several transactions match.
 	GetByPrefix(prefix string) (Transaction, error)
+	// Hashes returns the hashes in hex of the transactions starting with
+	// prefix, in lexical order.
+	Hashes(prefix string) ([]string, error)
 
 	// ==== UPDATE ====
 	// NO UPDATE, IMMUTABLE
 
+	// Reindex rebuilds the date and account indexes from the transactions.
+	Reindex(ctx context.Context) error
+
 	// ==== DELETE ====
 	// NO DELETE, IMMUTABLE
 
@@ -54,33 +90,112 @@ type TxService interface {
 // It stores transactions file in the given dir.
 // It uses the given accService to search for accounts.
 func NewTxService(dir string, accService account.AccService) (TxService, error) {
-	dbinfoFilePath := filepath.Join(dir, dbInfoFileName)
-	_, err := os.Stat(dbinfoFilePath)
-	if errors.Is(err, os.ErrNotExist) {
-		f, err := os.Create(dbinfoFilePath)
-		if err != nil {
-			return nil, fmt.Errorf("transaction.service: could not create .dbinfo: %s", err)
-		}
-		defer f.Close()
+	return NewTxServiceWithStore(store.NewDirStore(dir), accService)
+}
 
-		if _, err = f.WriteString("quick:v0.4"); err != nil {
-			return nil, fmt.Errorf("transaction.service: could not write .dbinfo content: %s", err)
+// NewTxServiceWithStore returns a new TxService keeping transactions in st,
+// without indexes.
+// It uses the given accService to search for accounts.
+func NewTxServiceWithStore(st store.Store, accService account.AccService) (TxService, error) {
+	return NewTxServiceWithIndex(st, nil, accService)
+}
+
+// NewTxServiceWithIndex returns a new TxService keeping transactions in st,
+// and their date and account indexes in indexStore.
+// It uses the given accService to search for accounts.
+func NewTxServiceWithIndex(st, indexStore store.Store, accService account.AccService) (TxService, error) {
+	return NewTxServiceWithSearch(st, indexStore, nil, accService)
+}
+
+// NewTxServiceWithSearch is like NewTxServiceWithIndex, also indexing the
+// notes of the recorded transactions into searchIndex, if not nil.
+func NewTxServiceWithSearch(st, indexStore store.Store, searchIndex *search.Index, accService account.AccService) (TxService, error) {
+	_, err := st.Get(dbInfoFileName)
+	if errors.Is(err, os.ErrNotExist) {
+		if err = st.Put(dbInfoFileName, []byte("quick:v0.4")); err != nil {
+			return nil, fmt.Errorf("transaction.service: could not write .dbinfo content: %w", err)
 		}
 	} else if err != nil {
-		return nil, fmt.Errorf("transaction.service: could not read .dbinfo: %s", err)
+		return nil, fmt.Errorf("transaction.service: could not read .dbinfo: %w", err)
 	}
 
-	return &txService{dir: dir, accService: accService}, nil
+	s := &txService{store: st, accService: accService, search: searchIndex}
+	if indexStore != nil {
+		s.index = &txIndex{store: indexStore}
+		if err := s.initIndex(); err != nil {
+			return nil, fmt.Errorf("transaction.service: could not initialize indexes: %w", err)
+		}
+	}
+	if err := s.replayJournal(); err != nil {
+		return nil, fmt.Errorf("transaction.service: could not record the interrupted batch: %w", err)
+	}
+	return s, nil
 }
 
 type txService struct {
-	dir        string
+	store      store.Store
 	accService account.AccService
+
+	// index is nil if the transactions are not indexed.
+	index *txIndex
+
+	// search is nil if the transactions are not indexed for search.
+	search *search.Index
+
+	// mu serializes the updates of the indexes.
+	mu sync.Mutex
+}
+
+// initIndex marks the indexes as built if there are no transactions yet.
+// Otherwise, they are built by Reindex.
+func (s *txService) initIndex() error {
+	built, err := s.index.built()
+	if err != nil || built {
+		return err
+	}
+	keys, err := s.store.Keys()
+	if err != nil || len(keys) > 0 {
+		return err
+	}
+	return s.index.store.Put(indexInfoKey, []byte(s.index.infoContent()))
 }
 
 const dbInfoFileName = ".dbinfo"
 
 func (s *txService) RecordFromMaps(note string, debitsMap, creditsMap map[string]int64) (Transaction, error) {
+	return s.RecordFromMapsContext(context.Background(), note, debitsMap, creditsMap)
+}
+
+func (s *txService) RecordFromMapsContext(ctx context.Context, note string, debitsMap, creditsMap map[string]int64) (Transaction, error) {
+	return s.RecordFromMapsAt(ctx, time.Now(), note, debitsMap, creditsMap)
+}
+
+func (s *txService) RecordFromMapsAt(ctx context.Context, t time.Time, note string, debitsMap, creditsMap map[string]int64) (Transaction, error) {
+	tx, b, err := s.prepare(ctx, t, note, debitsMap, creditsMap)
+	if err != nil {
+		return nil, err
+	}
+
+	// stored and indexed together, so that Reindex never misses it
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err = s.store.Put(fmt.Sprintf("%x", tx.hash), b); err != nil {
+		return nil, fmt.Errorf("transaction.service: could not write transaction file: %w", err)
+	}
+	if err = s.addToIndexes(tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// prepare returns the transaction given by the maps, dated at t, and its
+// encoding, checking that it is balanced. It is not stored.
+func (s *txService) prepare(ctx context.Context, t time.Time, note string, debitsMap, creditsMap map[string]int64) (*transaction, []byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
 	entriesLen := len(debitsMap) + len(creditsMap)
 
 	type aliasEntry struct {
@@ -97,168 +212,204 @@ func (s *txService) RecordFromMaps(note string, debitsMap, creditsMap map[string
 		aliasEntries = append(aliasEntries, aliasEntry{OpCredit, alias, amount})
 	}
 
-	// TODO validation: sum debits = sum credits
-
 	entries := make([]Entry, 0, entriesLen)
 	for _, ae := range aliasEntries {
-		acc, err := s.accService.GetByAlias(ae.alias)
+		if ae.amount <= 0 {
+			return nil, nil, fmt.Errorf("transaction.service: %w: %d on %q", ErrInvalidAmount, ae.amount, ae.alias)
+		}
+		acc, err := s.accService.GetByAliasContext(ctx, ae.alias)
 		if err != nil {
-			// TODO refactor the account not found error
-			return nil, fmt.Errorf("account of alias %q not found: %s", ae.alias, err)
+			return nil, nil, fmt.Errorf("transaction.service: account of alias %q: %w", ae.alias, err)
 		}
 
 		entries = append(entries, NewEntry(ae.op, acc.ID, ae.amount))
 	}
 
 	tx := &transaction{
-		timestamp: time.Now().UTC().Unix(),
+		timestamp: t.UTC().Unix(),
 		note:      note,
 		entries:   entries,
 	}
-
-	encoder := encoding.NewEncoderV3()
+	debits, credits, ok := totals(tx)
+	if !ok {
+		return nil, nil, fmt.Errorf("transaction.service: %w", ErrAmountOverflow)
+	}
+	if debits != credits {
+		return nil, nil, fmt.Errorf("transaction.service: %w", &UnbalancedError{Debits: debits, Credits: credits})
+	}
 
 	b := new(bytes.Buffer)
 
-	var err error
-	if err = encoder.WriteEncoded(b, tx.timestamp); err != nil {
-		return nil, err
+	rw, err := encoding.NewRecordWriter(b, encoding.FormatVersionCurrent)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if err = encoder.WriteEncoded(b, uint16(entriesLen)); err != nil {
-		return nil, err
-	}
-	for _, entry := range entries {
-		if err = encoder.WriteEncoded(b, entry.Operation()); err != nil {
-			return nil, err
-		}
-		if err = encoder.WriteEncoded(b, entry.AccountID()); err != nil {
-			return nil, err
-		}
-		if err = encoder.WriteEncoded(b, entry.Amount()); err != nil {
-			return nil, err
-		}
+	if err = rw.WriteEncoded(tx); err != nil {
+		return nil, nil, err
 	}
-
-	if err = encoder.WriteEncoded(b, tx.note); err != nil {
-		return nil, err
+	if err = rw.Close(); err != nil {
+		return nil, nil, err
 	}
 
 	tx.hash = sha256.Sum256(b.Bytes())
 
-	txFilePath := filepath.Join(s.dir, fmt.Sprintf("%x", tx.hash))
-	f, err := os.Create(txFilePath)
-	if err != nil {
-		return nil, fmt.Errorf("error creating transaction file: %v", err)
+	if err = ctx.Err(); err != nil {
+		return nil, nil, err
 	}
-	defer f.Close()
+	return tx, b.Bytes(), nil
+}
 
-	if _, err = b.WriteTo(f); err != nil {
-		return nil, fmt.Errorf("error writing transaction data: %v", err)
+// addToIndexes adds the stored tx to the indexes. s.mu must be held.
+func (s *txService) addToIndexes(tx *transaction) error {
+	if s.index != nil {
+		if err := s.index.add(tx); err != nil {
+			return fmt.Errorf("transaction.service: transaction %x recorded but not indexed, reindex needed: %w", tx.hash, err)
+		}
 	}
-
-	return tx, nil
+	if s.search != nil {
+		if err := s.search.Add(SearchDocument(tx)); err != nil {
+			return fmt.Errorf("transaction.service: transaction %x recorded but not indexed, reindex needed: %w", tx.hash, err)
+		}
+	}
+	return nil
 }
 
-func (s *txService) Count() uint64 {
-	// TODO
-	return 0
+func (s *txService) List() ([]Transaction, error) {
+	return s.ListContext(context.Background())
 }
-func (s *txService) List() []Transaction {
-	txs := []Transaction{}
 
-	dirEntries, err := os.ReadDir(s.dir)
+func (s *txService) ListContext(ctx context.Context) ([]Transaction, error) {
+	keys, err := s.store.Keys()
 	if err != nil {
-		return txs
+		return nil, fmt.Errorf("transaction.service: could not list transactions: %w", err)
 	}
 
-	for _, entry := range dirEntries {
-		fileName := entry.Name()
-		// TODO refactor account.ID to reuse the DecodeID function.
-
-		if fileName == ".dbinfo" {
-			// special file
-			continue
+	txs := []Transaction{}
+	listErr := &store.ListError{}
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
 
-		tx, err := s.GetByHash(fileName)
+		tx, err := s.GetByHash(key)
 		if err != nil {
+			listErr.Add(key, err)
 			continue
 		}
 
 		txs = append(txs, tx)
 	}
 
-	return txs
+	return txs, listErr.ErrOrNil()
 }
+
 func (s *txService) Get(prefix string) (Transaction, error) {
-	// TODO
-	return nil, nil
+	if len(prefix) == 2*sha256.Size {
+		tx, err := s.GetByHash(prefix)
+		if !errors.Is(err, ErrTxNotFound) {
+			return tx, err
+		}
+	}
+	return s.GetByPrefix(prefix)
 }
 
 // GetByHash returns a transaction given its hash in  hex.
 func (s *txService) GetByHash(hash string) (Transaction, error) {
 	b, err := hex.DecodeString(hash)
 	if err != nil {
-		return nil, fmt.Errorf("transaction.service: invalid hash")
+		return nil, fmt.Errorf("transaction.service: invalid hash %q", hash)
+	}
+	if len(b) != sha256.Size {
+		return nil, fmt.Errorf("transaction.service: invalid hash length %d", len(b))
 	}
 
 	actualHash := [sha256.Size]byte{}
 	copy(actualHash[:], b[:])
 
-	// open the file
-	txFilePath := filepath.Join(s.dir, hash)
-	f, err := os.Open(txFilePath)
+	txBytes, err := s.store.Get(hash)
 	if errors.Is(err, os.ErrNotExist) {
-		return nil, fmt.Errorf("transaction.service: transaction not found")
+		return nil, fmt.Errorf("transaction.service: %w", ErrTxNotFound)
 	} else if err != nil {
-		return nil, fmt.Errorf("transaction.service: could not open transaction file: %v", err)
+		return nil, fmt.Errorf("transaction.service: could not read transaction file: %w", err)
 	}
-	defer f.Close()
 
-	tx := transaction{hash: actualHash}
-
-	decoder := encoding.NewDecoderV3()
+	tx, err := Decode(txBytes)
+	if err != nil {
+		err = encoding.SetDecodePath(err, store.PathOf(s.store, hash))
+		return nil, fmt.Errorf("transaction.service: invalid transaction file format: %w", err)
+	}
+	tx.(*transaction).hash = actualHash
 
-	r := bufio.NewReader(f)
+	return tx, nil
+}
 
-	if err = decoder.ReadDecoded(r, &tx.timestamp); err != nil {
-		return nil, fmt.Errorf("transaction.service: invalid transaction file format: %v", err)
-	}
-	entriesLen := new(uint16)
-	if err = decoder.ReadDecoded(r, entriesLen); err != nil {
-		return nil, fmt.Errorf("transaction.service: invalid transaction file format: %v", err)
-	}
-	entries := make([]Entry, 0, *entriesLen)
-	for i := 0; i < int(*entriesLen); i++ {
-		entry := entry{}
-		if err = decoder.ReadDecoded(r, &entry.operation); err != nil {
-			return nil, fmt.Errorf("transaction.service: invalid transaction file format: %v", err)
-		}
-		if err = decoder.ReadDecoded(r, &entry.accountID); err != nil {
-			return nil, fmt.Errorf("transaction.service: invalid transaction file format: %v", err)
-		}
-		if err = decoder.ReadDecoded(r, &entry.amount); err != nil {
-			return nil, fmt.Errorf("transaction.service: invalid transaction file format: %v", err)
-		}
-		entries = append(entries, &entry)
+// GetByPrefix returns a transaction given a prefix of its hash in hex.
+func (s *txService) GetByPrefix(prefix string) (Transaction, error) {
+	hashes, err := s.Hashes(prefix)
+	if err != nil {
+		return nil, err
 	}
-	if err = decoder.ReadDecoded(r, &tx.note); err != nil {
-		return nil, fmt.Errorf("transaction.service: invalid transaction file format: %v", err)
+	switch len(hashes) {
+	case 0:
+		return nil, fmt.Errorf("transaction.service: %w", ErrTxNotFound)
+	case 1:
+		return s.GetByHash(hashes[0])
 	}
+	return nil, fmt.Errorf("transaction.service: %w: %q matches %d transactions", ErrAmbiguousPrefix, prefix, len(hashes))
+}
 
-	tx.entries = entries
+func (s *txService) Hashes(prefix string) ([]string, error) {
+	keys, err := s.store.Keys()
+	if err != nil {
+		return nil, fmt.Errorf("transaction.service: could not list transactions: %w", err)
+	}
+	prefix = strings.ToLower(prefix)
 
-	return &tx, nil
+	// keys are sorted: the matching ones are contiguous
+	i := sort.SearchStrings(keys, prefix)
+	j := i
+	for j < len(keys) && strings.HasPrefix(keys[j], prefix) {
+		j++
+	}
+	return keys[i:j], nil
 }
 
-// GetByPrefix returns a transaction given a prefix.
-func (s *txService) GetByPrefix(prefix string) (Transaction, error) {
-	// TODO
-	return nil, nil
+func (s *txService) Reindex(ctx context.Context) error {
+	if s.index == nil {
+		return fmt.Errorf("transaction.service: transactions are not indexed")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.index.rebuild(ctx, s.store)
 }
 
 func (s *txService) Cleanup() error {
 	// TODO
 	return nil
 }
+
+// ErrTxNotFound is returned when no transaction matches the search.
+var ErrTxNotFound = errors.New("transaction not found")
+
+// ErrAmbiguousPrefix is returned when several transactions match a prefix.
+var ErrAmbiguousPrefix = errors.New("ambiguous transaction prefix")
+
+// ErrInvalidAmount is returned when recording an entry whose amount is not
+// positive.
+var ErrInvalidAmount = errors.New("amounts must be positive")
+
+// ErrAmountOverflow is returned when recording a transaction whose debits
+// or credits sum up past the largest amount.
+var ErrAmountOverflow = errors.New("total amount too large")
+
+// UnbalancedError is returned when recording a transaction whose debits
+// do not equal its credits.
+type UnbalancedError struct {
+	Debits, Credits int64
+}
+
+func (e *UnbalancedError) Error() string {
+	return fmt.Sprintf("unbalanced transaction: debits %d != credits %d", e.Debits, e.Credits)
+}
@@ -1,15 +1,21 @@
 package transaction
 
 import (
-	"bufio"
+	"bytes"
+	"context"
 	"crypto/sha256"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/fitiavana07/mitrack/pkg/account"
 	"github.com/fitiavana07/mitrack/pkg/encoding"
+	"github.com/fitiavana07/mitrack/pkg/store"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -97,39 +103,128 @@ func TestTxServiceRecordFromMaps(t *testing.T) {
 		require.NoError(t, err)
 		defer f.Close()
 
-		r := bufio.NewReader(f)
-
-		decoder := encoding.NewDecoderV3()
+		r, err := encoding.NewRecordReader(f)
+		require.NoError(t, err)
+		assert.Equal(t, encoding.FormatVersionCurrent, r.Version(), "record format version")
 
 		timestamp := new(int64)
-		err = decoder.ReadDecoded(r, timestamp)
+		err = r.ReadDecoded(timestamp)
 		checkNoErrorAndEqual(t, err, tx.Timestamp(), *timestamp, "Timestamp")
 
 		entriesCount := new(uint16)
-		err = decoder.ReadDecoded(r, entriesCount)
+		err = r.ReadDecoded(entriesCount)
 		checkNoErrorAndEqual(t, err, uint16(len(tx.Entries())), *entriesCount, "EntriesCount")
 
 		for i := 0; i < len(tx.Entries()); i++ {
 			entry := tx.Entries()[i]
 			op := new(Operation)
-			err = decoder.ReadDecoded(r, op)
+			err = r.ReadDecoded(op)
 			checkNoErrorAndEqual(t, err, entry.Operation(), *op, "Operation")
 
 			accountID := new(account.ID)
-			err = decoder.ReadDecoded(r, accountID)
+			err = r.ReadDecoded(accountID)
 			checkNoErrorAndEqual(t, err, entry.AccountID(), *accountID, "AccountID")
 
 			amount := new(int64)
-			err = decoder.ReadDecoded(r, amount)
+			err = r.ReadDecoded(amount)
 			checkNoErrorAndEqual(t, err, entry.Amount(), *amount, "Amount")
 		}
 
 		gotNote := new(string)
-		err = decoder.ReadDecoded(r, gotNote)
+		err = r.ReadDecoded(gotNote)
 		checkNoErrorAndEqual(t, err, tx.Note(), *gotNote, "Note")
 	})
 
-	t.Run("difference between credits and debits", func(t *testing.T) {})
+	t.Run("difference between credits and debits", func(t *testing.T) {
+		accService, cleanup := createTestAccService(t, t.TempDir())
+		defer cleanup()
+
+		txDir := t.TempDir()
+		s, cleanup := createTestTxService(t, txDir, accService)
+		defer cleanup()
+
+		accCashInWallet := account.NewAccount("Cash in Wallet", account.TypeAsset)
+		accService.Register(accCashInWallet)
+
+		accInitialBalance := account.NewAccount("Initial Balance", account.TypeEquity)
+		accService.Register(accInitialBalance)
+
+		tx, err := s.RecordFromMaps(
+			"unbalanced",
+			map[string]int64{accCashInWallet.Alias: 46000},
+			map[string]int64{accInitialBalance.Alias: 45000},
+		)
+
+		assert.Nil(t, tx)
+		var unbalanced *UnbalancedError
+		require.ErrorAs(t, err, &unbalanced)
+		assert.Equal(t, &UnbalancedError{Debits: 46000, Credits: 45000}, unbalanced)
+
+		count, err := s.Count()
+		require.NoError(t, err)
+		assert.Zero(t, count, "unbalanced transaction was recorded")
+	})
+	t.Run("invalid amounts", func(t *testing.T) {
+		accService, cleanup := createTestAccService(t, t.TempDir())
+		defer cleanup()
+
+		s, cleanup := createTestTxService(t, t.TempDir(), accService)
+		defer cleanup()
+
+		cash := account.NewAccount("Cash", account.TypeAsset)
+		require.NoError(t, accService.Register(cash))
+		bank := account.NewAccount("Bank", account.TypeAsset)
+		require.NoError(t, accService.Register(bank))
+		food := account.NewAccount("Food", account.TypeExpense)
+		require.NoError(t, accService.Register(food))
+
+		for _, amount := range []int64{0, -100} {
+			_, err := s.RecordFromMaps("refund", map[string]int64{cash.Alias: amount}, map[string]int64{food.Alias: amount})
+			assert.ErrorIs(t, err, ErrInvalidAmount, amount)
+		}
+
+		_, err := s.RecordFromMaps("overflow",
+			map[string]int64{cash.Alias: math.MaxInt64, bank.Alias: 2},
+			map[string]int64{food.Alias: 1})
+		assert.ErrorIs(t, err, ErrAmountOverflow)
+
+		count, err := s.Count()
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+	t.Run("unknown account", func(t *testing.T) {
+		accService, cleanup := createTestAccService(t, t.TempDir())
+		defer cleanup()
+
+		s, cleanup := createTestTxService(t, t.TempDir(), accService)
+		defer cleanup()
+
+		_, err := s.RecordFromMaps("unknown", map[string]int64{"cash": 1}, map[string]int64{"equity": 1})
+
+		assert.ErrorIs(t, err, account.ErrAccountNotFound)
+	})
+	t.Run("dated", func(t *testing.T) {
+		accService, cleanup := createTestAccService(t, t.TempDir())
+		defer cleanup()
+
+		s, cleanup := createTestTxService(t, t.TempDir(), accService)
+		defer cleanup()
+
+		cash := account.NewAccount("Cash", account.TypeAsset)
+		require.NoError(t, accService.Register(cash))
+		food := account.NewAccount("Food", account.TypeExpense)
+		require.NoError(t, accService.Register(food))
+
+		date := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+		tx, err := s.RecordFromMapsAt(context.Background(), date, "vary",
+			map[string]int64{food.Alias: 500}, map[string]int64{cash.Alias: 500})
+		require.NoError(t, err)
+		assert.Equal(t, date.Unix(), tx.Timestamp())
+
+		count, err := s.CountByDate(date, date.AddDate(0, 0, 1))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), count, "indexed at its date")
+	})
 }
 
 func checkNoErrorAndEqual(t testing.TB, err error, want, got interface{}, name string) {
@@ -184,11 +279,45 @@ func TestTxServiceList(t *testing.T) {
 		)
 		require.NoError(t, err)
 
-		txs := s.List()
+		txs, err := s.List()
+		require.NoError(t, err)
 
 		assert.Equal(t, 1, len(txs), "wrong list length")
 		assert.Contains(t, txs, tx, "tx not in list")
 	})
+	t.Run("unreadable tx", func(t *testing.T) {
+		accDir := t.TempDir()
+		txDir := t.TempDir()
+
+		accService, cleanup := createTestAccService(t, accDir)
+		defer cleanup()
+
+		s, cleanup := createTestTxService(t, txDir, accService)
+		defer cleanup()
+
+		accCash := account.NewAccount("Cash", account.TypeAsset)
+		require.NoError(t, accService.Register(accCash))
+		accEquity := account.NewAccount("Equity", account.TypeEquity)
+		require.NoError(t, accService.Register(accEquity))
+
+		tx, err := s.RecordFromMaps(
+			"initial",
+			map[string]int64{accCash.Alias: 100},
+			map[string]int64{accEquity.Alias: 100},
+		)
+		require.NoError(t, err)
+
+		corrupted := fmt.Sprintf("%x", sha256.Sum256([]byte("corrupted")))
+		require.NoError(t, os.WriteFile(filepath.Join(txDir, corrupted), []byte{0x42}, 0644))
+
+		txs, err := s.List()
+		assert.Equal(t, []Transaction{tx}, txs, "readable txs must still be listed")
+
+		var listErr *store.ListError
+		require.ErrorAs(t, err, &listErr)
+		require.Len(t, listErr.Failures, 1)
+		assert.Equal(t, corrupted, listErr.Failures[0].Key)
+	})
 }
 
 func TestTxServiceGetByHash(t *testing.T) {
@@ -222,7 +351,123 @@ func TestTxServiceGetByHash(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, tx, foundTx)
 	})
+	t.Run("v3 transaction file", func(t *testing.T) {
+		accDir := t.TempDir()
+		txDir := t.TempDir()
+
+		accService, cleanup := createTestAccService(t, accDir)
+		defer cleanup()
+
+		s, cleanup := createTestTxService(t, txDir, accService)
+		defer cleanup()
+
+		accID := account.NewAccount("Cash in Wallet", account.TypeAsset).ID
+		values := []interface{}{int64(1617000000), uint16(1), OpDebit, accID, int64(46000), "naka vola"}
+
+		b := new(bytes.Buffer)
+		rw, err := encoding.NewRecordWriter(b, encoding.FormatVersionV3)
+		require.NoError(t, err)
+		for _, v := range values {
+			require.NoError(t, rw.WriteEncoded(v))
+		}
+		require.NoError(t, rw.Close())
+
+		hash := fmt.Sprintf("%x", sha256.Sum256(b.Bytes()))
+		require.NoError(t, os.WriteFile(filepath.Join(txDir, hash), b.Bytes(), 0644))
+
+		foundTx, err := s.GetByHash(hash)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(1617000000), foundTx.Timestamp())
+		assert.Equal(t, "naka vola", foundTx.Note())
+		assert.Equal(t, []Entry{NewEntry(OpDebit, accID, 46000)}, foundTx.Entries())
+	})
 	t.Run("not existing", func(t *testing.T) {
-		// should return error
+		accService, cleanup := createTestAccService(t, t.TempDir())
+		defer cleanup()
+
+		s, cleanup := createTestTxService(t, t.TempDir(), accService)
+		defer cleanup()
+
+		_, err := s.GetByHash(fmt.Sprintf("%x", sha256.Sum256([]byte("nothing"))))
+
+		assert.ErrorIs(t, err, ErrTxNotFound)
 	})
+	t.Run("corrupted transaction file", func(t *testing.T) {
+		accService, cleanup := createTestAccService(t, t.TempDir())
+		defer cleanup()
+
+		txDir := t.TempDir()
+		s, cleanup := createTestTxService(t, txDir, accService)
+		defer cleanup()
+
+		// a timestamp, then a truncated entries count
+		b := []byte{0, 0, 0, 0, 0, 0, 0, 0, 1}
+		hash := fmt.Sprintf("%x", sha256.Sum256(b))
+		path := filepath.Join(txDir, hash)
+		require.NoError(t, os.WriteFile(path, b, 0644))
+
+		_, err := s.GetByHash(hash)
+
+		var decodeErr *encoding.DecodeError
+		require.ErrorAs(t, err, &decodeErr)
+		assert.Equal(t, path, decodeErr.Path)
+		// failed at the end of the file, reading the entries count
+		assert.Equal(t, int64(len(b)), decodeErr.Offset)
+	})
+}
+
+func TestTxServiceGetByPrefix(t *testing.T) {
+	accService, cleanup := createTestAccService(t, t.TempDir())
+	defer cleanup()
+
+	s, cleanup := createTestTxService(t, t.TempDir(), accService)
+	defer cleanup()
+
+	accCashInWallet := account.NewAccount("Cash in Wallet", account.TypeAsset)
+	require.NoError(t, accService.Register(accCashInWallet))
+	accFood := account.NewAccount("Food", account.TypeExpense)
+	require.NoError(t, accService.Register(accFood))
+
+	hashes := []string{}
+	txs := map[string]Transaction{}
+	for _, note := range []string{"mofo", "vary", "laoka"} {
+		tx, err := s.RecordFromMaps(note,
+			map[string]int64{accFood.Alias: 500},
+			map[string]int64{accCashInWallet.Alias: 500},
+		)
+		require.NoError(t, err)
+		hash := fmt.Sprintf("%x", tx.Hash())
+		hashes = append(hashes, hash)
+		txs[hash] = tx
+	}
+	sort.Strings(hashes)
+
+	got, err := s.Hashes("")
+	require.NoError(t, err)
+	assert.Equal(t, hashes, got)
+
+	for _, hash := range hashes {
+		got, err := s.Hashes(hash[:8])
+		require.NoError(t, err)
+		assert.Equal(t, []string{hash}, got)
+
+		tx, err := s.GetByPrefix(strings.ToUpper(hash[:8]))
+		require.NoError(t, err)
+		assert.Equal(t, txs[hash], tx)
+
+		tx, err = s.Get(hash)
+		require.NoError(t, err)
+		assert.Equal(t, txs[hash], tx)
+	}
+
+	_, err = s.GetByPrefix("")
+	assert.ErrorIs(t, err, ErrAmbiguousPrefix)
+
+	_, err = s.Get("not-a-hash")
+	assert.ErrorIs(t, err, ErrTxNotFound)
+
+	got, err = s.Hashes("zz")
+	require.NoError(t, err)
+	assert.Empty(t, got)
 }
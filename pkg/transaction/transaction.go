@@ -1,8 +1,14 @@
 package transaction
 
 import (
+	"bytes"
 	"crypto/sha256"
+	"fmt"
+	"io"
+	"math"
 	"time"
+
+	"github.com/fitiavana07/mitrack/pkg/encoding"
 )
 
 // Transaction represents a financial transaction in a double-entry
@@ -78,6 +84,54 @@ func NewFromMaps(note string, debits, credits map[string]int64) (Transaction, er
 	}, nil
 }
 
+// Decode decodes a transaction from its record bytes.
+// Its hash is computed from b.
+func Decode(b []byte) (Transaction, error) {
+	r, err := encoding.NewRecordReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	tx := transaction{hash: sha256.Sum256(b)}
+	if err = r.ReadDecoded(&tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// Totals returns the sums of the debit and of the credit entries of tx.
+// A valid transaction is balanced: both sums are equal.
+// A sum which does not fit in an int64, which TxService never records, is
+// saturated.
+func Totals(tx Transaction) (debits, credits int64) {
+	debits, credits, _ = totals(tx)
+	return
+}
+
+// totals is like Totals, also returning false if a sum overflowed.
+func totals(tx Transaction) (debits, credits int64, ok bool) {
+	ok = true
+	add := func(sum *int64, amount int64) {
+		switch {
+		case amount > 0 && *sum > math.MaxInt64-amount:
+			*sum, ok = math.MaxInt64, false
+		case amount < 0 && *sum < math.MinInt64-amount:
+			*sum, ok = math.MinInt64, false
+		default:
+			*sum += amount
+		}
+	}
+	for _, e := range tx.Entries() {
+		switch e.Operation() {
+		case OpDebit:
+			add(&debits, e.Amount())
+		case OpCredit:
+			add(&credits, e.Amount())
+		}
+	}
+	return
+}
+
 type transaction struct {
 	hash      [sha256.Size]byte
 	timestamp int64
@@ -100,3 +154,44 @@ func (t *transaction) Entries() []Entry {
 func (t *transaction) Note() string {
 	return t.note
 }
+
+// MarshalEncoded implements encoding.Marshaler.
+// The hash is not encoded, as it is computed from the encoded bytes.
+func (t *transaction) MarshalEncoded(w io.Writer, e encoding.Encoder) error {
+	if len(t.entries) > math.MaxUint16 {
+		return fmt.Errorf("%w: too many entries: %d", encoding.ErrRecordTooLarge, len(t.entries))
+	}
+
+	if err := e.WriteEncoded(w, t.timestamp); err != nil {
+		return err
+	}
+	if err := e.WriteEncoded(w, uint16(len(t.entries))); err != nil {
+		return err
+	}
+	for _, en := range t.entries {
+		if err := e.WriteEncoded(w, &entry{en.Operation(), en.AccountID(), en.Amount()}); err != nil {
+			return err
+		}
+	}
+	return e.WriteEncoded(w, t.note)
+}
+
+// UnmarshalDecoded implements encoding.Unmarshaler.
+func (t *transaction) UnmarshalDecoded(r io.Reader, d encoding.Decoder) error {
+	if err := d.ReadDecoded(r, &t.timestamp); err != nil {
+		return err
+	}
+	var entriesLen uint16
+	if err := d.ReadDecoded(r, &entriesLen); err != nil {
+		return err
+	}
+	t.entries = make([]Entry, 0, entriesLen)
+	for i := 0; i < int(entriesLen); i++ {
+		en := &entry{}
+		if err := d.ReadDecoded(r, en); err != nil {
+			return err
+		}
+		t.entries = append(t.entries, en)
+	}
+	return d.ReadDecoded(r, &t.note)
+}
@@ -1,3 +1,98 @@
 package transaction
 
-import ()
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/fitiavana07/mitrack/pkg/account"
+	"github.com/fitiavana07/mitrack/pkg/encoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntryMarshal(t *testing.T) {
+	for _, version := range []uint32{encoding.FormatVersionV3, encoding.FormatVersionV4} {
+		en := &entry{OpCredit, account.NewAccount("Checking Account", account.TypeAsset).ID, 900}
+
+		got := &entry{}
+		marshalRoundTrip(t, version, en, got)
+
+		assert.Equal(t, en, got)
+	}
+}
+
+func TestTransactionMarshal(t *testing.T) {
+	for _, version := range []uint32{encoding.FormatVersionV3, encoding.FormatVersionV4} {
+		cash := account.NewAccount("Cash in Wallet", account.TypeAsset)
+		checking := account.NewAccount("Checking Account", account.TypeAsset)
+		tx := &transaction{
+			timestamp: 1617000000,
+			note:      "naka vola sabotsy namehana",
+			entries: []Entry{
+				NewEntry(OpDebit, cash.ID, 900),
+				NewEntry(OpCredit, checking.ID, 900),
+			},
+		}
+
+		got := &transaction{}
+		marshalRoundTrip(t, version, tx, got)
+
+		assert.Equal(t, tx, got)
+	}
+}
+
+func TestTransactionMarshalTooLarge(t *testing.T) {
+	cash := account.NewAccount("Cash in Wallet", account.TypeAsset)
+	tt := []struct {
+		name string
+		tx   *transaction
+	}{
+		{"note", &transaction{note: strings.Repeat("a", math.MaxUint16+1)}},
+		{"entries", &transaction{entries: make([]Entry, math.MaxUint16+1)}},
+	}
+	for _, tc := range tt {
+		for i := range tc.tx.entries {
+			tc.tx.entries[i] = NewEntry(OpDebit, cash.ID, 1)
+		}
+		rw, err := encoding.NewRecordWriter(new(bytes.Buffer), encoding.FormatVersionV3)
+		require.NoError(t, err)
+		assert.ErrorIs(t, rw.WriteEncoded(tc.tx), encoding.ErrRecordTooLarge, tc.name)
+	}
+}
+
+func marshalRoundTrip(t testing.TB, version uint32, m encoding.Marshaler, u encoding.Unmarshaler) {
+	t.Helper()
+
+	b := new(bytes.Buffer)
+	rw, err := encoding.NewRecordWriter(b, version)
+	require.NoError(t, err)
+	require.NoError(t, rw.WriteEncoded(m))
+	require.NoError(t, rw.Close())
+
+	rr, err := encoding.NewRecordReader(b)
+	require.NoError(t, err)
+	require.NoError(t, rr.ReadDecoded(u))
+}
+
+func TestTotals(t *testing.T) {
+	cash := account.NewAccount("Cash in Wallet", account.TypeAsset)
+	tx := &transaction{entries: []Entry{
+		NewEntry(OpDebit, cash.ID, 900),
+		NewEntry(OpDebit, cash.ID, 100),
+		NewEntry(OpCredit, cash.ID, 1000),
+	}}
+	debits, credits := Totals(tx)
+	assert.Equal(t, int64(1000), debits)
+	assert.Equal(t, int64(1000), credits)
+	_, _, ok := totals(tx)
+	assert.True(t, ok)
+
+	tx.entries = append(tx.entries, NewEntry(OpCredit, cash.ID, math.MaxInt64))
+	debits, credits = Totals(tx)
+	assert.Equal(t, int64(1000), debits)
+	assert.Equal(t, int64(math.MaxInt64), credits, "saturated")
+	_, _, ok = totals(tx)
+	assert.False(t, ok)
+}